@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	cfg "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/config"
+)
+
+func configCmd(args []string) error {
+	sub := "explain"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		sub, args = args[0], args[1:]
+	}
+	switch sub {
+	case "explain":
+		return configExplain(args)
+	case "diff":
+		return configDiff(args)
+	}
+	return fmt.Errorf("unknown config command %q (want explain or diff)", sub)
+}
+
+// configExplain prints the config a run for the city would see, layered the
+// same way run layers it.
+func configExplain(args []string) error {
+	fs := flag.NewFlagSet("config explain", flag.ExitOnError)
+	city := fs.String("city", "Edinburgh", "city to resolve")
+	input := fs.String("input", "", "execution input JSON to layer over the city config")
+	asJSON := fs.Bool("json", false, "print entries as JSON")
+	fs.Parse(args)
+
+	ctx := context.Background()
+	var overlay string
+	reloader, _, err := cityReloader(ctx, *city, &overlay)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	rd, err := loadConfig(ctx, reloader)
+	if err != nil {
+		return err
+	}
+	cityCfg := rd.ForCity(*city)
+	if *input != "" {
+		data, err := os.ReadFile(*input)
+		if err != nil {
+			return err
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("%s: %w", *input, err)
+		}
+		if err := cityCfg.ApplyInput(doc); err != nil {
+			return err
+		}
+		if err := cityCfg.Validate(); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+	}
+
+	entries, err := cfg.Explain(rd, cityCfg)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(entries)
+	}
+	fmt.Printf("# effective config for %s\n", cityCfg.City)
+	for _, e := range entries {
+		fmt.Println(e)
+	}
+	return nil
+}
+
+// configDiff compares two environments' defaults files, each with the city
+// overlay from the cities/ directory next to it. Env overrides are left out:
+// they belong to the shell running the diff, not to either environment.
+func configDiff(args []string) error {
+	fs := flag.NewFlagSet("config diff", flag.ExitOnError)
+	city := fs.String("city", "Edinburgh", "city whose overlays to apply")
+	asJSON := fs.Bool("json", false, "print changes as JSON")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return fmt.Errorf("want two defaults files, got %d", fs.NArg())
+	}
+
+	var sides [2][]cfg.Entry
+	for i, path := range fs.Args() {
+		entries, err := explainFile(path, *city)
+		if err != nil {
+			return err
+		}
+		sides[i] = entries
+	}
+	changes := cfg.Diff(sides[0], sides[1])
+	if *asJSON {
+		return printJSON(changes)
+	}
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	fmt.Printf("%d difference(s)\n", len(changes))
+	return nil
+}
+
+func explainFile(path, city string) ([]cfg.Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rd, err := cfg.ParseDefaults(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := cfg.ApplyCityOverlay(&rd, filepath.Join(filepath.Dir(path), "cities"), city); err != nil {
+		return nil, err
+	}
+	if err := rd.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg.Explain(rd, rd.ForCity(city))
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -2,24 +2,185 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/asl"
 	b "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/budget"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/cache"
 	cfg "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/config"
 	obs "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/observability"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/queue"
 	wf "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/workflow"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
 )
 
 func main() {
+	command, args := "run", os.Args[1:]
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		command, args = args[0], args[1:]
+	}
+
+	var err error
+	switch command {
+	case "run":
+		err = runCity(args)
+	case "validate-asl":
+		err = validateASL(args)
+	case "config":
+		err = configCmd(args)
+	default:
+		fmt.Printf("Unknown command: %s\n", command)
+		printUsage()
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Printf("cityjob %s: %v", command, err)
+		os.Exit(1)
+	}
+}
+
+// cityReloader reads defaults the way every command does: from
+// CONFIG_SSM_PARAMETER or CONFIG_PATH, with city's overlay from
+// CITY_CONFIG_DIR and the env overrides layered on. Each load sets *overlay
+// to the overlay file applied, if any.
+func cityReloader(ctx context.Context, city string, overlay *string, opts ...cfg.ReloaderOption) (*cfg.Reloader, cfg.Source, error) {
+	defaultPath := filepath.Join("config", "defaults.yaml")
+	if env := os.Getenv("CONFIG_PATH"); env != "" {
+		defaultPath = env
+	}
+	cityDir := filepath.Join(filepath.Dir(defaultPath), "cities")
+	if env := os.Getenv("CITY_CONFIG_DIR"); env != "" {
+		cityDir = env
+	}
+	src, err := configSource(ctx, defaultPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts = append(opts, cfg.WithPrepare(func(rd *cfg.RawDefaults) error {
+		var err error
+		if *overlay, err = cfg.ApplyCityOverlay(rd, cityDir, city); err != nil {
+			return err
+		}
+		cfg.ApplyEnvOverrides(rd)
+		return nil
+	}))
+	return cfg.NewReloader(src, opts...), src, nil
+}
+
+// loadConfig loads r's initial config, telling validation failures apart
+// from unreadable sources.
+func loadConfig(ctx context.Context, r *cfg.Reloader) (cfg.RawDefaults, error) {
+	rd, err := r.Load(ctx)
+	var invalid *cfg.ValidationError
+	if errors.As(err, &invalid) {
+		return rd, fmt.Errorf("invalid config: %w", err)
+	}
+	if err != nil {
+		return rd, fmt.Errorf("load config: %w", err)
+	}
+	return rd, nil
+}
+
+// configSource reads defaults from the SSM parameter named by
+// CONFIG_SSM_PARAMETER when set, and from path otherwise.
+func configSource(ctx context.Context, path string) (cfg.Source, error) {
+	param := os.Getenv("CONFIG_SSM_PARAMETER")
+	if param == "" {
+		return cfg.NewFileSource(path), nil
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("aws config: %w", err)
+	}
+	return cfg.NewSSMSource(ssm.NewFromConfig(awsCfg), param), nil
+}
+
+// seenStore keeps frontier fingerprints in the DynamoDB table named by
+// FRONTIER_SEEN_TABLE when set, so processes sharing RUN_ID drop each other's
+// duplicates, and in memory otherwise.
+func seenStore(ctx context.Context) (queue.SeenStore, error) {
+	table := os.Getenv("FRONTIER_SEEN_TABLE")
+	if table == "" {
+		return queue.NewMemorySeenStore(), nil
+	}
+	ttl, err := envDuration("FRONTIER_SEEN_TTL")
+	if err != nil {
+		return nil, err
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("aws config: %w", err)
+	}
+	store := queue.NewDynamoSeenStore(dynamodb.NewFromConfig(awsCfg), table)
+	store.TTL = ttl
+	return store, nil
+}
+
+func envDuration(key string) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %q is not a valid duration", key, v)
+	}
+	return d, nil
+}
+
+func printUsage() {
+	fmt.Println("City job runner for Jaunt Data Scout")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  cityjob [run] [--city <name>] [--fail-fast]")
+	fmt.Println("    Run a full city job in process with mocked states and in-memory queue/cache")
+	fmt.Println()
+	fmt.Println("  cityjob config [explain] [--city <name>] [--input <input.json>] [--json]")
+	fmt.Println("    Print the effective config for a city, each value annotated with the layer that set it")
+	fmt.Println()
+	fmt.Println("  cityjob config diff [--city <name>] [--json] <from.yaml> <to.yaml>")
+	fmt.Println("    Compare two environments' defaults files (each with its own cities/ overlay, no env overrides)")
+	fmt.Println()
+	fmt.Println("  cityjob validate-asl [--definition <path>] [--terraform <main.tf>] [--input <input.json>]...")
+	fmt.Println("    Statically validate the Step Functions definition (offline)")
+	fmt.Println()
+	fmt.Println("Environment Variables:")
+	fmt.Println("  CONFIG_PATH - defaults.yaml location (default: config/defaults.yaml)")
+	fmt.Println("  RUN_ID - run identifier; processes sharing it share budget state (default: cityjob-run-<unix>)")
+	fmt.Println("  BUDGET_STATE_DIR - keep budget state in <dir>/<run_id>.json instead of memory")
+	fmt.Println("  CONFIG_SSM_PARAMETER - read defaults.yaml from this SSM parameter instead of CONFIG_PATH")
+	fmt.Println("  CONFIG_RELOAD_INTERVAL - poll the config source this often and apply budget/kill switch changes (default: off)")
+	fmt.Println("  FRONTIER_SEEN_TABLE - DynamoDB table (run_id, fingerprint) for frontier dedup shared across processes (default: in memory)")
+	fmt.Println("  FRONTIER_SEEN_TTL - stamp seen fingerprints with expires_at this far ahead for DynamoDB TTL (default: off)")
+	fmt.Println("  CITY_CONFIG_DIR - per-city overlays <dir>/<city>.yaml (default: cities/ next to CONFIG_PATH)")
+	fmt.Println()
+}
+
+func runCity(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	city := fs.String("city", "Edinburgh", "city to run")
+	failFast := fs.Bool("fail-fast", false, "route caught task errors to the DLQ instead of Finalize")
+	fs.Parse(args)
+
 	ctx := context.Background()
 
 	// Ensure we have a correlation_id for this execution
 	ctx = obs.EnsureCorrelationID(ctx)
-	ctx = context.WithValue(ctx, "run_id", fmt.Sprintf("cityjob-run-%d", time.Now().Unix()))
+	runID := fmt.Sprintf("cityjob-run-%d", time.Now().Unix())
+	if env := os.Getenv("RUN_ID"); env != "" {
+		runID = env
+	}
+	ctx = context.WithValue(ctx, "run_id", runID)
 	ctx = context.WithValue(ctx, "split", "primary")
 
 	// Create logger with correlation_id
@@ -28,42 +189,142 @@ func main() {
 	logger.Printf("Starting cityjob execution")
 
 	// Load defaults
-	defaultPath := filepath.Join("config", "defaults.yaml")
-	if env := os.Getenv("CONFIG_PATH"); env != "" {
-		defaultPath = env
+	reloadEvery, err := envDuration("CONFIG_RELOAD_INTERVAL")
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	var reloadOpts []cfg.ReloaderOption
+	if reloadEvery > 0 {
+		reloadOpts = append(reloadOpts, cfg.WithInterval(reloadEvery))
 	}
-	rd, err := cfg.LoadDefaults(defaultPath)
+	var overlay string
+	reloader, src, err := cityReloader(ctx, *city, &overlay, reloadOpts...)
 	if err != nil {
-		logger.Printf("failed to load config: %v", err)
-		return
+		return fmt.Errorf("load config: %w", err)
 	}
-	cfg.ApplyEnvOverrides(&rd)
+	rd, err := loadConfig(ctx, reloader)
+	if err != nil {
+		return err
+	}
+	cityCfg := rd.ForCity(*city)
 	bcfg := cfg.BuildBudgetConfig(rd)
-	guard := b.NewGuard(bcfg)
-	guard.Rebalance()
+	flags := cfg.NewFlags(rd.FeatureFlags)
+	guardOpts := []b.GuardOption{b.WithConnectorGate(func(ctx context.Context, c b.Connector) bool {
+		return flags.IsEnabled(ctx, cfg.ConnectorFlag(c))
+	})}
+	if dir := os.Getenv("BUDGET_STATE_DIR"); dir != "" {
+		store, err := b.NewFileStore(dir)
+		if err != nil {
+			return fmt.Errorf("budget state: %w", err)
+		}
+		guardOpts = append(guardOpts, b.WithStore(store, runID))
+	}
+	guard := b.NewGuard(bcfg, guardOpts...)
+	if err := guard.Rebalance(ctx); err != nil {
+		return fmt.Errorf("budget rebalance: %w", err)
+	}
 
-	logger.Printf("Loaded config successfully")
+	logger.Printf("Configuration loaded from %s: %s", src.Name(), rd.String())
+	if overlay != "" {
+		logger.Printf("City overlay applied: %s", overlay)
+	}
 
-	// Example workflow guard wired from config
+	// Workflow guard wired from config; the runner re-applies the caps after
+	// layering the execution input.
 	bg := wf.BudgetGuard{
-		MaxAPICalls:      rd.CityDefaults.Budgets.MaxAPICalls,
-		MaxWallClock:     time.Duration(rd.CityDefaults.Budgets.MaxWallClockHours) * time.Hour,
-		MinNewUniqueRate: rd.CityDefaults.EarlyStop.MinNewUniqueRate,
+		MaxAPICalls:      cityCfg.Budgets.MaxAPICalls,
+		MaxWallClock:     time.Duration(cityCfg.Budgets.MaxWallClockHours) * time.Hour,
+		MinNewUniqueRate: cityCfg.EarlyStop.MinNewUniqueRate,
+		MaxCostUSD:       cityCfg.Budgets.MaxCostUSD,
+		Windows:          b.NewWindowSet(cityCfg.EarlyStop.Window, cityCfg.EarlyStop.MinSamples),
 		StartTime:        time.Now(),
 	}
 
-	stats := wf.Stats{}
-	shouldStop := bg.ShouldStop(ctx, stats)
-	
-	logger.Printf("Configuration loaded: %s", rd.String())
-	logger.Printf("Budget check (initial): shouldStop=%v", shouldStop)
-
-	// Demonstrate EMF metrics emission
-	obs.CountCall(ctx, "cityjob", "initialize", "config", "edinburgh")
-	obs.RecordDurationMS(ctx, "cityjob", "initialize", "config", "edinburgh", 50.0)
-	
-	logger.Printf("EMF metrics emitted for initialization")
-	logger.Printf("Cityjob execution completed")
-	
-	_ = guard // placeholder to suppress unused; in real states Acquire() would be used per connector
+	seen, err := seenStore(ctx)
+	if err != nil {
+		return fmt.Errorf("frontier dedup: %w", err)
+	}
+	frontierQ := queue.NewDedupQueue(queue.NewMemoryPriorityQueue(queue.MemoryOptions{}, rd.FrontierLanes), seen, runID, cityCfg.H3Res)
+
+	runner := wf.NewLocalRunner(frontierQ, cache.NewMemoryCache(), bg)
+	runner.FailFast = *failFast
+	runner.Logger = logger
+	runner.Budget = guard
+	runner.Config = &cityCfg
+	runner.Flags = flags
+
+	reportCtx, stopReport := context.WithCancel(ctx)
+	defer stopReport()
+	go guard.ReportEvery(reportCtx, time.Minute, "cityjob", "run", *city)
+	if reloadEvery > 0 {
+		reloader.Subscribe(cfg.BudgetSubscriber(guard))
+		reloader.Subscribe(cfg.FlagsSubscriber(flags))
+		go reloader.Run(reportCtx, func(err error) {
+			logger.Printf("Config reload: %v", err)
+		})
+	}
+
+	start := time.Now()
+	exec, err := runner.Run(ctx, map[string]any{"city": *city})
+	if err != nil {
+		return err
+	}
+	obs.RecordDurationMS(ctx, "cityjob", "run", "local", *city, float64(time.Since(start).Milliseconds()))
+
+	logger.Printf("Run %s finished: states=%d api_calls=%d cost_usd=%.2f stopped_by=%q stop_reason=%q dead_lettered=%v duplicates_dropped=%d",
+		exec.RunID, len(exec.History), exec.Stats.APICalls, exec.Stats.CostUSD, exec.StoppedBy, exec.Decision.Reason, exec.DeadLettered, frontierQ.Dropped())
+	if exec.DeadLettered {
+		return wf.ErrDeadLettered
+	}
+	return nil
+}
+
+// stringList collects a repeatable string flag.
+type stringList []string
+
+func (s *stringList) String() string     { return strings.Join(*s, ",") }
+func (s *stringList) Set(v string) error { *s = append(*s, v); return nil }
+
+func validateASL(args []string) error {
+	fs := flag.NewFlagSet("validate-asl", flag.ExitOnError)
+	definition := fs.String("definition", filepath.Join("..", "terraform", "sfn", "definition.asl.json"), "ASL definition to validate")
+	terraform := fs.String("terraform", filepath.Join("..", "terraform", "sfn", "main.tf"), "terraform file whose templatefile() call renders the definition; empty skips the check")
+	var inputs stringList
+	fs.Var(&inputs, "input", "execution input JSON whose top-level keys are assumed present (repeatable)")
+	fs.Parse(args)
+
+	var opts asl.ValidateOptions
+	if *terraform != "" {
+		vars, err := asl.TerraformTemplateVars(*terraform)
+		if err != nil {
+			return err
+		}
+		opts.TemplateVars = vars
+	}
+	for _, path := range inputs {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var input map[string]any
+		if err := json.Unmarshal(data, &input); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for k := range input {
+			opts.InputPaths = append(opts.InputPaths, "$."+k)
+		}
+	}
+
+	issues, err := asl.ValidateFile(*definition, opts)
+	if err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("%s: %d issue(s)", *definition, len(issues))
+	}
+	fmt.Printf("%s: OK\n", *definition)
+	return nil
 }
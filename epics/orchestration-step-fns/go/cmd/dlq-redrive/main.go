@@ -11,10 +11,10 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
-	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 
 	obs "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/observability"
 	frontier "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/frontier"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/queue"
 )
 
 const (
@@ -32,13 +32,15 @@ type Config struct {
 }
 
 type DLQMessage struct {
-	ReceiptHandle string                 `json:"-"`
 	MessageId     string                 `json:"message_id"`
 	Body          string                 `json:"body"`
 	Attributes    map[string]string      `json:"attributes"`
 	CorrelationID string                 `json:"correlation_id"`
+	ReceiveCount  int                    `json:"receive_count"`
 	ParsedBody    interface{}            `json:"parsed_body,omitempty"`
 	Error         string                 `json:"error,omitempty"`
+
+	delivery *queue.Delivery
 }
 
 func main() {
@@ -102,8 +104,9 @@ func handleList() {
 	}
 	
 	sqsClient := sqs.NewFromConfig(awsCfg)
+	dlq := newDLQ(sqsClient, cfg)
 	
-	messages, err := receiveDLQMessages(ctx, sqsClient, cfg)
+	messages, err := receiveDLQMessages(ctx, dlq, cfg)
 	if err != nil {
 		logger.Printf("Failed to receive DLQ messages: %v", err)
 		os.Exit(1)
@@ -125,7 +128,8 @@ func handleList() {
 		if err := parseMessageBody(&msg); err != nil {
 			fmt.Printf("  Parse Error: %s\n", err.Error())
 		} else {
-			if envelope, ok := msg.ParsedBody.(frontier.Envelope); ok {
+			if m, ok := msg.ParsedBody.(frontier.Message); ok {
+				envelope := m.Header()
 				fmt.Printf("  Type: %s\n", envelope.Type)
 				fmt.Printf("  City: %s\n", envelope.City)
 				fmt.Printf("  Enqueued At: %s\n", time.Unix(envelope.EnqueuedAt, 0).Format(time.RFC3339))
@@ -152,8 +156,9 @@ func handleInspect() {
 	}
 	
 	sqsClient := sqs.NewFromConfig(awsCfg)
+	dlq := newDLQ(sqsClient, cfg)
 	
-	messages, err := receiveDLQMessages(ctx, sqsClient, cfg)
+	messages, err := receiveDLQMessages(ctx, dlq, cfg)
 	if err != nil {
 		logger.Printf("Failed to receive DLQ messages: %v", err)
 		os.Exit(1)
@@ -204,8 +209,9 @@ func handleRedrive() {
 	}
 	
 	sqsClient := sqs.NewFromConfig(awsCfg)
+	dlq := newDLQ(sqsClient, cfg)
 	
-	messages, err := receiveDLQMessages(ctx, sqsClient, cfg)
+	messages, err := receiveDLQMessages(ctx, dlq, cfg)
 	if err != nil {
 		logger.Printf("Failed to receive DLQ messages: %v", err)
 		os.Exit(1)
@@ -224,7 +230,7 @@ func handleRedrive() {
 		os.Exit(1)
 	}
 	
-	if err := redriveMessage(ctx, sqsClient, cfg, *targetMessage, logger); err != nil {
+	if err := redriveMessage(ctx, newFrontier(sqsClient, cfg), cfg, *targetMessage, logger); err != nil {
 		logger.Printf("Failed to redrive message: %v", err)
 		os.Exit(1)
 	}
@@ -250,8 +256,9 @@ func handleRedriveAll() {
 	}
 	
 	sqsClient := sqs.NewFromConfig(awsCfg)
+	dlq := newDLQ(sqsClient, cfg)
 	
-	messages, err := receiveDLQMessages(ctx, sqsClient, cfg)
+	messages, err := receiveDLQMessages(ctx, dlq, cfg)
 	if err != nil {
 		logger.Printf("Failed to receive DLQ messages: %v", err)
 		os.Exit(1)
@@ -263,6 +270,7 @@ func handleRedriveAll() {
 	}
 	
 	fmt.Printf("Found %d message(s) to redrive\n", len(messages))
+	frontierQueue := newFrontier(sqsClient, cfg)
 	
 	redriveCount := 0
 	errorCount := 0
@@ -270,7 +278,7 @@ func handleRedriveAll() {
 	for _, msg := range messages {
 		fmt.Printf("Redriving message %s (correlation_id: %s)...", msg.MessageId, msg.CorrelationID)
 		
-		if err := redriveMessage(ctx, sqsClient, cfg, msg, logger); err != nil {
+		if err := redriveMessage(ctx, frontierQueue, cfg, msg, logger); err != nil {
 			fmt.Printf(" ERROR: %v\n", err)
 			errorCount++
 		} else {
@@ -282,46 +290,62 @@ func handleRedriveAll() {
 	fmt.Printf("\nCompleted: %d successful, %d errors\n", redriveCount, errorCount)
 }
 
-func receiveDLQMessages(ctx context.Context, sqsClient *sqs.Client, cfg Config) ([]DLQMessage, error) {
-	input := &sqs.ReceiveMessageInput{
-		QueueUrl:            &cfg.DLQUrl,
-		MaxNumberOfMessages: cfg.MaxMessages,
-		WaitTimeSeconds:     cfg.WaitTime,
-		MessageAttributeNames: []string{"All"},
-		AttributeNames: []types.QueueAttributeName{
-			types.QueueAttributeNameAll,
-		},
-	}
-	
-	result, err := sqsClient.ReceiveMessage(ctx, input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to receive messages: %w", err)
-	}
-	
+// newDLQ reads the DLQ, keeping bodies that are not valid frontier messages
+// so they can be listed and inspected.
+func newDLQ(sqsClient *sqs.Client, cfg Config) *queue.SQSQueue {
+	return queue.NewSQSQueue(sqsClient, queue.SQSOptions{
+		QueueURL:        cfg.DLQUrl,
+		WaitTime:        time.Duration(cfg.WaitTime) * time.Second,
+		KeepUndecodable: true,
+	})
+}
+
+func newFrontier(sqsClient *sqs.Client, cfg Config) *queue.SQSQueue {
+	return queue.NewSQSQueue(sqsClient, queue.SQSOptions{QueueURL: cfg.FrontierUrl, DLQURL: cfg.DLQUrl})
+}
+
+// receiveDLQMessages receives up to cfg.MaxMessages messages, one SQS batch at
+// a time, stopping early when the DLQ returns nothing.
+func receiveDLQMessages(ctx context.Context, dlq queue.FrontierQueue, cfg Config) ([]DLQMessage, error) {
 	var messages []DLQMessage
-	for _, msg := range result.Messages {
-		dlqMsg := DLQMessage{
-			ReceiptHandle: *msg.ReceiptHandle,
-			MessageId:     *msg.MessageId,
-			Body:          *msg.Body,
-			Attributes:    make(map[string]string),
+	for remaining := int(cfg.MaxMessages); remaining > 0; {
+		deliveries, err := dlq.Dequeue(ctx, min(remaining, queue.MaxBatch))
+		if err != nil {
+			return nil, fmt.Errorf("failed to receive messages: %w", err)
 		}
-		
-		// Extract attributes
-		for name, value := range msg.Attributes {
-			dlqMsg.Attributes[name] = value
+		if len(deliveries) == 0 {
+			break
 		}
+		remaining -= len(deliveries)
 		
-		// Extract correlation_id from message attributes
-		dlqMsg.CorrelationID = obs.ReadCorrelationIDFromSQS(&msg)
-		
-		messages = append(messages, dlqMsg)
+		for _, d := range deliveries {
+			dlqMsg := DLQMessage{
+				MessageId:     d.ID,
+				Body:          string(d.Body),
+				Attributes:    make(map[string]string),
+				CorrelationID: d.Attributes[obs.CorrelationIDAttribute],
+				ReceiveCount:  d.ReceiveCount,
+				delivery:      d,
+			}
+			
+			// Extract attributes
+			for name, value := range d.Attributes {
+				dlqMsg.Attributes[name] = value
+			}
+			
+			// Fall back to the envelope when the attribute was not stamped
+			if dlqMsg.CorrelationID == "" && d.Message != nil {
+				dlqMsg.CorrelationID = d.Message.Header().CorrelationID
+			}
+			
+			messages = append(messages, dlqMsg)
+		}
 	}
 	
 	return messages, nil
 }
 
-func redriveMessage(ctx context.Context, sqsClient *sqs.Client, cfg Config, msg DLQMessage, logger *obs.CorrelationLogger) error {
+func redriveMessage(ctx context.Context, frontierQueue queue.RawEnqueuer, cfg Config, msg DLQMessage, logger *obs.CorrelationLogger) error {
 	// Validate message body can be parsed
 	if err := parseMessageBody(&msg); err != nil {
 		return fmt.Errorf("message validation failed: %w", err)
@@ -337,20 +361,16 @@ func redriveMessage(ctx context.Context, sqsClient *sqs.Client, cfg Config, msg
 		return nil
 	}
 	
-	// Re-enqueue to frontier with correlation_id
+	// Re-enqueue the original body with correlation_id; re-encoding the parsed
+	// message would drop fields the Go types do not model
 	ctx = obs.WithCorrelationID(ctx, msg.CorrelationID)
 	
-	_, err := obs.SQSPublishWithCorrelationID(ctx, sqsClient, cfg.FrontierUrl, msg.Body, nil)
-	if err != nil {
+	if err := frontierQueue.EnqueueRaw(ctx, []byte(msg.Body)); err != nil {
 		return fmt.Errorf("failed to enqueue to frontier: %w", err)
 	}
 	
 	// Delete from DLQ only after successful enqueue
-	_, err = sqsClient.DeleteMessage(ctx, &sqs.DeleteMessageInput{
-		QueueUrl:      &cfg.DLQUrl,
-		ReceiptHandle: &msg.ReceiptHandle,
-	})
-	if err != nil {
+	if err := msg.delivery.Ack(ctx); err != nil {
 		return fmt.Errorf("failed to delete from DLQ (message was redriven): %w", err)
 	}
 	
@@ -376,7 +396,7 @@ func parseMessageBody(msg *DLQMessage) error {
 			msg.Error = fmt.Sprintf("Maps message parse error: %v", err)
 			return err
 		}
-		if err := mapsMsg.Validate(); err != nil {
+		if err := frontier.ValidateBody([]byte(msg.Body)); err != nil {
 			msg.Error = fmt.Sprintf("Maps message validation error: %v", err)
 			return err
 		}
@@ -387,7 +407,7 @@ func parseMessageBody(msg *DLQMessage) error {
 			msg.Error = fmt.Sprintf("Web message parse error: %v", err)
 			return err
 		}
-		if err := webMsg.Validate(); err != nil {
+		if err := frontier.ValidateBody([]byte(msg.Body)); err != nil {
 			msg.Error = fmt.Sprintf("Web message validation error: %v", err)
 			return err
 		}
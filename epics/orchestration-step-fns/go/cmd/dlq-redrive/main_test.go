@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"log"
 	"testing"
 	"time"
 
@@ -9,16 +11,18 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	frontier "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/frontier"
+	obs "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/observability"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/queue"
 )
 
 func TestParseMessageBody_ValidMapsMessage(t *testing.T) {
 	// Create a valid maps message
-	envelope := frontier.NewEnvelope("maps", "edinburgh", "test-correlation-123")
+	envelope := frontier.NewEnvelope("maps", "edinburgh", "0b1f6a2e-6f7c-4d0e-9a53-5b0c2f1e7a10")
 	mapsMsg := frontier.MapsMessage{
 		Envelope: envelope,
 		Lat:      55.9533,
 		Lng:      -3.1883,
-		Rad:      1000.0,
+		Rad:      1000,
 		Cat:      nil,
 	}
 
@@ -28,7 +32,7 @@ func TestParseMessageBody_ValidMapsMessage(t *testing.T) {
 	dlqMsg := DLQMessage{
 		MessageId:     "msg-123",
 		Body:          string(body),
-		CorrelationID: "test-correlation-123",
+		CorrelationID: "0b1f6a2e-6f7c-4d0e-9a53-5b0c2f1e7a10",
 	}
 
 	// Test parsing
@@ -47,12 +51,12 @@ func TestParseMessageBody_ValidMapsMessage(t *testing.T) {
 
 func TestParseMessageBody_ValidWebMessage(t *testing.T) {
 	// Create a valid web message
-	envelope := frontier.NewEnvelope("web", "edinburgh", "test-correlation-456")
+	envelope := frontier.NewEnvelope("web", "edinburgh", "0b1f6a2e-6f7c-4d0e-9a53-5b0c2f1e7a11")
 	webMsg := frontier.WebMessage{
 		Envelope:   envelope,
 		SourceURL:  "https://example.com",
 		SourceName: "example",
-		SourceType: "restaurant",
+		SourceType: "html",
 		CrawlDepth: 1,
 	}
 
@@ -62,7 +66,7 @@ func TestParseMessageBody_ValidWebMessage(t *testing.T) {
 	dlqMsg := DLQMessage{
 		MessageId:     "msg-456",
 		Body:          string(body),
-		CorrelationID: "test-correlation-456",
+		CorrelationID: "0b1f6a2e-6f7c-4d0e-9a53-5b0c2f1e7a11",
 	}
 
 	// Test parsing
@@ -234,6 +238,66 @@ func TestRedriveSafety_CorrelationIDRequired(t *testing.T) {
 	}
 }
 
+func TestRedriveMessage_MovesDeliveryToFrontier(t *testing.T) {
+	ctx := context.Background()
+	logger := obs.LogWithCorrelationID(ctx, log.Default())
+	frontierQueue := queue.NewMemoryQueue(queue.MemoryOptions{})
+	dlq := frontierQueue.DLQ()
+
+	mapsMsg := frontier.MapsMessage{
+		Envelope: frontier.NewEnvelope("maps", "edinburgh", "0b1f6a2e-6f7c-4d0e-9a53-5b0c2f1e7a12"),
+		Lat:      55.9533,
+		Lng:      -3.1883,
+		Rad:      1000,
+	}
+	assert.NoError(t, frontierQueue.DeadLetter(ctx, mapsMsg, "TileSweep failed"))
+	assert.NoError(t, frontierQueue.DeadLetter(ctx, map[string]interface{}{"city": "edinburgh"}, "Rank failed"))
+
+	messages, err := receiveDLQMessages(ctx, dlq, Config{MaxMessages: 50})
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "0b1f6a2e-6f7c-4d0e-9a53-5b0c2f1e7a12", messages[0].CorrelationID, "falls back to the envelope")
+	assert.Equal(t, "TileSweep failed", messages[0].Attributes[queue.DeadLetterReasonAttribute])
+
+	// Dry run neither enqueues nor acks
+	assert.NoError(t, redriveMessage(ctx, frontierQueue, Config{DryRun: true}, messages[0], logger))
+	visible, _, _ := frontierQueue.Counts()
+	assert.Equal(t, 0, visible)
+
+	assert.NoError(t, redriveMessage(ctx, frontierQueue, Config{}, messages[0], logger))
+	redriven, err := frontierQueue.Dequeue(ctx, 1)
+	assert.NoError(t, err)
+	assert.Len(t, redriven, 1)
+	assert.Equal(t, mapsMsg, redriven[0].Message)
+
+	// Non-frontier payloads are listed but never redriven
+	assert.Error(t, redriveMessage(ctx, frontierQueue, Config{}, messages[1], logger))
+	_, inFlight, _ := dlq.Counts()
+	assert.Equal(t, 1, inFlight, "only the redriven message was deleted from the DLQ")
+}
+
+func TestRedriveMessage_KeepsUnmodeledFields(t *testing.T) {
+	ctx := context.Background()
+	logger := obs.LogWithCorrelationID(ctx, log.Default())
+	frontierQueue := queue.NewMemoryQueue(queue.MemoryOptions{})
+
+	body := `{"type":"web","city":"Edinburgh","source_url":"https://www.edinburgh.gov.uk/attractions","source_name":"Edinburgh Attractions","source_type":"html","crawl_depth":2,"correlation_id":"f47ac10b-58cc-4372-a567-0e02b2c3d479","priority":"high","timeout_seconds":600,"metadata":{"domain_authority":"gov","content_type_hint":"text/html"}}`
+	assert.NoError(t, frontierQueue.DeadLetter(ctx, json.RawMessage(body), "WebFetch failed"))
+
+	messages, err := receiveDLQMessages(ctx, frontierQueue.DLQ(), Config{MaxMessages: 10})
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.NoError(t, redriveMessage(ctx, frontierQueue, Config{}, messages[0], logger))
+
+	redriven, err := frontierQueue.Dequeue(ctx, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, redriven, 1) {
+		assert.JSONEq(t, body, string(redriven[0].Body))
+		assert.Contains(t, string(redriven[0].Body), `"priority":"high"`)
+		assert.Contains(t, string(redriven[0].Body), `"metadata":{"domain_authority":"gov"`)
+	}
+}
+
 // Helper function for string pointers
 func stringPtr(s string) *string {
 	return &s
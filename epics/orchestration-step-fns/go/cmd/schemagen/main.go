@@ -0,0 +1,59 @@
+// Command schemagen writes Go structs for JSON schemas; internal/types runs it
+// through go generate.
+//
+//	schemagen -pkg types -out schemas_gen.go ../schema/schemas
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/schemagen"
+)
+
+func main() {
+	pkg := flag.String("pkg", "types", "package name of the generated file")
+	out := flag.String("out", "", "file to write (default: stdout)")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: schemagen [-pkg name] [-out file] <schema.json | dir>...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var inputs []schemagen.Input
+	for _, arg := range flag.Args() {
+		paths := []string{arg}
+		if info, err := os.Stat(arg); err == nil && info.IsDir() {
+			if paths, err = filepath.Glob(filepath.Join(arg, "*.json")); err != nil {
+				log.Fatalf("schemagen: %v", err)
+			}
+		}
+		for _, p := range paths {
+			data, err := os.ReadFile(p)
+			if err != nil {
+				log.Fatalf("schemagen: %v", err)
+			}
+			inputs = append(inputs, schemagen.Input{Name: strings.TrimSuffix(filepath.Base(p), ".json"), Data: data})
+		}
+	}
+
+	src, err := schemagen.Generate(*pkg, inputs)
+	if err != nil {
+		log.Fatalf("schemagen: %v", err)
+	}
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatalf("schemagen: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package asl
+
+import (
+	"errors"
+	"fmt"
+)
+
+// evalRule evaluates a Choice rule against the state's effective input. A
+// Variable that does not resolve is a States.Runtime error, except for IsPresent.
+func evalRule(r ChoiceRule, input any) (bool, error) {
+	switch {
+	case len(r.And) > 0:
+		for _, sub := range r.And {
+			ok, err := evalRule(sub, input)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case len(r.Or) > 0:
+		for _, sub := range r.Or {
+			ok, err := evalRule(sub, input)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case r.Not != nil:
+		ok, err := evalRule(*r.Not, input)
+		return !ok, err
+	}
+
+	if r.Variable == "" {
+		return false, fmt.Errorf("choice rule has no Variable")
+	}
+	v, err := getPath(input, r.Variable)
+	if r.IsPresent != nil {
+		var nf errPathNotFound
+		if err != nil && !errors.As(err, &nf) {
+			return false, err
+		}
+		return (err == nil) == *r.IsPresent, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("choice variable %s: %w", r.Variable, err)
+	}
+
+	switch {
+	case r.IsNull != nil:
+		return (v == nil) == *r.IsNull, nil
+	case r.BooleanEquals != nil:
+		b, ok := v.(bool)
+		return ok && b == *r.BooleanEquals, nil
+	case r.StringEquals != nil, r.StringLessThan != nil, r.StringGreaterThan != nil:
+		s, ok := v.(string)
+		if !ok {
+			return false, nil
+		}
+		switch {
+		case r.StringEquals != nil:
+			return s == *r.StringEquals, nil
+		case r.StringLessThan != nil:
+			return s < *r.StringLessThan, nil
+		default:
+			return s > *r.StringGreaterThan, nil
+		}
+	}
+
+	n, ok := v.(float64)
+	if !ok {
+		return false, nil
+	}
+	switch {
+	case r.NumericEquals != nil:
+		return n == *r.NumericEquals, nil
+	case r.NumericLessThan != nil:
+		return n < *r.NumericLessThan, nil
+	case r.NumericLessThanEquals != nil:
+		return n <= *r.NumericLessThanEquals, nil
+	case r.NumericGreaterThan != nil:
+		return n > *r.NumericGreaterThan, nil
+	case r.NumericGreaterThanEquals != nil:
+		return n >= *r.NumericGreaterThanEquals, nil
+	}
+	return false, fmt.Errorf("choice rule on %s has no supported comparison operator", r.Variable)
+}
@@ -0,0 +1,166 @@
+// Package asl parses and executes the Amazon States Language definition in
+// terraform/sfn/definition.asl.json so the state machine can be exercised
+// without deploying Step Functions.
+package asl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// State types supported by the interpreter.
+const (
+	TypePass    = "Pass"
+	TypeTask    = "Task"
+	TypeChoice  = "Choice"
+	TypeSucceed = "Succeed"
+	TypeFail    = "Fail"
+)
+
+// Definition is a parsed state machine.
+type Definition struct {
+	Comment        string            `json:"Comment,omitempty"`
+	StartAt        string            `json:"StartAt"`
+	TimeoutSeconds int               `json:"TimeoutSeconds,omitempty"`
+	States         map[string]*State `json:"States"`
+}
+
+// State is the union of the fields used by the supported state types.
+type State struct {
+	Type    string `json:"Type"`
+	Comment string `json:"Comment,omitempty"`
+	Next    string `json:"Next,omitempty"`
+	End     bool   `json:"End,omitempty"`
+
+	InputPath  Path            `json:"InputPath,omitempty"`
+	OutputPath Path            `json:"OutputPath,omitempty"`
+	ResultPath Path            `json:"ResultPath,omitempty"`
+	Parameters json.RawMessage `json:"Parameters,omitempty"`
+	Result     json.RawMessage `json:"Result,omitempty"`
+
+	// Task
+	Resource       string    `json:"Resource,omitempty"`
+	TimeoutSeconds int       `json:"TimeoutSeconds,omitempty"`
+	Retry          []Retrier `json:"Retry,omitempty"`
+	Catch          []Catcher `json:"Catch,omitempty"`
+
+	// Choice
+	Choices []ChoiceRule `json:"Choices,omitempty"`
+	Default string       `json:"Default,omitempty"`
+
+	// Fail
+	Error string `json:"Error,omitempty"`
+	Cause string `json:"Cause,omitempty"`
+}
+
+// Path is an optional JSONPath field. ASL distinguishes an absent path (defaults
+// to "$") from an explicit null (discard), so both are tracked.
+type Path struct {
+	Value string
+	Set   bool // field present in the definition
+	Null  bool // field explicitly set to null
+}
+
+func (p *Path) UnmarshalJSON(data []byte) error {
+	p.Set = true
+	if string(data) == "null" {
+		p.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &p.Value)
+}
+
+func (p Path) MarshalJSON() ([]byte, error) {
+	if p.Null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(p.Value)
+}
+
+// orDefault returns the path, "$" when absent, or "" when explicitly null.
+func (p Path) orDefault() string {
+	if p.Null {
+		return ""
+	}
+	if !p.Set || p.Value == "" {
+		return "$"
+	}
+	return p.Value
+}
+
+// Retrier is a single Retry entry.
+type Retrier struct {
+	ErrorEquals     []string `json:"ErrorEquals"`
+	IntervalSeconds *float64 `json:"IntervalSeconds,omitempty"`
+	MaxAttempts     *int     `json:"MaxAttempts,omitempty"`
+	BackoffRate     *float64 `json:"BackoffRate,omitempty"`
+}
+
+// Catcher is a single Catch entry.
+type Catcher struct {
+	ErrorEquals []string `json:"ErrorEquals"`
+	ResultPath  Path     `json:"ResultPath,omitempty"`
+	Next        string   `json:"Next"`
+}
+
+// ChoiceRule is a Choice state rule; top-level rules carry Next, nested And/Or/Not rules do not.
+type ChoiceRule struct {
+	Variable string `json:"Variable,omitempty"`
+	Next     string `json:"Next,omitempty"`
+
+	And []ChoiceRule `json:"And,omitempty"`
+	Or  []ChoiceRule `json:"Or,omitempty"`
+	Not *ChoiceRule  `json:"Not,omitempty"`
+
+	StringEquals             *string  `json:"StringEquals,omitempty"`
+	StringLessThan           *string  `json:"StringLessThan,omitempty"`
+	StringGreaterThan        *string  `json:"StringGreaterThan,omitempty"`
+	NumericEquals            *float64 `json:"NumericEquals,omitempty"`
+	NumericLessThan          *float64 `json:"NumericLessThan,omitempty"`
+	NumericLessThanEquals    *float64 `json:"NumericLessThanEquals,omitempty"`
+	NumericGreaterThan       *float64 `json:"NumericGreaterThan,omitempty"`
+	NumericGreaterThanEquals *float64 `json:"NumericGreaterThanEquals,omitempty"`
+	BooleanEquals            *bool    `json:"BooleanEquals,omitempty"`
+	IsPresent                *bool    `json:"IsPresent,omitempty"`
+	IsNull                   *bool    `json:"IsNull,omitempty"`
+}
+
+var templateVar = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)\}`)
+
+// Render substitutes terraform templatefile variables (${name}). Unknown
+// variables are left untouched so resources can be registered by placeholder.
+func Render(data []byte, vars map[string]string) []byte {
+	return templateVar.ReplaceAllFunc(data, func(m []byte) []byte {
+		name := string(templateVar.FindSubmatch(m)[1])
+		if v, ok := vars[name]; ok {
+			return []byte(v)
+		}
+		return m
+	})
+}
+
+// Parse decodes an ASL document.
+func Parse(data []byte) (*Definition, error) {
+	var def Definition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("parse asl: %w", err)
+	}
+	if def.StartAt == "" {
+		return nil, fmt.Errorf("parse asl: StartAt is required")
+	}
+	if _, ok := def.States[def.StartAt]; !ok {
+		return nil, fmt.Errorf("parse asl: StartAt state %q not defined", def.StartAt)
+	}
+	return &def, nil
+}
+
+// LoadFile reads, renders, and parses an ASL file. vars may be nil.
+func LoadFile(path string, vars map[string]string) (*Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(Render(data, vars))
+}
@@ -0,0 +1,540 @@
+package asl
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// ResourceSQSSendMessage is the optimized SQS integration resource.
+const ResourceSQSSendMessage = "arn:aws:states:::sqs:sendMessage"
+
+// Predefined ASL error names.
+const (
+	ErrorAll             = "States.ALL"
+	ErrorTaskFailed      = "States.TaskFailed"
+	ErrorTimeout         = "States.Timeout"
+	ErrorRuntime         = "States.Runtime"
+	ErrorNoChoiceMatched = "States.NoChoiceMatched"
+)
+
+// maxTransitions bounds an execution so a cycle in the definition cannot spin forever.
+const maxTransitions = 10000
+
+// Handler implements a Task resource. Input and output are plain JSON values.
+//
+// Handlers must honor ctx: it is canceled when the state's TimeoutSeconds
+// elapses or the execution ends, and the interpreter moves on without
+// waiting. A handler that ignores ctx keeps its goroutine running until it
+// returns on its own; its result is then discarded without blocking.
+type Handler func(ctx context.Context, input any) (any, error)
+
+// SendMessageFunc backs the sqs:sendMessage integration.
+type SendMessageFunc func(ctx context.Context, queueURL, body string) (messageID string, err error)
+
+// TaskError lets a handler fail with a specific ASL error name so Retry and
+// Catch can match on it. Other errors are reported as States.TaskFailed.
+type TaskError struct {
+	Name  string
+	Cause string
+}
+
+func (e *TaskError) Error() string { return e.Name + ": " + e.Cause }
+
+// Failure describes a failed execution.
+type Failure struct {
+	State string
+	Name  string
+	Cause string
+}
+
+func (f *Failure) Error() string {
+	return fmt.Sprintf("execution failed in %s: %s: %s", f.State, f.Name, f.Cause)
+}
+
+// EventType names an entry in the execution history.
+type EventType string
+
+const (
+	EventExecutionStarted   EventType = "ExecutionStarted"
+	EventExecutionSucceeded EventType = "ExecutionSucceeded"
+	EventExecutionFailed    EventType = "ExecutionFailed"
+	EventStateEntered       EventType = "StateEntered"
+	EventStateExited        EventType = "StateExited"
+	EventTaskSucceeded      EventType = "TaskSucceeded"
+	EventTaskFailed         EventType = "TaskFailed"
+	EventTaskRetried        EventType = "TaskRetried"
+	EventTaskCaught         EventType = "TaskCaught"
+	EventChoiceMatched      EventType = "ChoiceMatched"
+)
+
+// Event is a single entry in the execution history.
+type Event struct {
+	Type      EventType `json:"type"`
+	State     string    `json:"state,omitempty"`
+	Next      string    `json:"next,omitempty"`
+	Attempt   int       `json:"attempt,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Cause     string    `json:"cause,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Execution statuses.
+const (
+	StatusSucceeded = "SUCCEEDED"
+	StatusFailed    = "FAILED"
+)
+
+// Execution is the result of running a definition.
+type Execution struct {
+	ID      string
+	Status  string
+	Output  any
+	Failure *Failure
+	History []Event
+}
+
+// States returns the names of the states entered, in order.
+func (e *Execution) States() []string {
+	var out []string
+	for _, ev := range e.History {
+		if ev.Type == EventStateEntered {
+			out = append(out, ev.State)
+		}
+	}
+	return out
+}
+
+// Interpreter executes a Definition with Task resources mapped to Go handlers.
+type Interpreter struct {
+	def      *Definition
+	handlers map[string]Handler
+
+	// SendMessage backs sqs:sendMessage; executions fail with States.Runtime when unset.
+	SendMessage SendMessageFunc
+	// Sleep waits between retries; override in tests to avoid real delays.
+	Sleep func(ctx context.Context, d time.Duration) error
+	Now   func() time.Time
+}
+
+var executionSeq atomic.Int64
+
+func New(def *Definition) *Interpreter {
+	return &Interpreter{
+		def:      def,
+		handlers: make(map[string]Handler),
+		Sleep:    sleepCtx,
+		Now:      time.Now,
+	}
+}
+
+// Register maps a Task Resource (rendered ARN or raw ${placeholder}) to a handler.
+func (in *Interpreter) Register(resource string, h Handler) {
+	in.handlers[resource] = h
+}
+
+// FromLambda adapts a Lambda-style handler, such as lambdas/mock-go's, to a Handler.
+func FromLambda(fn func(ctx context.Context, event map[string]any) (map[string]any, error)) Handler {
+	return func(ctx context.Context, input any) (any, error) {
+		event, _ := input.(map[string]any)
+		if event == nil {
+			event = map[string]any{}
+		}
+		return fn(ctx, event)
+	}
+}
+
+// Execute runs the state machine from StartAt. The returned Execution is always
+// non-nil; the error is a *Failure when the execution failed.
+func (in *Interpreter) Execute(ctx context.Context, input any) (*Execution, error) {
+	exec := &Execution{ID: fmt.Sprintf("local:execution:%d", executionSeq.Add(1))}
+	if in.def.TimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(in.def.TimeoutSeconds)*time.Second)
+		defer cancel()
+	}
+
+	doc, err := normalize(input)
+	if err != nil {
+		return exec, in.fail(exec, "", ErrorRuntime, fmt.Sprintf("input is not JSON: %v", err))
+	}
+	if doc == nil {
+		doc = map[string]any{}
+	}
+	start := in.Now()
+	contextObj := map[string]any{
+		"Execution": map[string]any{"Id": exec.ID, "Input": doc, "StartTime": start.UTC().Format(time.RFC3339Nano)},
+		"State":     map[string]any{},
+	}
+	in.record(exec, Event{Type: EventExecutionStarted})
+
+	name := in.def.StartAt
+	for i := 0; ; i++ {
+		if i >= maxTransitions {
+			return exec, in.fail(exec, name, ErrorRuntime, "exceeded maximum number of state transitions")
+		}
+		st, ok := in.def.States[name]
+		if !ok {
+			return exec, in.fail(exec, name, ErrorRuntime, fmt.Sprintf("state %q is not defined", name))
+		}
+		contextObj["State"] = map[string]any{"Name": name, "EnteredTime": in.Now().UTC().Format(time.RFC3339Nano), "RetryCount": 0}
+		in.record(exec, Event{Type: EventStateEntered, State: name})
+
+		next, out, f := in.step(ctx, exec, name, st, doc, contextObj)
+		if f == nil && ctx.Err() != nil {
+			f = contextFailure(name, ctx.Err())
+		}
+		if f != nil {
+			return exec, in.fail(exec, f.State, f.Name, f.Cause)
+		}
+		in.record(exec, Event{Type: EventStateExited, State: name, Next: next})
+		if next == "" {
+			exec.Status = StatusSucceeded
+			exec.Output = out
+			in.record(exec, Event{Type: EventExecutionSucceeded})
+			return exec, nil
+		}
+		doc, name = out, next
+	}
+}
+
+// step runs one state and returns the next state name ("" when terminal) and its output.
+func (in *Interpreter) step(ctx context.Context, exec *Execution, name string, st *State, doc any, contextObj any) (string, any, *Failure) {
+	runtimeErr := func(err error) *Failure {
+		return &Failure{State: name, Name: ErrorRuntime, Cause: err.Error()}
+	}
+	effective, err := applyInputPath(doc, st.InputPath)
+	if err != nil {
+		return "", nil, runtimeErr(err)
+	}
+
+	switch st.Type {
+	case TypePass:
+		var result any = effective
+		switch {
+		case len(st.Result) > 0:
+			if err := json.Unmarshal(st.Result, &result); err != nil {
+				return "", nil, runtimeErr(err)
+			}
+		case len(st.Parameters) > 0:
+			if result, err = in.parameters(st, effective, contextObj); err != nil {
+				return "", nil, runtimeErr(err)
+			}
+		}
+		out, err := applyOutput(doc, result, st)
+		if err != nil {
+			return "", nil, runtimeErr(err)
+		}
+		return st.Next, out, nil
+
+	case TypeTask:
+		params := effective
+		if len(st.Parameters) > 0 {
+			if params, err = in.parameters(st, effective, contextObj); err != nil {
+				return "", nil, runtimeErr(err)
+			}
+		}
+		result, taskErr := in.runTask(ctx, exec, name, st, params)
+		if taskErr != nil {
+			if ctx.Err() != nil {
+				return "", nil, contextFailure(name, ctx.Err())
+			}
+			for _, c := range st.Catch {
+				if !ErrorMatches(c.ErrorEquals, taskErr.Name) {
+					continue
+				}
+				in.record(exec, Event{Type: EventTaskCaught, State: name, Next: c.Next, Error: taskErr.Name, Cause: taskErr.Cause})
+				errOut := map[string]any{"Error": taskErr.Name, "Cause": taskErr.Cause}
+				out, err := applyResultPath(doc, errOut, c.ResultPath)
+				if err != nil {
+					return "", nil, runtimeErr(err)
+				}
+				return c.Next, out, nil
+			}
+			return "", nil, &Failure{State: name, Name: taskErr.Name, Cause: taskErr.Cause}
+		}
+		out, err := applyOutput(doc, result, st)
+		if err != nil {
+			return "", nil, runtimeErr(err)
+		}
+		return st.Next, out, nil
+
+	case TypeChoice:
+		for _, rule := range st.Choices {
+			ok, err := evalRule(rule, effective)
+			if err != nil {
+				return "", nil, runtimeErr(err)
+			}
+			if ok {
+				in.record(exec, Event{Type: EventChoiceMatched, State: name, Next: rule.Next})
+				out, err := applyOutputPath(effective, st.OutputPath)
+				if err != nil {
+					return "", nil, runtimeErr(err)
+				}
+				return rule.Next, out, nil
+			}
+		}
+		if st.Default == "" {
+			return "", nil, &Failure{State: name, Name: ErrorNoChoiceMatched, Cause: "no choice rule matched and no Default is set"}
+		}
+		in.record(exec, Event{Type: EventChoiceMatched, State: name, Next: st.Default})
+		out, err := applyOutputPath(effective, st.OutputPath)
+		if err != nil {
+			return "", nil, runtimeErr(err)
+		}
+		return st.Default, out, nil
+
+	case TypeSucceed:
+		out, err := applyOutputPath(effective, st.OutputPath)
+		if err != nil {
+			return "", nil, runtimeErr(err)
+		}
+		return "", out, nil
+
+	case TypeFail:
+		return "", nil, &Failure{State: name, Name: st.Error, Cause: st.Cause}
+
+	default:
+		return "", nil, runtimeErr(fmt.Errorf("unsupported state type %q", st.Type))
+	}
+}
+
+// runTask invokes the resource with the state's Retry policy applied.
+func (in *Interpreter) runTask(ctx context.Context, exec *Execution, name string, st *State, input any) (any, *TaskError) {
+	retries := make([]int, len(st.Retry))
+	for attempt := 1; ; attempt++ {
+		result, err := in.invoke(ctx, st, input)
+		if err == nil {
+			in.record(exec, Event{Type: EventTaskSucceeded, State: name, Attempt: attempt})
+			return result, nil
+		}
+		in.record(exec, Event{Type: EventTaskFailed, State: name, Attempt: attempt, Error: err.Name, Cause: err.Cause})
+		if err.Name == ErrorRuntime || ctx.Err() != nil {
+			return nil, err
+		}
+
+		idx := -1
+		for i, r := range st.Retry {
+			if ErrorMatches(r.ErrorEquals, err.Name) {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return nil, err
+		}
+		r := st.Retry[idx]
+		maxAttempts, interval, rate := 3, 1.0, 2.0
+		if r.MaxAttempts != nil {
+			maxAttempts = *r.MaxAttempts
+		}
+		if r.IntervalSeconds != nil {
+			interval = *r.IntervalSeconds
+		}
+		if r.BackoffRate != nil {
+			rate = *r.BackoffRate
+		}
+		if retries[idx] >= maxAttempts {
+			return nil, err
+		}
+		delay := time.Duration(interval * math.Pow(rate, float64(retries[idx])) * float64(time.Second))
+		retries[idx]++
+		in.record(exec, Event{Type: EventTaskRetried, State: name, Attempt: attempt, Error: err.Name})
+		if serr := in.Sleep(ctx, delay); serr != nil {
+			return nil, &TaskError{Name: ErrorTimeout, Cause: serr.Error()}
+		}
+	}
+}
+
+// invoke calls the handler for st.Resource, enforcing the state's TimeoutSeconds.
+func (in *Interpreter) invoke(ctx context.Context, st *State, input any) (any, *TaskError) {
+	var h Handler
+	if st.Resource == ResourceSQSSendMessage {
+		h = in.sendMessage
+	} else if h = in.handlers[st.Resource]; h == nil {
+		return nil, &TaskError{Name: ErrorRuntime, Cause: fmt.Sprintf("no handler registered for resource %q", st.Resource)}
+	}
+
+	taskCtx := ctx
+	if st.TimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		taskCtx, cancel = context.WithTimeout(ctx, time.Duration(st.TimeoutSeconds)*time.Second)
+		defer cancel()
+	}
+
+	type result struct {
+		out any
+		err error
+	}
+	// Buffered so the handler goroutine never blocks on send after a timeout
+	// has abandoned it.
+	done := make(chan result, 1)
+	go func() {
+		out, err := h(taskCtx, input)
+		done <- result{out, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			var te *TaskError
+			if errors.As(r.err, &te) {
+				return nil, te
+			}
+			if errors.Is(r.err, context.DeadlineExceeded) && ctx.Err() == nil {
+				return nil, &TaskError{Name: ErrorTimeout, Cause: r.err.Error()}
+			}
+			return nil, &TaskError{Name: ErrorTaskFailed, Cause: r.err.Error()}
+		}
+		out, err := normalize(r.out)
+		if err != nil {
+			return nil, &TaskError{Name: ErrorRuntime, Cause: fmt.Sprintf("task output is not JSON: %v", err)}
+		}
+		return out, nil
+	case <-taskCtx.Done():
+		if ctx.Err() != nil {
+			return nil, &TaskError{Name: ErrorTimeout, Cause: ctx.Err().Error()}
+		}
+		return nil, &TaskError{Name: ErrorTimeout, Cause: fmt.Sprintf("task timed out after %ds", st.TimeoutSeconds)}
+	}
+}
+
+// sendMessage implements arn:aws:states:::sqs:sendMessage.
+func (in *Interpreter) sendMessage(ctx context.Context, input any) (any, error) {
+	if in.SendMessage == nil {
+		return nil, &TaskError{Name: ErrorRuntime, Cause: "sqs:sendMessage used but no SendMessage func is configured"}
+	}
+	params, _ := input.(map[string]any)
+	queueURL, _ := params["QueueUrl"].(string)
+	if queueURL == "" {
+		return nil, &TaskError{Name: ErrorRuntime, Cause: "sqs:sendMessage requires QueueUrl"}
+	}
+	var body string
+	switch b := params["MessageBody"].(type) {
+	case string:
+		body = b
+	case nil:
+		return nil, &TaskError{Name: ErrorRuntime, Cause: "sqs:sendMessage requires MessageBody"}
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		body = string(data)
+	}
+	id, err := in.SendMessage(ctx, queueURL, body)
+	if err != nil {
+		return nil, err
+	}
+	sum := md5.Sum([]byte(body))
+	return map[string]any{"MessageId": id, "MD5OfMessageBody": hex.EncodeToString(sum[:])}, nil
+}
+
+func (in *Interpreter) parameters(st *State, input, contextObj any) (any, error) {
+	var tmpl any
+	if err := json.Unmarshal(st.Parameters, &tmpl); err != nil {
+		return nil, fmt.Errorf("parameters: %w", err)
+	}
+	return resolveParameters(tmpl, input, contextObj)
+}
+
+func (in *Interpreter) record(exec *Execution, ev Event) {
+	ev.Timestamp = in.Now()
+	exec.History = append(exec.History, ev)
+}
+
+func (in *Interpreter) fail(exec *Execution, state, errName, cause string) *Failure {
+	f := &Failure{State: state, Name: errName, Cause: cause}
+	exec.Status = StatusFailed
+	exec.Failure = f
+	in.record(exec, Event{Type: EventExecutionFailed, State: state, Error: errName, Cause: cause})
+	return f
+}
+
+func contextFailure(state string, err error) *Failure {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &Failure{State: state, Name: ErrorTimeout, Cause: "execution timed out"}
+	}
+	return &Failure{State: state, Name: "States.Aborted", Cause: err.Error()}
+}
+
+// ErrorMatches applies ASL ErrorEquals semantics. States.ALL matches everything
+// except States.Runtime; States.TaskFailed matches everything except States.Timeout.
+func ErrorMatches(list []string, name string) bool {
+	for _, e := range list {
+		switch {
+		case e == name:
+			return true
+		case e == ErrorAll && name != ErrorRuntime:
+			return true
+		case e == ErrorTaskFailed && name != ErrorTimeout && name != ErrorRuntime:
+			return true
+		}
+	}
+	return false
+}
+
+func applyInputPath(doc any, p Path) (any, error) {
+	path := p.orDefault()
+	if path == "" {
+		return map[string]any{}, nil
+	}
+	v, err := getPath(doc, path)
+	if err != nil {
+		return nil, fmt.Errorf("InputPath: %w", err)
+	}
+	return v, nil
+}
+
+func applyResultPath(doc, result any, p Path) (any, error) {
+	path := p.orDefault()
+	if path == "" {
+		return doc, nil
+	}
+	cp, err := normalize(doc)
+	if err != nil {
+		return nil, err
+	}
+	out, err := setPath(cp, path, result)
+	if err != nil {
+		return nil, fmt.Errorf("ResultPath: %w", err)
+	}
+	return out, nil
+}
+
+func applyOutputPath(doc any, p Path) (any, error) {
+	path := p.orDefault()
+	if path == "" {
+		return map[string]any{}, nil
+	}
+	v, err := getPath(doc, path)
+	if err != nil {
+		return nil, fmt.Errorf("OutputPath: %w", err)
+	}
+	return v, nil
+}
+
+func applyOutput(doc, result any, st *State) (any, error) {
+	out, err := applyResultPath(doc, result, st.ResultPath)
+	if err != nil {
+		return nil, err
+	}
+	return applyOutputPath(out, st.OutputPath)
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
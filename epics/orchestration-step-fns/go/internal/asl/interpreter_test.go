@@ -0,0 +1,248 @@
+package asl
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func definitionPath() string {
+	return filepath.Join("..", "..", "..", "terraform", "sfn", "definition.asl.json")
+}
+
+// mockLambda mirrors the behaviour of lambdas/mock-go.
+func mockLambda(state string) Handler {
+	return FromLambda(func(ctx context.Context, event map[string]any) (map[string]any, error) {
+		out := map[string]any{"state": state, "status": "ok", "items": []any{}, "new_unique_rate": 0.2}
+		if v, ok := event["new_unique_rate"].(float64); ok {
+			out["new_unique_rate"] = v
+		}
+		for _, k := range []string{"job_id", "city", "s3_prefix", "budgets", "kill_switches", "early_stop", "timeouts"} {
+			if v, ok := event[k]; ok {
+				out[k] = v
+			}
+		}
+		return out, nil
+	})
+}
+
+func loadRealDefinition(t *testing.T) (*Definition, *Interpreter, *[]time.Duration) {
+	t.Helper()
+	def, err := LoadFile(definitionPath(), nil)
+	require.NoError(t, err)
+	in := New(def)
+	for _, st := range def.States {
+		if st.Type == TypeTask && strings.HasPrefix(st.Resource, "${") {
+			in.Register(st.Resource, mockLambda(st.Resource))
+		}
+	}
+	var sleeps []time.Duration
+	in.Sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	return def, in, &sleeps
+}
+
+func TestExecute_Definition_HappyPath(t *testing.T) {
+	_, in, _ := loadRealDefinition(t)
+
+	exec, err := in.Execute(context.Background(), map[string]any{"city": "Edinburgh", "job_id": "job-1"})
+	require.NoError(t, err)
+	require.Equal(t, StatusSucceeded, exec.Status)
+	require.Equal(t, []string{
+		"Initialize", "InitBudget", "InitMetrics",
+		"DiscoverWebSources", "DiscoverTargets", "SeedPrimaries", "ExpandNeighbors", "TileSweep",
+		"EarlyStopGate", "BudgetGate",
+		"WebFetch", "ExtractWithLLM", "GeocodeValidate", "DedupeCanonicalize", "Persist", "Rank",
+		"Finalize",
+	}, exec.States())
+
+	out := exec.Output.(map[string]any)
+	require.Equal(t, "Edinburgh", out["city"])
+	v, err := getPath(out, "$.ranked.status")
+	require.NoError(t, err)
+	require.Equal(t, "ok", v)
+}
+
+func TestExecute_Definition_EarlyStopGate(t *testing.T) {
+	_, in, _ := loadRealDefinition(t)
+	in.Register("${lambda_tile_sweep_arn}", func(ctx context.Context, input any) (any, error) {
+		return map[string]any{"new_unique_rate": 0.01}, nil
+	})
+
+	exec, err := in.Execute(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	states := exec.States()
+	require.Equal(t, []string{"EarlyStopGate", "Finalize"}, states[len(states)-2:])
+}
+
+func TestExecute_Definition_CatchRoutesToFinalize(t *testing.T) {
+	_, in, sleeps := loadRealDefinition(t)
+	calls := 0
+	in.Register("${lambda_web_fetch_arn}", func(ctx context.Context, input any) (any, error) {
+		calls++
+		return nil, errors.New("fetch failed")
+	})
+
+	exec, err := in.Execute(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	require.Equal(t, 5, calls, "first attempt plus MaxAttempts=4 retries")
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}, *sleeps)
+
+	states := exec.States()
+	require.Equal(t, []string{"WebFetch", "ToDLQOrContinue", "Finalize"}, states[len(states)-3:])
+	v, err := getPath(exec.Output, "$.errors.WebFetch.Error")
+	require.NoError(t, err)
+	require.Equal(t, ErrorTaskFailed, v)
+}
+
+func TestExecute_Definition_UnregisteredResourceFails(t *testing.T) {
+	def, err := LoadFile(definitionPath(), nil)
+	require.NoError(t, err)
+
+	exec, err := New(def).Execute(context.Background(), map[string]any{"city": "Edinburgh"})
+	var f *Failure
+	require.ErrorAs(t, err, &f)
+	require.Equal(t, ErrorRuntime, f.Name)
+	require.Equal(t, "DiscoverWebSources", f.State)
+	require.Equal(t, StatusFailed, exec.Status)
+}
+
+const failFastDefinition = `{
+  "StartAt": "Work",
+  "States": {
+    "Work": {
+      "Type": "Task",
+      "Resource": "work",
+      "ResultPath": "$.work",
+      "Catch": [{"ErrorEquals": ["States.ALL"], "ResultPath": "$.errors.Work", "Next": "ToDLQOrContinue"}],
+      "End": true
+    },
+    "ToDLQOrContinue": {
+      "Type": "Choice",
+      "Choices": [{"Variable": "$.orchestrator.fail_fast", "BooleanEquals": true, "Next": "SendToDLQ"}],
+      "Default": "Done"
+    },
+    "SendToDLQ": {
+      "Type": "Task",
+      "Resource": "arn:aws:states:::sqs:sendMessage",
+      "Parameters": {"QueueUrl": "${frontier_dlq_url}", "MessageBody.$": "$"},
+      "End": true
+    },
+    "Done": {"Type": "Pass", "End": true}
+  }
+}`
+
+func TestExecute_SQSSendMessage(t *testing.T) {
+	def, err := Parse(Render([]byte(failFastDefinition), map[string]string{"frontier_dlq_url": "https://sqs.local/dlq"}))
+	require.NoError(t, err)
+	in := New(def)
+	in.Register("work", func(ctx context.Context, input any) (any, error) {
+		return nil, &TaskError{Name: "CustomError", Cause: "nope"}
+	})
+	var gotURL, gotBody string
+	in.SendMessage = func(ctx context.Context, queueURL, body string) (string, error) {
+		gotURL, gotBody = queueURL, body
+		return "msg-1", nil
+	}
+
+	exec, err := in.Execute(context.Background(), map[string]any{"orchestrator": map[string]any{"fail_fast": true}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Work", "ToDLQOrContinue", "SendToDLQ"}, exec.States())
+	require.Equal(t, "https://sqs.local/dlq", gotURL)
+	require.Contains(t, gotBody, `"Error":"CustomError"`)
+	require.Equal(t, "msg-1", exec.Output.(map[string]any)["MessageId"])
+}
+
+func TestExecute_ChoiceVariableMissingIsRuntimeError(t *testing.T) {
+	def, err := Parse([]byte(failFastDefinition))
+	require.NoError(t, err)
+	in := New(def)
+	in.Register("work", func(ctx context.Context, input any) (any, error) { return nil, errors.New("boom") })
+
+	_, err = in.Execute(context.Background(), map[string]any{})
+	var f *Failure
+	require.ErrorAs(t, err, &f)
+	require.Equal(t, ErrorRuntime, f.Name)
+	require.Equal(t, "ToDLQOrContinue", f.State)
+}
+
+func TestExecute_ChoiceWithoutDefaultDeadEnds(t *testing.T) {
+	def, err := Parse([]byte(`{
+	  "StartAt": "Gate",
+	  "States": {
+	    "Gate": {"Type": "Choice", "Choices": [{"Variable": "$.n", "NumericGreaterThan": 10, "Next": "Done"}]},
+	    "Done": {"Type": "Succeed"}
+	  }
+	}`))
+	require.NoError(t, err)
+
+	_, err = New(def).Execute(context.Background(), map[string]any{"n": 1})
+	var f *Failure
+	require.ErrorAs(t, err, &f)
+	require.Equal(t, ErrorNoChoiceMatched, f.Name)
+}
+
+func TestExecute_InputPathResultPath(t *testing.T) {
+	def, err := Parse([]byte(`{
+	  "StartAt": "Echo",
+	  "States": {
+	    "Echo": {"Type": "Task", "Resource": "echo", "InputPath": "$.payload", "ResultPath": "$.echoed", "Next": "Drop"},
+	    "Drop": {"Type": "Task", "Resource": "echo", "ResultPath": null, "OutputPath": "$.echoed", "End": true}
+	  }
+	}`))
+	require.NoError(t, err)
+	in := New(def)
+	var inputs []any
+	in.Register("echo", func(ctx context.Context, input any) (any, error) {
+		inputs = append(inputs, input)
+		return map[string]any{"seen": input}, nil
+	})
+
+	exec, err := in.Execute(context.Background(), map[string]any{"payload": map[string]any{"x": 1}, "other": true})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"x": 1.0}, inputs[0])
+	require.Equal(t, map[string]any{"seen": map[string]any{"x": 1.0}}, exec.Output)
+}
+
+func TestExecute_TaskTimeout(t *testing.T) {
+	def, err := Parse([]byte(`{
+	  "StartAt": "Slow",
+	  "States": {
+	    "Slow": {
+	      "Type": "Task", "Resource": "slow", "TimeoutSeconds": 1,
+	      "Retry": [{"ErrorEquals": ["States.TaskFailed"], "MaxAttempts": 2}],
+	      "Catch": [{"ErrorEquals": ["States.Timeout"], "ResultPath": "$.err", "Next": "Done"}],
+	      "End": true
+	    },
+	    "Done": {"Type": "Pass", "End": true}
+	  }
+	}`))
+	require.NoError(t, err)
+	in := New(def)
+	var calls atomic.Int32
+	in.Register("slow", func(ctx context.Context, input any) (any, error) {
+		calls.Add(1)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	exec, err := in.Execute(context.Background(), map[string]any{})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, calls.Load(), "States.TaskFailed retrier must not match States.Timeout")
+	v, err := getPath(exec.Output, "$.err.Error")
+	require.NoError(t, err)
+	require.Equal(t, ErrorTimeout, v)
+}
+
+func TestRender_LeavesUnknownPlaceholders(t *testing.T) {
+	out := Render([]byte(`{"a":"${known}","b":"${unknown}"}`), map[string]string{"known": "arn:x"})
+	require.Equal(t, `{"a":"arn:x","b":"${unknown}"}`, string(out))
+}
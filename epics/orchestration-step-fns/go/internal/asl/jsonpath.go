@@ -0,0 +1,189 @@
+package asl
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// segment is one step of a reference path: a field name or an array index.
+type segment struct {
+	field string
+	index int
+	isIdx bool
+}
+
+// parsePath parses the reference-path subset of JSONPath used by ASL:
+// $, $.a.b, $['a'], $.a[0]. The leading "$" is required.
+func parsePath(path string) ([]segment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path %q must start with $", path)
+	}
+	rest := path[1:]
+	var segs []segment
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("path %q has an empty field name", path)
+			}
+			segs = append(segs, segment{field: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("path %q has an unterminated [", path)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				segs = append(segs, segment{field: inner[1 : len(inner)-1]})
+				continue
+			}
+			n, err := strconv.Atoi(inner)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("path %q has an unsupported selector [%s]", path, inner)
+			}
+			segs = append(segs, segment{index: n, isIdx: true})
+		default:
+			return nil, fmt.Errorf("path %q: unexpected %q", path, rest[0])
+		}
+	}
+	return segs, nil
+}
+
+// errPathNotFound is returned by getPath when a segment does not resolve.
+type errPathNotFound struct{ path string }
+
+func (e errPathNotFound) Error() string {
+	return fmt.Sprintf("path %s not found in input", e.path)
+}
+
+// getPath resolves a reference path against a JSON document.
+func getPath(doc any, path string) (any, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	cur := doc
+	for _, s := range segs {
+		if s.isIdx {
+			arr, ok := cur.([]any)
+			if !ok || s.index >= len(arr) {
+				return nil, errPathNotFound{path}
+			}
+			cur = arr[s.index]
+			continue
+		}
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil, errPathNotFound{path}
+		}
+		v, ok := obj[s.field]
+		if !ok {
+			return nil, errPathNotFound{path}
+		}
+		cur = v
+	}
+	return cur, nil
+}
+
+// setPath returns doc with value written at path, creating intermediate
+// objects as needed. "$" replaces the whole document.
+func setPath(doc any, path string, value any) (any, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(segs) == 0 {
+		return value, nil
+	}
+	root, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("cannot set %s: input is not an object", path)
+	}
+	cur := root
+	for i, s := range segs {
+		if s.isIdx {
+			return nil, fmt.Errorf("cannot set %s: array indexes are not supported in ResultPath", path)
+		}
+		if i == len(segs)-1 {
+			cur[s.field] = value
+			break
+		}
+		next, ok := cur[s.field].(map[string]any)
+		if !ok {
+			if _, exists := cur[s.field]; exists {
+				return nil, fmt.Errorf("cannot set %s: %s is not an object", path, s.field)
+			}
+			next = make(map[string]any)
+			cur[s.field] = next
+		}
+		cur = next
+	}
+	return root, nil
+}
+
+// normalize deep-copies v into plain JSON types (map[string]any, []any, float64, ...).
+func normalize(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// resolveParameters evaluates a Parameters/ResultSelector template: keys ending
+// in ".$" are replaced by the value at the referenced path ($ for input, $$ for context).
+func resolveParameters(tmpl any, input, contextObj any) (any, error) {
+	switch t := tmpl.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, v := range t {
+			if strings.HasSuffix(k, ".$") {
+				ref, ok := v.(string)
+				if !ok {
+					return nil, fmt.Errorf("parameter %s must be a path string", k)
+				}
+				src := input
+				if strings.HasPrefix(ref, "$$") {
+					src, ref = contextObj, ref[1:]
+				}
+				val, err := getPath(src, ref)
+				if err != nil {
+					return nil, fmt.Errorf("parameter %s: %w", k, err)
+				}
+				out[strings.TrimSuffix(k, ".$")] = val
+				continue
+			}
+			val, err := resolveParameters(v, input, contextObj)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = val
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(t))
+		for i, v := range t {
+			val, err := resolveParameters(v, input, contextObj)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = val
+		}
+		return out, nil
+	default:
+		return t, nil
+	}
+}
@@ -0,0 +1,68 @@
+package asl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPath(t *testing.T) {
+	doc := map[string]any{
+		"a":     map[string]any{"b": 1.0},
+		"items": []any{"x", "y"},
+		"dot.k": "quoted",
+	}
+	tests := []struct {
+		path string
+		want any
+	}{
+		{"$", doc},
+		{"$.a.b", 1.0},
+		{"$.items[1]", "y"},
+		{"$['dot.k']", "quoted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := getPath(doc, tt.path)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := getPath(doc, "$.a.missing")
+	require.ErrorAs(t, err, &errPathNotFound{})
+	_, err = getPath(doc, "a.b")
+	require.Error(t, err)
+}
+
+func TestSetPath_CreatesIntermediateObjects(t *testing.T) {
+	out, err := setPath(map[string]any{"keep": true}, "$.errors.WebFetch", "x")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"keep": true, "errors": map[string]any{"WebFetch": "x"}}, out)
+
+	out, err = setPath(map[string]any{"keep": true}, "$", "replaced")
+	require.NoError(t, err)
+	require.Equal(t, "replaced", out)
+
+	_, err = setPath(map[string]any{"a": 1.0}, "$.a.b", "x")
+	require.Error(t, err)
+}
+
+func TestResolveParameters(t *testing.T) {
+	tmpl := map[string]any{
+		"QueueUrl":      "https://q",
+		"MessageBody.$": "$.body",
+		"ExecId.$":      "$$.Execution.Id",
+		"nested":        map[string]any{"city.$": "$.city"},
+	}
+	out, err := resolveParameters(tmpl,
+		map[string]any{"body": "hello", "city": "Edinburgh"},
+		map[string]any{"Execution": map[string]any{"Id": "exec-1"}})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"QueueUrl":    "https://q",
+		"MessageBody": "hello",
+		"ExecId":      "exec-1",
+		"nested":      map[string]any{"city": "Edinburgh"},
+	}, out)
+}
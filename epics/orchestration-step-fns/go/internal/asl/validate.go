@@ -0,0 +1,361 @@
+package asl
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Issue codes reported by Validate.
+const (
+	CodeUnknownType        = "unknown-type"
+	CodeMissingTarget      = "missing-target"
+	CodeMissingTransition  = "missing-transition"
+	CodeUnreachable        = "unreachable"
+	CodeNoTerminalPath     = "no-terminal-path"
+	CodeChoiceNoDefault    = "choice-without-default"
+	CodeInvalidPath        = "invalid-path"
+	CodeUnresolvedVariable = "unresolved-variable"
+	CodeUnknownTemplateVar = "unknown-template-var"
+)
+
+// Issue is a single validation finding.
+type Issue struct {
+	Code    string `json:"code"`
+	State   string `json:"state,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	if i.State == "" {
+		return fmt.Sprintf("[%s] %s", i.Code, i.Message)
+	}
+	return fmt.Sprintf("[%s] %s: %s", i.Code, i.State, i.Message)
+}
+
+// ValidateOptions tunes the checks that depend on context outside the definition.
+type ValidateOptions struct {
+	// InputPaths are execution input paths assumed to exist (e.g. "$.city").
+	// Anything below them is treated as present.
+	InputPaths []string
+	// TemplateVars are the variables passed to templatefile(). When nil, the
+	// ${...} placeholder check is skipped.
+	TemplateVars map[string]bool
+}
+
+// ValidateFile loads an unrendered definition and validates it, including its
+// ${...} placeholders against opts.TemplateVars.
+func ValidateFile(path string, opts ValidateOptions) ([]Issue, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	def, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	issues := Validate(def, opts)
+	if opts.TemplateVars != nil {
+		seen := make(map[string]bool)
+		for _, m := range templateVar.FindAllSubmatch(raw, -1) {
+			name := string(m[1])
+			if !opts.TemplateVars[name] && !seen[name] {
+				seen[name] = true
+				issues = append(issues, Issue{Code: CodeUnknownTemplateVar, Message: fmt.Sprintf("${%s} has no matching terraform template variable", name)})
+			}
+		}
+	}
+	return issues, nil
+}
+
+// Validate statically checks a definition's graph and data-flow. It never
+// contacts AWS.
+func Validate(def *Definition, opts ValidateOptions) []Issue {
+	var issues []Issue
+	add := func(code, state, format string, args ...any) {
+		issues = append(issues, Issue{Code: code, State: state, Message: fmt.Sprintf(format, args...)})
+	}
+
+	names := make([]string, 0, len(def.States))
+	for name := range def.States {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Edges and per-state structural checks.
+	edges := make(map[string][]string, len(def.States))
+	terminal := make(map[string]bool)
+	for _, name := range names {
+		st := def.States[name]
+		for _, t := range targets(st) {
+			if _, ok := def.States[t.next]; !ok {
+				add(CodeMissingTarget, name, "%s target %q does not exist", t.field, t.next)
+				continue
+			}
+			edges[name] = append(edges[name], t.next)
+		}
+		switch st.Type {
+		case TypePass, TypeTask:
+			if st.End {
+				terminal[name] = true
+			} else if st.Next == "" {
+				add(CodeMissingTransition, name, "%s state has neither Next nor End", st.Type)
+			}
+		case TypeChoice:
+			if st.Default == "" {
+				add(CodeChoiceNoDefault, name, "no Default; an unmatched input fails with %s", ErrorNoChoiceMatched)
+			}
+		case TypeSucceed, TypeFail:
+			terminal[name] = true
+		default:
+			add(CodeUnknownType, name, "unsupported state type %q", st.Type)
+		}
+		for _, p := range []struct {
+			field string
+			path  Path
+		}{{"InputPath", st.InputPath}, {"OutputPath", st.OutputPath}, {"ResultPath", st.ResultPath}} {
+			if p.path.Set && !p.path.Null {
+				if _, err := parsePath(p.path.Value); err != nil {
+					add(CodeInvalidPath, name, "%s: %v", p.field, err)
+				}
+			}
+		}
+	}
+
+	// Reachability from StartAt.
+	reachable := walk(def.StartAt, edges)
+	for _, name := range names {
+		if !reachable[name] {
+			add(CodeUnreachable, name, "state is not reachable from StartAt %q", def.StartAt)
+		}
+	}
+
+	// Every reachable state must be able to reach a terminal state.
+	reverse := make(map[string][]string)
+	for from, tos := range edges {
+		for _, to := range tos {
+			reverse[to] = append(reverse[to], from)
+		}
+	}
+	canFinish := make(map[string]bool)
+	for name := range terminal {
+		for n := range walk(name, reverse) {
+			canFinish[n] = true
+		}
+	}
+	for _, name := range names {
+		if reachable[name] && !canFinish[name] {
+			add(CodeNoTerminalPath, name, "no path from this state reaches an End, Succeed, or Fail state")
+		}
+	}
+
+	// Choice variables must be produced by the execution input or an upstream ResultPath.
+	for _, name := range names {
+		st := def.States[name]
+		if st.Type != TypeChoice || !reachable[name] {
+			continue
+		}
+		var upstream []string
+		for n := range walk(name, reverse) {
+			if n != name {
+				upstream = append(upstream, n)
+			}
+		}
+		produced := producedPaths(def, upstream, opts.InputPaths)
+		for _, v := range choiceVariables(st.Choices) {
+			if _, err := parsePath(v); err != nil {
+				add(CodeInvalidPath, name, "Variable: %v", err)
+				continue
+			}
+			if !produced.covers(v) {
+				add(CodeUnresolvedVariable, name, "Variable %s is not produced by the execution input or any upstream ResultPath", v)
+			}
+		}
+	}
+	return issues
+}
+
+type target struct {
+	field string
+	next  string
+}
+
+func targets(st *State) []target {
+	var out []target
+	if st.Next != "" {
+		out = append(out, target{"Next", st.Next})
+	}
+	if st.Default != "" {
+		out = append(out, target{"Default", st.Default})
+	}
+	for i, c := range st.Choices {
+		out = append(out, target{fmt.Sprintf("Choices[%d].Next", i), c.Next})
+	}
+	for i, c := range st.Catch {
+		out = append(out, target{fmt.Sprintf("Catch[%d].Next", i), c.Next})
+	}
+	return out
+}
+
+func walk(start string, edges map[string][]string) map[string]bool {
+	seen := map[string]bool{start: true}
+	stack := []string{start}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, next := range edges[n] {
+			if !seen[next] {
+				seen[next] = true
+				stack = append(stack, next)
+			}
+		}
+	}
+	return seen
+}
+
+func choiceVariables(rules []ChoiceRule) []string {
+	var out []string
+	for _, r := range rules {
+		if r.Variable != "" {
+			out = append(out, r.Variable)
+		}
+		out = append(out, choiceVariables(r.And)...)
+		out = append(out, choiceVariables(r.Or)...)
+		if r.Not != nil {
+			out = append(out, choiceVariables([]ChoiceRule{*r.Not})...)
+		}
+	}
+	return out
+}
+
+// pathSet records which document paths are known to exist. Opaque prefixes
+// (task results, execution input) cover everything below them; known paths
+// (literal Pass results) cover only themselves.
+type pathSet struct {
+	anything bool
+	opaque   map[string]bool
+	known    map[string]bool
+}
+
+func producedPaths(def *Definition, states []string, inputPaths []string) pathSet {
+	ps := pathSet{opaque: make(map[string]bool), known: make(map[string]bool)}
+	for _, p := range inputPaths {
+		ps.addOpaque(p)
+	}
+	for _, name := range states {
+		st := def.States[name]
+		for _, c := range st.Catch {
+			rp := c.ResultPath.orDefault()
+			if rp == "$" {
+				ps.anything = true
+			} else if rp != "" {
+				ps.addKnown(rp, map[string]any{"Error": "", "Cause": ""})
+			}
+		}
+		if st.Type != TypePass && st.Type != TypeTask {
+			continue
+		}
+		rp := st.ResultPath.orDefault()
+		switch {
+		case rp == "":
+			// ResultPath null: the input passes through unchanged.
+		case rp == "$":
+			ps.anything = true
+		case st.Type == TypePass && len(st.Result) > 0:
+			var result any
+			if json.Unmarshal(st.Result, &result) == nil {
+				ps.addKnown(rp, result)
+			} else {
+				ps.addOpaque(rp)
+			}
+		default:
+			ps.addOpaque(rp)
+		}
+	}
+	return ps
+}
+
+func (ps pathSet) addOpaque(p string) { ps.opaque[p] = true }
+
+func (ps pathSet) addKnown(p string, v any) {
+	ps.known[p] = true
+	if obj, ok := v.(map[string]any); ok {
+		for k, child := range obj {
+			ps.addKnown(p+"."+k, child)
+		}
+	}
+}
+
+// covers reports whether v is produced: it lies under an opaque prefix, matches
+// a known path, or is a parent of something produced.
+func (ps pathSet) covers(v string) bool {
+	if ps.anything || v == "$" {
+		return true
+	}
+	for p := range ps.opaque {
+		if v == p || isUnder(v, p) || isUnder(p, v) {
+			return true
+		}
+	}
+	for p := range ps.known {
+		if v == p || isUnder(p, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func isUnder(path, prefix string) bool {
+	return strings.HasPrefix(path, prefix+".") || strings.HasPrefix(path, prefix+"[")
+}
+
+var (
+	templatefileCall = regexp.MustCompile(`templatefile\(`)
+	templateKey      = regexp.MustCompile(`^\s*([A-Za-z0-9_]+)\s*=`)
+)
+
+// TerraformTemplateVars extracts the variable names passed in the templatefile()
+// call(s) of a terraform file, e.g. terraform/sfn/main.tf.
+func TerraformTemplateVars(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	vars := make(map[string]bool)
+	depth := 0
+	inCall := false
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if !inCall {
+			loc := templatefileCall.FindStringIndex(line)
+			if loc == nil {
+				continue
+			}
+			inCall, depth = true, 0
+			line = line[loc[1]:]
+		}
+		if depth == 1 {
+			if m := templateKey.FindStringSubmatch(line); m != nil {
+				vars[m[1]] = true
+			}
+		}
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if depth <= 0 && strings.Contains(line, ")") {
+			inCall = false
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(vars) == 0 {
+		return nil, fmt.Errorf("%s: no templatefile() variables found", path)
+	}
+	return vars, nil
+}
@@ -0,0 +1,97 @@
+package asl
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateFile_Definition(t *testing.T) {
+	vars, err := TerraformTemplateVars(filepath.Join("..", "..", "..", "terraform", "sfn", "main.tf"))
+	require.NoError(t, err)
+	require.True(t, vars["frontier_dlq_url"])
+	require.True(t, vars["lambda_rank_arn"])
+	require.False(t, vars["path"], "${path.module} inside the call is not a template variable")
+
+	issues, err := ValidateFile(definitionPath(), ValidateOptions{TemplateVars: vars})
+	require.NoError(t, err)
+	require.Empty(t, issues)
+}
+
+func codes(issues []Issue) map[string][]string {
+	out := make(map[string][]string)
+	for _, i := range issues {
+		out[i.Code] = append(out[i.Code], i.State)
+	}
+	return out
+}
+
+func TestValidate_ReportsGraphErrors(t *testing.T) {
+	def, err := Parse([]byte(`{
+	  "StartAt": "A",
+	  "States": {
+	    "A": {"Type": "Task", "Resource": "a", "Next": "Missing",
+	          "Catch": [{"ErrorEquals": ["States.ALL"], "Next": "Loop"}]},
+	    "Loop": {"Type": "Pass", "Next": "Loop2"},
+	    "Loop2": {"Type": "Pass", "Next": "Loop"},
+	    "Orphan": {"Type": "Pass", "End": true},
+	    "Dangling": {"Type": "Task", "Resource": "x"},
+	    "Gate": {"Type": "Choice", "Choices": [{"Variable": "$.x", "BooleanEquals": true, "Next": "Orphan"}]},
+	    "Weird": {"Type": "Map", "End": true}
+	  }
+	}`))
+	require.NoError(t, err)
+
+	got := codes(Validate(def, ValidateOptions{}))
+	require.Equal(t, []string{"A"}, got[CodeMissingTarget])
+	require.Equal(t, []string{"Dangling"}, got[CodeMissingTransition])
+	require.ElementsMatch(t, []string{"Orphan", "Dangling", "Gate", "Weird"}, got[CodeUnreachable])
+	require.ElementsMatch(t, []string{"A", "Loop", "Loop2"}, got[CodeNoTerminalPath])
+	require.Equal(t, []string{"Gate"}, got[CodeChoiceNoDefault])
+	require.Equal(t, []string{"Weird"}, got[CodeUnknownType])
+}
+
+func TestValidate_ChoiceVariables(t *testing.T) {
+	def, err := Parse([]byte(`{
+	  "StartAt": "Init",
+	  "States": {
+	    "Init": {"Type": "Pass", "ResultPath": "$.orchestrator", "Result": {"run_id": "x"}, "Next": "Work"},
+	    "Work": {"Type": "Task", "Resource": "w", "ResultPath": "$.work", "Next": "Gate",
+	             "Catch": [{"ErrorEquals": ["States.ALL"], "ResultPath": "$.errors.Work", "Next": "Gate"}]},
+	    "Gate": {
+	      "Type": "Choice",
+	      "Choices": [
+	        {"Variable": "$.work.new_unique_rate", "NumericLessThan": 0.05, "Next": "Done"},
+	        {"Variable": "$.errors.Work.Error", "StringEquals": "States.Timeout", "Next": "Done"},
+	        {"Variable": "$.city", "StringEquals": "Edinburgh", "Next": "Done"},
+	        {"Variable": "$.orchestrator.fail_fast", "BooleanEquals": true, "Next": "Done"},
+	        {"And": [{"Variable": "$.tile_sweep.rate", "NumericLessThan": 1}], "Next": "Done"},
+	        {"Variable": "$.bad..path", "IsPresent": true, "Next": "Done"}
+	      ],
+	      "Default": "Done"
+	    },
+	    "Done": {"Type": "Succeed"}
+	  }
+	}`))
+	require.NoError(t, err)
+
+	issues := Validate(def, ValidateOptions{InputPaths: []string{"$.city"}})
+	var unresolved []string
+	for _, i := range issues {
+		if i.Code == CodeUnresolvedVariable {
+			unresolved = append(unresolved, i.Message)
+		}
+	}
+	require.Len(t, unresolved, 2)
+	require.Contains(t, unresolved[0], "$.orchestrator.fail_fast")
+	require.Contains(t, unresolved[1], "$.tile_sweep.rate")
+	require.Equal(t, []string{"Gate"}, codes(issues)[CodeInvalidPath])
+}
+
+func TestValidateFile_UnknownTemplateVariable(t *testing.T) {
+	issues, err := ValidateFile(definitionPath(), ValidateOptions{TemplateVars: map[string]bool{"frontier_dlq_url": true}})
+	require.NoError(t, err)
+	got := codes(issues)[CodeUnknownTemplateVar]
+	require.Len(t, got, 11, "one per lambda ARN placeholder")
+}
@@ -3,8 +3,12 @@ package budget
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math/rand/v2"
 	"sync"
 	"time"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/clock"
 )
 
 type Connector string
@@ -22,6 +26,22 @@ const (
 	Nominatim     Connector = "nominatim"
 )
 
+// Connectors lists every connector the guard knows how to budget.
+var Connectors = []Connector{
+	GoogleText, GoogleNearby, GoogleDetails, Overpass, OTM,
+	Wiki, TavilyAPI, WebFetch, LLMTokens, Nominatim,
+}
+
+// Known reports whether c is one of Connectors.
+func (c Connector) Known() bool {
+	for _, k := range Connectors {
+		if c == k {
+			return true
+		}
+	}
+	return false
+}
+
 type Split string
 
 const (
@@ -29,54 +49,70 @@ const (
 	Secondaries Split = "secondaries"
 )
 
+// Bucket is a connector's token-bucket policy. Its token count lives in the
+// Guard's Store so that every worker in a run draws from the same bucket.
 type Bucket struct {
 	capacity int64
-	tokens   int64
 	refill   int64
 	period   time.Duration
-	last     time.Time
-	mu       sync.Mutex
 }
 
 func newBucket(capacity, refill int64, period time.Duration) *Bucket {
 	return &Bucket{
 		capacity: capacity,
-		tokens:   capacity,
 		refill:   refill,
 		period:   period,
-		last:     time.Now(),
 	}
 }
 
-func (b *Bucket) take(n int64) bool {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	now := time.Now()
-	elapsed := now.Sub(b.last)
+// advance applies the refills due by now. Last moves in whole periods so a
+// partially elapsed period is not lost.
+func (b *Bucket) advance(st *BucketState, now time.Time) {
+	elapsed := now.Sub(st.Last)
 	if elapsed >= b.period && b.refill > 0 {
 		steps := int64(elapsed / b.period)
-		b.tokens = min64(b.capacity, b.tokens+steps*b.refill)
-		b.last = now
+		tokens := min64(b.capacity, st.Tokens+steps*b.refill)
+		st.Refilled += tokens - st.Tokens
+		st.Tokens = tokens
+		st.Last = st.Last.Add(time.Duration(steps) * b.period)
 	}
-	if b.tokens >= n {
-		b.tokens -= n
-		return true
-	}
-	return false
 }
 
-func (b *Bucket) put(n int64) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	b.tokens = min64(b.capacity, b.tokens+n)
+// nextAvailable is how long until refills bring st up to n tokens, or zero if
+// the bucket never refills.
+func (b *Bucket) nextAvailable(st BucketState, n int64, now time.Time) time.Duration {
+	if b.refill <= 0 {
+		return 0
+	}
+	steps := (n - st.Tokens + b.refill - 1) / b.refill
+	if steps < 1 {
+		steps = 1
+	}
+	wait := st.Last.Add(time.Duration(steps) * b.period).Sub(now)
+	if wait <= 0 {
+		wait = time.Millisecond
+	}
+	return wait
 }
 
 type Guard struct {
 	mu         sync.RWMutex
 	buckets    map[Connector]*Bucket
-	splitQuota map[Split]int64 // tokens reserved per split in current window
-	splitUsed  map[Split]int64
-	splitRatio float64 // 0.7 => 70% primaries
+	qmu        sync.Mutex
+	queues     map[Connector][]*waiter // FIFO Acquire callers per connector
+	store      Store
+	runID      string
+	splits     map[Connector]*splitState // nil until the first Rebalance
+	splitRatio float64                   // 0.7 => 70% primaries
+	ratios     map[Connector]float64     // per-connector overrides of splitRatio
+	rebalance  RebalanceConfig
+	lastReflow time.Time
+	primaries  int64 // primaries found so far, see SetPrimariesFound
+	clock      clock.Clock
+	denials    map[Connector]map[DenialReason]int64
+	prices     map[Connector]Price
+	spend      map[Connector]float64                 // estimated USD
+	enabled    func(context.Context, Connector) bool // see WithConnectorGate
 }
 
 type Config struct {
@@ -86,9 +122,43 @@ type Config struct {
 		Period   time.Duration `yaml:"period"`
 	} `yaml:"budgets"`
 	SplitRatio float64 `yaml:"split_ratio"`
+	// SplitRatios overrides SplitRatio for individual connectors.
+	SplitRatios map[Connector]float64 `yaml:"split_ratios"`
+	Rebalance   RebalanceConfig       `yaml:"split_rebalance"`
+	// Prices estimate spend per connector; unpriced connectors cost nothing.
+	Prices map[Connector]Price `yaml:"pricing"`
+}
+
+// GuardOption customizes a Guard built by NewGuard.
+type GuardOption func(*Guard)
+
+// WithStore keeps bucket state in store under runID, so Guards in other
+// processes (e.g. Lambda invocations of the same run) share the budget.
+// Without it, state lives in a private MemoryStore.
+func WithStore(store Store, runID string) GuardOption {
+	return func(g *Guard) {
+		g.store = store
+		g.runID = runID
+	}
+}
+
+// WithConnectorGate consults enabled before every Acquire; a connector it
+// reports as disabled (e.g. by a kill switch) is denied immediately.
+func WithConnectorGate(enabled func(ctx context.Context, c Connector) bool) GuardOption {
+	return func(g *Guard) {
+		g.enabled = enabled
+	}
+}
+
+// WithClock drives refills, Acquire deadlines, and scheduled rebalancing
+// from c instead of the real clock.
+func WithClock(c clock.Clock) GuardOption {
+	return func(g *Guard) {
+		g.clock = c
+	}
 }
 
-func NewGuard(cfg Config) *Guard {
+func NewGuard(cfg Config, opts ...GuardOption) *Guard {
 	b := make(map[Connector]*Bucket, len(cfg.Budgets))
 	for c, v := range cfg.Budgets {
 		period := v.Period
@@ -97,26 +167,148 @@ func NewGuard(cfg Config) *Guard {
 		}
 		b[c] = newBucket(v.Capacity, v.Refill, period)
 	}
-	return &Guard{
+	g := &Guard{
 		buckets:    b,
-		splitQuota: map[Split]int64{Primaries: 0, Secondaries: 0},
-		splitUsed:  map[Split]int64{Primaries: 0, Secondaries: 0},
+		queues:     make(map[Connector][]*waiter),
+		denials:    make(map[Connector]map[DenialReason]int64),
+		prices:     cfg.Prices,
+		spend:      make(map[Connector]float64),
 		splitRatio: cfg.SplitRatio,
+		ratios:     cfg.SplitRatios,
+		rebalance:  cfg.Rebalance,
+		clock:      clock.Real(),
 	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.store == nil {
+		g.store = NewMemoryStore()
+	}
+	return g
+}
+
+// RunID returns the run whose budget this Guard draws from.
+func (g *Guard) RunID() string {
+	return g.runID
 }
 
-// Rebalance should be called at run start and periodically to set split quotas based on current token stocks.
-func (g *Guard) Rebalance() {
+// Rebalance should be called at run start, after SetPrimariesFound and
+// periodically to set each connector's split quotas from its current token
+// stock, then reflow them at once. Between calls, quota is reflowed on the
+// split_rebalance schedule; see reflow.
+func (g *Guard) Rebalance(ctx context.Context) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
-	var total int64
-	for _, b := range g.buckets {
-		total += b.tokens
+	if err := g.resplit(ctx); err != nil {
+		return err
+	}
+	if g.rebalance.Interval > 0 {
+		g.reflow()
+		g.wakeHeads()
+	}
+	return nil
+}
+
+// resplit rebuilds every connector's split quotas from its token stock.
+// Callers hold g.mu.
+func (g *Guard) resplit(ctx context.Context) error {
+	splits := make(map[Connector]*splitState, len(g.buckets))
+	for c, b := range g.buckets {
+		st, err := g.load(ctx, c, b)
+		if err != nil {
+			return err
+		}
+		splits[c] = newSplitState(st.Tokens, g.ratio(c))
+		if old := g.splits[c]; old != nil {
+			splits[c].starved = old.starved // still owed a reflow
+		}
+	}
+	g.splits = splits
+	g.lastReflow = g.clock.Now()
+	g.wakeHeads()
+	return nil
+}
+
+// load returns the connector's state with refills applied. A bucket the store
+// has never seen starts full.
+func (g *Guard) load(ctx context.Context, c Connector, b *Bucket) (BucketState, error) {
+	now := g.clock.Now()
+	st, ok, err := g.store.Load(ctx, StateKey{RunID: g.runID, Connector: c})
+	if err != nil {
+		return BucketState{}, err
+	}
+	if !ok {
+		return BucketState{Tokens: b.capacity, Last: now, Capacity: b.capacity}, nil
+	}
+	b.advance(&st, now)
+	return st, nil
+}
+
+// update applies fn to the connector's current state and writes it back with
+// a conditional write, retrying with jittered backoff when another worker got
+// there first. After maxCASAttempts conflicts it gives up with a
+// *ConflictError. fn returns false to leave the state unchanged. update talks
+// to the store, so Acquire and Release call it without g.mu; only the rare
+// Reconfigure holds g.mu across it, to apply a new config atomically.
+func (g *Guard) update(ctx context.Context, c Connector, b *Bucket, fn func(*BucketState) bool) (bool, error) {
+	key := StateKey{RunID: g.runID, Connector: c}
+	for attempt := 1; ; attempt++ {
+		st, err := g.load(ctx, c, b)
+		if err != nil {
+			return false, err
+		}
+		prev := st.Version
+		if !fn(&st) {
+			return false, nil
+		}
+		st.Version = prev + 1
+		err = g.store.CompareAndSwap(ctx, key, prev, st)
+		if !errors.Is(err, ErrConflict) {
+			return err == nil, err
+		}
+		if attempt == maxCASAttempts {
+			return false, &ConflictError{Connector: c, Attempts: attempt}
+		}
+		if err := g.backoff(ctx, attempt); err != nil {
+			return false, err
+		}
+	}
+}
+
+const (
+	// maxCASAttempts bounds the conditional writes update makes before
+	// giving up on a contended bucket.
+	maxCASAttempts = 8
+	casBackoffBase = 5 * time.Millisecond
+	casBackoffMax  = 500 * time.Millisecond
+)
+
+// ConflictError is returned when a connector's shared state kept changing
+// under a conditional write. It matches ErrConflict.
+type ConflictError struct {
+	Connector Connector
+	Attempts  int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("budget: %s state still changing after %d conditional writes", e.Connector, e.Attempts)
+}
+
+func (e *ConflictError) Unwrap() error { return ErrConflict }
+
+// backoff waits out the attempt'th conflict: a random duration in the upper
+// half of an exponentially growing window, so competing workers spread out.
+func (g *Guard) backoff(ctx context.Context, attempt int) error {
+	d := min(casBackoffBase<<(attempt-1), casBackoffMax)
+	d = d/2 + rand.N(d/2)
+	t := g.clock.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C():
+		return nil
 	}
-	g.splitQuota[Primaries] = int64(float64(total) * g.splitRatio)
-	g.splitQuota[Secondaries] = total - g.splitQuota[Primaries]
-	g.splitUsed[Primaries] = 0
-	g.splitUsed[Secondaries] = 0
 }
 
 type AcquireOpts struct {
@@ -126,9 +318,65 @@ type AcquireOpts struct {
 	Deadline  time.Duration
 }
 
-var ErrBudgetExceeded = errors.New("budget exceeded")
+var (
+	ErrBudgetExceeded    = errors.New("budget exceeded")
+	ErrUnknownConnector  = errors.New("unknown connector")
+	ErrConnectorDisabled = errors.New("connector disabled")
+)
+
+// DenialReason says what stopped Acquire from taking tokens.
+type DenialReason string
+
+const (
+	DeniedByBucket         DenialReason = "connector_bucket"
+	DeniedBySplit          DenialReason = "split_quota"
+	DeniedUnknownConnector DenialReason = "unknown_connector"
+	DeniedByKillSwitch     DenialReason = "kill_switch"
+)
+
+// DenialError is returned by Acquire when tokens could not be taken. It
+// matches ErrBudgetExceeded for bucket and split denials,
+// ErrUnknownConnector for connectors without a configured budget and
+// ErrConnectorDisabled for connectors turned off by the connector gate.
+type DenialError struct {
+	Connector Connector
+	Split     Split
+	Tokens    int64
+	Reason    DenialReason
+}
+
+func (e *DenialError) Error() string {
+	return fmt.Sprintf("budget: %d %s tokens for %s denied by %s", e.Tokens, e.Connector, e.Split, e.Reason)
+}
+
+func (e *DenialError) Is(target error) bool {
+	switch e.Reason {
+	case DeniedUnknownConnector:
+		return target == ErrUnknownConnector
+	case DeniedByKillSwitch:
+		return target == ErrConnectorDisabled
+	}
+	return target == ErrBudgetExceeded
+}
+
+// waiter is one Acquire call queued on a connector. Only the head of a
+// connector's queue tries to take tokens; wake is signalled when it should
+// try again.
+type waiter struct {
+	wake chan struct{}
+}
+
+func (w *waiter) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
 
-// Acquire tries to take tokens from the connector bucket while honoring the 70/30 split.
+// Acquire takes tokens from the connector bucket while honoring the 70/30
+// split. Callers queue FIFO per connector; the head sleeps until the bucket's
+// next refill (or a Release/Rebalance) instead of polling. When the deadline
+// passes it returns a *DenialError naming what held it back.
 func (g *Guard) Acquire(ctx context.Context, opts AcquireOpts) error {
 	if opts.Tokens <= 0 {
 		opts.Tokens = 1
@@ -136,74 +384,232 @@ func (g *Guard) Acquire(ctx context.Context, opts AcquireOpts) error {
 	if opts.Deadline <= 0 {
 		opts.Deadline = 2 * time.Second
 	}
-	deadline := time.After(opts.Deadline)
+	deny := func(reason DenialReason) error {
+		g.recordDenial(opts.Connector, reason)
+		return &DenialError{Connector: opts.Connector, Split: opts.Split, Tokens: opts.Tokens, Reason: reason}
+	}
+	g.mu.RLock()
+	b, ok := g.buckets[opts.Connector]
+	g.mu.RUnlock()
+	if !ok {
+		return deny(DeniedUnknownConnector)
+	}
+	if g.enabled != nil && !g.enabled(ctx, opts.Connector) {
+		return deny(DeniedByKillSwitch)
+	}
+	if opts.Tokens > b.capacity {
+		return deny(DeniedByBucket)
+	}
+
+	deadline := g.clock.NewTimer(opts.Deadline)
+	defer deadline.Stop()
+	w := g.enqueue(opts.Connector)
+	defer g.dequeue(opts.Connector, w)
+
+	// Waiters behind the head are held back by the connector's queue.
+	reason := DeniedByBucket
 	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-deadline:
-			return ErrBudgetExceeded
-		default:
-			if g.tryAcquire(opts) {
+		var retry clock.Timer
+		if g.isHead(opts.Connector, w) {
+			res, err := g.tryAcquire(ctx, opts)
+			if err != nil {
+				return err
+			}
+			if res.ok {
 				return nil
 			}
-			time.Sleep(50 * time.Millisecond)
+			reason = res.reason
+			if res.retryIn > 0 {
+				retry = g.clock.NewTimer(res.retryIn)
+			}
+		}
+		if err := g.wait(ctx, w, deadline, retry); err != nil {
+			if errors.Is(err, errDeadline) {
+				return deny(reason)
+			}
+			return err
 		}
 	}
 }
 
-func (g *Guard) tryAcquire(opts AcquireOpts) bool {
+var errDeadline = errors.New("acquire deadline")
+
+// wait blocks until w is woken, the retry timer (if any) fires, the Acquire
+// deadline passes (errDeadline), or ctx ends.
+func (g *Guard) wait(ctx context.Context, w *waiter, deadline, retry clock.Timer) error {
+	var retryC <-chan time.Time
+	if retry != nil {
+		defer retry.Stop()
+		retryC = retry.C()
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-deadline.C():
+		return errDeadline
+	case <-w.wake:
+	case <-retryC:
+	}
+	return nil
+}
+
+func (g *Guard) recordDenial(c Connector, reason DenialReason) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	if g.denials[c] == nil {
+		g.denials[c] = make(map[DenialReason]int64)
+	}
+	g.denials[c][reason]++
+}
+
+func (g *Guard) enqueue(c Connector) *waiter {
+	w := &waiter{wake: make(chan struct{}, 1)}
+	g.qmu.Lock()
+	defer g.qmu.Unlock()
+	g.queues[c] = append(g.queues[c], w)
+	return w
+}
+
+// dequeue removes w and wakes whoever is now at the head of the queue.
+func (g *Guard) dequeue(c Connector, w *waiter) {
+	g.qmu.Lock()
+	defer g.qmu.Unlock()
+	q := g.queues[c]
+	for i, x := range q {
+		if x == w {
+			q = append(q[:i], q[i+1:]...)
+			break
+		}
+	}
+	if len(q) == 0 {
+		delete(g.queues, c)
+		return
+	}
+	g.queues[c] = q
+	q[0].signal()
+}
+
+func (g *Guard) isHead(c Connector, w *waiter) bool {
+	g.qmu.Lock()
+	defer g.qmu.Unlock()
+	q := g.queues[c]
+	return len(q) > 0 && q[0] == w
+}
 
+// wakeHeads asks the head of every queue to retry, after tokens or split
+// quota were handed back.
+func (g *Guard) wakeHeads() {
+	g.qmu.Lock()
+	defer g.qmu.Unlock()
+	for _, q := range g.queues {
+		q[0].signal()
+	}
+}
+
+type acquireResult struct {
+	ok     bool
+	reason DenialReason
+	// retryIn is when the bucket will next hold enough tokens; zero means
+	// only a Release or Rebalance can help.
+	retryIn time.Duration
+}
+
+// tryAcquire reserves split quota under g.mu, then takes the tokens from the
+// store without holding it, so a slow store (DynamoDB) does not serialize
+// every connector. The reservation is handed back if the bucket is short.
+func (g *Guard) tryAcquire(ctx context.Context, opts AcquireOpts) (acquireResult, error) {
+	g.mu.Lock()
 	b, ok := g.buckets[opts.Connector]
 	if !ok {
-		return false
+		g.mu.Unlock()
+		return acquireResult{reason: DeniedUnknownConnector}, nil
 	}
-
 	// enforce split
-	if g.splitQuota[opts.Split] > 0 && g.splitUsed[opts.Split]+opts.Tokens > g.splitQuota[opts.Split] {
-		return false
+	g.maybeReflow()
+	sp := g.splits[opts.Connector]
+	if !sp.allows(opts.Split, opts.Tokens) {
+		retryIn := g.untilReflow()
+		g.mu.Unlock()
+		return acquireResult{reason: DeniedBySplit, retryIn: retryIn}, nil
 	}
+	sp.use(opts.Split, opts.Tokens)
+	g.mu.Unlock()
 
-	if b.take(opts.Tokens) {
-		g.splitUsed[opts.Split] += opts.Tokens
+	var short BucketState
+	took, err := g.update(ctx, opts.Connector, b, func(st *BucketState) bool {
+		if st.Tokens < opts.Tokens {
+			short = *st
+			return false
+		}
+		st.Tokens -= opts.Tokens
 		return true
-	}
-	return false
-}
+	})
 
-func (g *Guard) Release(connector Connector, tokens int64, split Split) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
-	if b, ok := g.buckets[connector]; ok {
-		b.put(tokens)
+	if took {
+		g.recordSpend(opts.Connector, opts.Tokens)
+		return acquireResult{ok: true}, nil
 	}
-	if tokens < 0 {
-		tokens = 0
+	sp.release(opts.Split, opts.Tokens)
+	if err != nil {
+		return acquireResult{}, err
 	}
-	if g.splitUsed[split] >= tokens {
-		g.splitUsed[split] -= tokens
-	} else {
-		g.splitUsed[split] = 0
+	return acquireResult{reason: DeniedByBucket, retryIn: b.nextAvailable(short, opts.Tokens, g.clock.Now())}, nil
+}
+
+// Release returns unused tokens to the connector bucket and the split.
+func (g *Guard) Release(ctx context.Context, connector Connector, tokens int64, split Split) error {
+	g.mu.RLock()
+	b, ok := g.buckets[connector]
+	g.mu.RUnlock()
+	if ok && tokens > 0 {
+		_, err := g.update(ctx, connector, b, func(st *BucketState) bool {
+			st.Tokens = min64(b.capacity, st.Tokens+tokens)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+	g.mu.Lock()
+	if ok && tokens > 0 {
+		g.refundSpend(connector, tokens)
 	}
+	g.splits[connector].release(split, tokens)
+	g.mu.Unlock()
+	g.wakeHeads()
+	return nil
 }
 
 type ProgressWindow struct {
 	LastNNewUnique int64
 	LastNCalls     int64
+	// LastNItems, when set, is the number of items the calls saw; the rate is
+	// then new items per item seen rather than per call.
+	LastNItems int64
+	// MinSamples is how many calls the window needs before EarlyStop may fire.
+	MinSamples int64
 }
 
 func (pw ProgressWindow) NewUniqueRate() float64 {
+	if pw.LastNItems > 0 {
+		return float64(pw.LastNNewUnique) / float64(pw.LastNItems)
+	}
 	if pw.LastNCalls == 0 {
 		return 1.0
 	}
 	return float64(pw.LastNNewUnique) / float64(pw.LastNCalls)
 }
 
+// Ready reports whether the window holds enough calls to judge progress.
+func (pw ProgressWindow) Ready() bool {
+	return pw.LastNCalls >= pw.MinSamples
+}
+
 // EarlyStop returns true if the new_unique_rate over the last window falls below threshold.
 func EarlyStop(pw ProgressWindow, threshold float64) bool {
-	return pw.NewUniqueRate() < threshold
+	return pw.Ready() && pw.NewUniqueRate() < threshold
 }
 
 func min64(a, b int64) int64 {
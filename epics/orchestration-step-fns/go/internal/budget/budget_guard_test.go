@@ -2,8 +2,11 @@ package budget
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/clock"
 )
 
 func TestAcquireRelease(t *testing.T) {
@@ -18,13 +21,16 @@ func TestAcquireRelease(t *testing.T) {
 		SplitRatio: 0.7,
 	}
 	g := NewGuard(cfg)
-	g.Rebalance()
-
 	ctx := context.Background()
+	if err := g.Rebalance(ctx); err != nil {
+		t.Fatalf("rebalance: %v", err)
+	}
 	if err := g.Acquire(ctx, AcquireOpts{Connector: GoogleText, Split: Primaries, Tokens: 5, Deadline: time.Second}); err != nil {
 		t.Fatalf("expected acquire ok, got %v", err)
 	}
-	g.Release(GoogleText, 3, Primaries)
+	if err := g.Release(ctx, GoogleText, 3, Primaries); err != nil {
+		t.Fatalf("release: %v", err)
+	}
 
 	if err := g.Acquire(ctx, AcquireOpts{Connector: GoogleText, Split: Secondaries, Tokens: 2, Deadline: time.Second}); err != nil {
 		t.Fatalf("expected acquire ok, got %v", err)
@@ -37,3 +43,182 @@ func TestEarlyStop(t *testing.T) {
 		t.Fatalf("expected early stop")
 	}
 }
+
+func refillingConnector(capacity, refill int64, period time.Duration) Config {
+	cfg := singleConnector(capacity)
+	b := cfg.Budgets[GoogleText]
+	b.Refill, b.Period = refill, period
+	cfg.Budgets[GoogleText] = b
+	return cfg
+}
+
+func TestAcquireUnknownConnectorFailsFast(t *testing.T) {
+	g := NewGuard(singleConnector(1))
+	start := time.Now()
+	err := g.Acquire(context.Background(), AcquireOpts{Connector: Overpass, Split: Primaries, Deadline: time.Second})
+	var denial *DenialError
+	if !errors.As(err, &denial) || denial.Reason != DeniedUnknownConnector {
+		t.Fatalf("err = %v, want unknown connector denial", err)
+	}
+	if !errors.Is(err, ErrUnknownConnector) || errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("err = %v matches the wrong sentinel", err)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Fatalf("unknown connector waited for the deadline")
+	}
+}
+
+func TestAcquireDenialReasons(t *testing.T) {
+	ctx := context.Background()
+
+	g := NewGuard(singleConnector(2))
+	if err := g.Acquire(ctx, AcquireOpts{Connector: GoogleText, Split: Primaries, Tokens: 2}); err != nil {
+		t.Fatal(err)
+	}
+	err := g.Acquire(ctx, AcquireOpts{Connector: GoogleText, Split: Primaries, Deadline: 20 * time.Millisecond})
+	var denial *DenialError
+	if !errors.As(err, &denial) || denial.Reason != DeniedByBucket || !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("err = %v, want bucket denial", err)
+	}
+
+	g = NewGuard(singleConnector(10))
+	if err := g.Rebalance(ctx); err != nil {
+		t.Fatal(err)
+	}
+	err = g.Acquire(ctx, AcquireOpts{Connector: GoogleText, Split: Secondaries, Tokens: 4, Deadline: 20 * time.Millisecond})
+	if !errors.As(err, &denial) || denial.Reason != DeniedBySplit {
+		t.Fatalf("err = %v, want split denial", err)
+	}
+}
+
+func TestAcquireConnectorGate(t *testing.T) {
+	killed := true
+	g := NewGuard(singleConnector(5), WithConnectorGate(func(ctx context.Context, c Connector) bool {
+		return !(killed && c == GoogleText)
+	}))
+	ctx := context.Background()
+	err := g.Acquire(ctx, AcquireOpts{Connector: GoogleText, Split: Primaries, Deadline: time.Second})
+	var denial *DenialError
+	if !errors.As(err, &denial) || denial.Reason != DeniedByKillSwitch {
+		t.Fatalf("err = %v, want kill switch denial", err)
+	}
+	if !errors.Is(err, ErrConnectorDisabled) || errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("err = %v matches the wrong sentinel", err)
+	}
+
+	killed = false
+	if err := g.Acquire(ctx, AcquireOpts{Connector: GoogleText, Split: Primaries}); err != nil {
+		t.Fatalf("acquire after un-kill: %v", err)
+	}
+}
+
+// acquireAsync runs Acquire in a goroutine and waits until it is blocked on
+// want fake timers, so the test can Advance deterministically.
+func acquireAsync(t *testing.T, g *Guard, clk *clock.Fake, opts AcquireOpts, want int) <-chan error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- g.Acquire(context.Background(), opts) }()
+	for clk.Timers() < want {
+		time.Sleep(time.Millisecond)
+	}
+	return done
+}
+
+func TestAcquireWakesAtRefill(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Unix(0, 0))
+	g := NewGuard(refillingConnector(1, 1, time.Minute), WithClock(clk))
+	if err := g.Acquire(ctx, AcquireOpts{Connector: GoogleText, Split: Primaries}); err != nil {
+		t.Fatal(err)
+	}
+	// Blocked on its deadline and the next-refill timer.
+	done := acquireAsync(t, g, clk, AcquireOpts{Connector: GoogleText, Split: Primaries, Deadline: time.Hour}, 2)
+	clk.Advance(59 * time.Second)
+	select {
+	case err := <-done:
+		t.Fatalf("acquired before the refill: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	clk.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("acquire after refill: %v", err)
+	}
+}
+
+func TestAcquireDeadlineOnFakeClock(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	g := NewGuard(singleConnector(1), WithClock(clk))
+	if err := g.Acquire(context.Background(), AcquireOpts{Connector: GoogleText, Split: Primaries}); err != nil {
+		t.Fatal(err)
+	}
+	// No refill, so only the deadline timer is pending.
+	done := acquireAsync(t, g, clk, AcquireOpts{Connector: GoogleText, Split: Primaries, Deadline: 2 * time.Hour}, 1)
+	clk.Advance(2 * time.Hour)
+	if err := <-done; !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("err = %v, want ErrBudgetExceeded at the deadline", err)
+	}
+}
+
+func TestRefillMath(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Unix(0, 0))
+	g := NewGuard(refillingConnector(100, 10, time.Minute), WithClock(clk))
+	if !take(t, g, GoogleText, Primaries, 100).ok {
+		t.Fatal("initial take denied")
+	}
+	// 3.5 periods: three refills of 10, and the half period carries over.
+	clk.Advance(3*time.Minute + 30*time.Second)
+	if res := take(t, g, GoogleText, Primaries, 31); res.ok || res.retryIn != 30*time.Second {
+		t.Fatalf("take 31 of 30 = %+v, want denial retrying at the next refill", res)
+	}
+	clk.Advance(30 * time.Second)
+	if !take(t, g, GoogleText, Primaries, 40).ok {
+		t.Fatal("four refills should cover 40 tokens")
+	}
+	// Hours later the bucket is capped at capacity.
+	clk.Advance(5 * time.Hour)
+	snap, err := g.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs := snap.Connectors[GoogleText]; cs.Tokens != 100 || cs.Refilled != 140 {
+		t.Fatalf("snapshot = %+v, want full bucket after 140 refilled", cs)
+	}
+}
+
+func TestAcquireIsFIFO(t *testing.T) {
+	ctx := context.Background()
+	g := NewGuard(singleConnector(1))
+	if err := g.Acquire(ctx, AcquireOpts{Connector: GoogleText, Split: Primaries}); err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		go func(i int) {
+			if err := g.Acquire(ctx, AcquireOpts{Connector: GoogleText, Split: Primaries, Deadline: 5 * time.Second}); err != nil {
+				t.Errorf("waiter %d: %v", i, err)
+				return
+			}
+			order <- i
+		}(i)
+		// Let waiter i queue before the next one arrives.
+		for {
+			g.qmu.Lock()
+			n := len(g.queues[GoogleText])
+			g.qmu.Unlock()
+			if n == i+1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	for want := 0; want < 3; want++ {
+		if err := g.Release(ctx, GoogleText, 1, Primaries); err != nil {
+			t.Fatal(err)
+		}
+		if got := <-order; got != want {
+			t.Fatalf("waiter %d acquired before waiter %d", got, want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package budget
+
+// Price converts a connector's usage into estimated US dollars. API
+// connectors are usually priced per call (one Acquire); llm.tokens is priced
+// per token.
+type Price struct {
+	PerCallUSD  float64 `yaml:"per_call_usd" json:"per_call_usd,omitempty"`
+	PerTokenUSD float64 `yaml:"per_token_usd" json:"per_token_usd,omitempty"`
+}
+
+// Cost is the estimated spend of one call using tokens tokens.
+func (p Price) Cost(tokens int64) float64 {
+	return p.PerCallUSD + float64(tokens)*p.PerTokenUSD
+}
+
+// SpendUSD is the estimated spend of every Acquire this Guard granted, less
+// per-token refunds for released tokens.
+func (g *Guard) SpendUSD() float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var total float64
+	for _, v := range g.spend {
+		total += v
+	}
+	return total
+}
+
+// recordSpend adds the cost of a granted Acquire. Callers hold g.mu.
+func (g *Guard) recordSpend(c Connector, tokens int64) {
+	if p, ok := g.prices[c]; ok {
+		g.spend[c] += p.Cost(tokens)
+	}
+}
+
+// refundSpend takes released tokens back out of the estimate; the call
+// itself was still made. Callers hold g.mu.
+func (g *Guard) refundSpend(c Connector, tokens int64) {
+	if p, ok := g.prices[c]; ok {
+		g.spend[c] -= float64(tokens) * p.PerTokenUSD
+		if g.spend[c] < 0 {
+			g.spend[c] = 0
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package budget
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSpendAccumulatesFromPrices(t *testing.T) {
+	ctx := context.Background()
+	cfg := Config{Prices: map[Connector]Price{
+		GoogleText: {PerCallUSD: 0.032},
+		LLMTokens:  {PerTokenUSD: 0.00001},
+	}}
+	cfg.Budgets = map[Connector]struct {
+		Capacity int64         `yaml:"capacity"`
+		Refill   int64         `yaml:"refill"`
+		Period   time.Duration `yaml:"period"`
+	}{
+		GoogleText: {Capacity: 10},
+		LLMTokens:  {Capacity: 10000},
+		Wiki:       {Capacity: 10},
+	}
+	g := NewGuard(cfg)
+	for _, opts := range []AcquireOpts{
+		{Connector: GoogleText, Split: Primaries},
+		{Connector: GoogleText, Split: Primaries},
+		{Connector: LLMTokens, Split: Primaries, Tokens: 2000},
+		{Connector: Wiki, Split: Primaries},
+	} {
+		if err := g.Acquire(ctx, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 500 of the reserved LLM tokens went unused.
+	if err := g.Release(ctx, LLMTokens, 500, Primaries); err != nil {
+		t.Fatal(err)
+	}
+
+	want := 2*0.032 + 1500*0.00001
+	if got := g.SpendUSD(); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("spend = %v, want %v", got, want)
+	}
+	snap, err := g.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(snap.Connectors[GoogleText].SpendUSD-0.064) > 1e-9 || snap.Connectors[Wiki].SpendUSD != 0 {
+		t.Fatalf("per-connector spend = %+v", snap.Connectors)
+	}
+}
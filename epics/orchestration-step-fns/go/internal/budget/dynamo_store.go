@@ -0,0 +1,120 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBAPI is the subset of *dynamodb.Client used by DynamoStore.
+type DynamoDBAPI interface {
+	GetItem(ctx context.Context, in *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, in *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+}
+
+// DynamoStore keeps bucket states in a DynamoDB table with string partition
+// key "run_id" and sort key "connector". Every write is conditional on the
+// version read, so concurrent Lambda workers never double-spend a token.
+type DynamoStore struct {
+	client DynamoDBAPI
+	table  string
+}
+
+func NewDynamoStore(client DynamoDBAPI, table string) *DynamoStore {
+	return &DynamoStore{client: client, table: table}
+}
+
+func (s *DynamoStore) Load(ctx context.Context, key StateKey) (BucketState, bool, error) {
+	out, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.table),
+		Key:            dynamoKey(key),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return BucketState{}, false, fmt.Errorf("dynamodb get %s/%s: %w", key.RunID, key.Connector, err)
+	}
+	if len(out.Item) == 0 {
+		return BucketState{}, false, nil
+	}
+	st, err := decodeDynamoState(out.Item)
+	if err != nil {
+		return BucketState{}, false, fmt.Errorf("dynamodb item %s/%s: %w", key.RunID, key.Connector, err)
+	}
+	return st, true, nil
+}
+
+func (s *DynamoStore) CompareAndSwap(ctx context.Context, key StateKey, prevVersion int64, next BucketState) error {
+	item := dynamoKey(key)
+	item["tokens"] = number(next.Tokens)
+	item["last"] = &types.AttributeValueMemberS{Value: next.Last.UTC().Format(time.RFC3339Nano)}
+	item["refilled"] = number(next.Refilled)
+	item["capacity"] = number(next.Capacity)
+	item["version"] = number(next.Version)
+
+	in := &dynamodb.PutItemInput{TableName: aws.String(s.table), Item: item}
+	if prevVersion == 0 {
+		in.ConditionExpression = aws.String("attribute_not_exists(run_id)")
+	} else {
+		in.ConditionExpression = aws.String("version = :v")
+		in.ExpressionAttributeValues = map[string]types.AttributeValue{":v": number(prevVersion)}
+	}
+	_, err := s.client.PutItem(ctx, in)
+	var failed *types.ConditionalCheckFailedException
+	if errors.As(err, &failed) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("dynamodb put %s/%s: %w", key.RunID, key.Connector, err)
+	}
+	return nil
+}
+
+func dynamoKey(key StateKey) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"run_id":    &types.AttributeValueMemberS{Value: key.RunID},
+		"connector": &types.AttributeValueMemberS{Value: string(key.Connector)},
+	}
+}
+
+func number(n int64) *types.AttributeValueMemberN {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(n, 10)}
+}
+
+func decodeDynamoState(item map[string]types.AttributeValue) (BucketState, error) {
+	var st BucketState
+	var err error
+	if st.Tokens, err = itemInt(item, "tokens"); err != nil {
+		return st, err
+	}
+	if st.Version, err = itemInt(item, "version"); err != nil {
+		return st, err
+	}
+	if st.Refilled, err = itemInt(item, "refilled"); err != nil {
+		return st, err
+	}
+	if st.Capacity, err = itemInt(item, "capacity"); err != nil {
+		return st, err
+	}
+	last, ok := item["last"].(*types.AttributeValueMemberS)
+	if !ok {
+		return st, errors.New("missing last")
+	}
+	if st.Last, err = time.Parse(time.RFC3339Nano, last.Value); err != nil {
+		return st, err
+	}
+	return st, nil
+}
+
+func itemInt(item map[string]types.AttributeValue, name string) (int64, error) {
+	v, ok := item[name].(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, fmt.Errorf("missing %s", name)
+	}
+	return strconv.ParseInt(v.Value, 10, 64)
+}
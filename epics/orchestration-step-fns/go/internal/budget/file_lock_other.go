@@ -0,0 +1,16 @@
+//go:build !unix
+
+package budget
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// tryLockFile fails outright: FileStore relies on flock, which this platform
+// lacks. Use MemoryStore or DynamoStore here.
+func tryLockFile(f *os.File) error {
+	return fmt.Errorf("budget: file store on %s: %w", runtime.GOOS, errors.ErrUnsupported)
+}
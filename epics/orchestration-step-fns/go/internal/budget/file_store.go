@@ -0,0 +1,140 @@
+package budget
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockPollInterval is how often a FileStore retries a held lock.
+const lockPollInterval = 5 * time.Millisecond
+
+// errLockHeld is returned by tryLockFile while another holder has the lock.
+var errLockHeld = errors.New("budget: lock held")
+
+// FileStore keeps each run's bucket states in <dir>/<run_id>.json for local
+// runs where several processes share a budget. Writes are serialized with an
+// flock on a lockfile next to the state file and land via rename, so readers
+// never see a partial file.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) Load(ctx context.Context, key StateKey) (BucketState, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return BucketState{}, false, err
+	}
+	states, err := s.read(key.RunID)
+	if err != nil {
+		return BucketState{}, false, err
+	}
+	st, ok := states[key.Connector]
+	return st, ok, nil
+}
+
+func (s *FileStore) CompareAndSwap(ctx context.Context, key StateKey, prevVersion int64, next BucketState) error {
+	unlock, err := s.lock(ctx, key.RunID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	states, err := s.read(key.RunID)
+	if err != nil {
+		return err
+	}
+	if states[key.Connector].Version != prevVersion {
+		return ErrConflict
+	}
+	states[key.Connector] = next
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(key.RunID))
+}
+
+func (s *FileStore) read(runID string) (map[Connector]BucketState, error) {
+	states := make(map[Connector]BucketState)
+	data, err := os.ReadFile(s.path(runID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("budget state %s: %w", s.path(runID), err)
+	}
+	return states, nil
+}
+
+// lock takes the run's lockfile, waiting until it is free or ctx ends. The
+// kernel drops the lock when its holder exits, so a crashed worker cannot
+// leave the run locked. The lockfile itself is never removed: a worker still
+// holding the old file open would no longer exclude one creating a new one.
+func (s *FileStore) lock(ctx context.Context, runID string) (unlock func(), err error) {
+	f, err := os.OpenFile(s.path(runID)+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		err := tryLockFile(f)
+		if err == nil {
+			// Closing the file releases the lock.
+			return func() { f.Close() }, nil
+		}
+		if !errors.Is(err, errLockHeld) {
+			f.Close()
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+func (s *FileStore) path(runID string) string {
+	return filepath.Join(s.dir, sanitizeRunID(runID)+".json")
+}
+
+// sanitizeRunID keeps run ids usable as file names.
+func sanitizeRunID(runID string) string {
+	out := []byte(runID)
+	for i, c := range out {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			out[i] = '_'
+		}
+	}
+	if len(out) == 0 || string(out) == "." || string(out) == ".." {
+		return "_"
+	}
+	return string(out)
+}
@@ -0,0 +1,51 @@
+package budget
+
+import (
+	"context"
+	"time"
+)
+
+// Reconfigure applies cfg to a running Guard, resizing buckets in place. A
+// connector's stored tokens move by the change in capacity, clamped to
+// [0, capacity], so raising a cap mid-run frees the extra tokens at once and
+// lowering it takes them away; the stored Capacity makes this happen once
+// even when every worker of a run reconfigures. Refill, period, prices and
+// split settings apply from the next Acquire. Connectors missing from cfg
+// are dropped and new ones start full. If Rebalance has run, split quotas
+// are rebuilt as it would.
+func (g *Guard) Reconfigure(ctx context.Context, cfg Config) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	buckets := make(map[Connector]*Bucket, len(cfg.Budgets))
+	for c, v := range cfg.Budgets {
+		period := v.Period
+		if period == 0 {
+			period = time.Minute
+		}
+		nb := newBucket(v.Capacity, v.Refill, period)
+		if old, ok := g.buckets[c]; ok {
+			_, err := g.update(ctx, c, old, func(st *BucketState) bool {
+				if st.Capacity == nb.capacity {
+					return false
+				}
+				st.Tokens = max(0, min(nb.capacity, st.Tokens+nb.capacity-st.Capacity))
+				st.Capacity = nb.capacity
+				return true
+			})
+			if err != nil {
+				return err
+			}
+		}
+		buckets[c] = nb
+	}
+	g.buckets = buckets
+	g.prices = cfg.Prices
+	g.splitRatio = cfg.SplitRatio
+	g.ratios = cfg.SplitRatios
+	g.rebalance = cfg.Rebalance
+	if g.splits != nil {
+		return g.resplit(ctx)
+	}
+	g.wakeHeads()
+	return nil
+}
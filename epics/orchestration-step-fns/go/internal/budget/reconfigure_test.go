@@ -0,0 +1,95 @@
+package budget
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/clock"
+)
+
+func tokens(t *testing.T, g *Guard, c Connector) int64 {
+	t.Helper()
+	snap, err := g.Snapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snap.Connectors[c].Tokens
+}
+
+func TestReconfigureResizesOncePerRun(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	a := NewGuard(singleConnector(5), WithStore(store, "run-1"))
+	b := NewGuard(singleConnector(5), WithStore(store, "run-1"))
+	take(t, a, GoogleText, Primaries, 3)
+
+	// Both workers pick up the raised cap; the 5 extra tokens appear once.
+	for _, g := range []*Guard{a, b, a} {
+		if err := g.Reconfigure(ctx, singleConnector(10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := tokens(t, b, GoogleText); got != 7 {
+		t.Fatalf("tokens after raising capacity = %d, want 7", got)
+	}
+
+	if err := a.Reconfigure(ctx, singleConnector(4)); err != nil {
+		t.Fatal(err)
+	}
+	if got := tokens(t, a, GoogleText); got != 1 {
+		t.Fatalf("tokens after lowering capacity = %d, want 1", got)
+	}
+	if err := a.Reconfigure(ctx, singleConnector(1)); err != nil {
+		t.Fatal(err)
+	}
+	if got := tokens(t, a, GoogleText); got != 0 {
+		t.Fatalf("tokens after lowering below usage = %d, want 0", got)
+	}
+}
+
+func TestReconfigureWakesWaiters(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Unix(0, 0))
+	g := NewGuard(singleConnector(1), WithClock(clk))
+	if err := g.Rebalance(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Capacity 1 leaves primaries no quota; secondaries take the token.
+	if res := take(t, g, GoogleText, Secondaries, 1); !res.ok {
+		t.Fatalf("first acquire denied by %s", res.reason)
+	}
+
+	// A deadline timer only: the bucket never refills, so nothing else wakes it.
+	done := acquireAsync(t, g, clk, AcquireOpts{Connector: GoogleText, Split: Primaries, Deadline: time.Minute}, 1)
+	if err := g.Reconfigure(ctx, singleConnector(10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("acquire after raising capacity: %v", err)
+	}
+	if q := quota(g, GoogleText, Primaries); q != 6 {
+		t.Fatalf("primaries quota rebuilt from 9 tokens = %d, want 6", q)
+	}
+}
+
+func TestReconfigureAddsAndDropsConnectors(t *testing.T) {
+	ctx := context.Background()
+	g := NewGuard(singleConnector(5))
+	cfg := singleConnector(5)
+	delete(cfg.Budgets, GoogleText)
+	cfg.Budgets[Overpass] = struct {
+		Capacity int64         `yaml:"capacity"`
+		Refill   int64         `yaml:"refill"`
+		Period   time.Duration `yaml:"period"`
+	}{Capacity: 3}
+	if err := g.Reconfigure(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Acquire(ctx, AcquireOpts{Connector: Overpass, Split: Primaries}); err != nil {
+		t.Fatalf("acquire on added connector: %v", err)
+	}
+	if err := g.Acquire(ctx, AcquireOpts{Connector: GoogleText, Split: Primaries}); err == nil {
+		t.Fatalf("expected dropped connector to be unknown")
+	}
+}
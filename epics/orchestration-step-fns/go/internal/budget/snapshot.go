@@ -0,0 +1,138 @@
+package budget
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	obs "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/observability"
+)
+
+// ConnectorSnapshot is one connector's budget at a point in the run.
+type ConnectorSnapshot struct {
+	Tokens   int64 `json:"tokens"`   // left in the bucket
+	Capacity int64 `json:"capacity"` // bucket size from config
+	Refilled int64 `json:"refilled"` // added by refills since the run started
+	Used     int64 `json:"used"`     // capacity + refilled - tokens
+	// Denials counts this process's Acquire denials by reason.
+	Denials map[DenialReason]int64 `json:"denials,omitempty"`
+	// SpendUSD is this process's estimated spend on the connector.
+	SpendUSD float64 `json:"spend_usd"`
+	// Splits is this process's usage against the connector's split quotas;
+	// empty before the first Rebalance.
+	Splits map[Split]SplitSnapshot `json:"splits,omitempty"`
+}
+
+// Utilization is the share of the tokens made available so far that has
+// been spent, in [0, 1].
+func (c ConnectorSnapshot) Utilization() float64 {
+	avail := c.Capacity + c.Refilled
+	if avail <= 0 {
+		return 0
+	}
+	return float64(c.Used) / float64(avail)
+}
+
+// SplitSnapshot is a split's usage against its current quota.
+type SplitSnapshot struct {
+	Used  int64 `json:"used"`
+	Quota int64 `json:"quota"`
+}
+
+// Snapshot is a Guard's budget accounting, suitable for run manifests.
+type Snapshot struct {
+	RunID      string                          `json:"run_id,omitempty"`
+	TakenAt    time.Time                       `json:"taken_at"`
+	Connectors map[Connector]ConnectorSnapshot `json:"connectors"`
+	SpendUSD   float64                         `json:"spend_usd"`
+	// PrimariesFound is the last count passed to SetPrimariesFound.
+	PrimariesFound int64 `json:"primaries_found,omitempty"`
+}
+
+// Snapshot reads every connector's state from the store, with refills due by
+// now applied, along with split usage and denial counts.
+func (g *Guard) Snapshot(ctx context.Context) (Snapshot, error) {
+	// Load from the store without g.mu so reporting never holds up Acquire.
+	g.mu.RLock()
+	buckets := maps.Clone(g.buckets)
+	g.mu.RUnlock()
+	states := make(map[Connector]BucketState, len(buckets))
+	for c, b := range buckets {
+		st, err := g.load(ctx, c, b)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		states[c] = st
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	snap := Snapshot{
+		RunID:          g.runID,
+		TakenAt:        g.clock.Now(),
+		Connectors:     make(map[Connector]ConnectorSnapshot, len(buckets)),
+		PrimariesFound: g.primaries,
+	}
+	for c, b := range buckets {
+		st := states[c]
+		cs := ConnectorSnapshot{
+			Tokens:   st.Tokens,
+			Capacity: b.capacity,
+			Refilled: st.Refilled,
+			Used:     b.capacity + st.Refilled - st.Tokens,
+			SpendUSD: g.spend[c],
+		}
+		snap.SpendUSD += g.spend[c]
+		if len(g.denials[c]) > 0 {
+			cs.Denials = make(map[DenialReason]int64, len(g.denials[c]))
+			for r, n := range g.denials[c] {
+				cs.Denials[r] = n
+			}
+		}
+		if sp := g.splits[c]; sp != nil {
+			cs.Splits = make(map[Split]SplitSnapshot, len(sp.quota))
+			for s, q := range sp.quota {
+				cs.Splits[s] = SplitSnapshot{Used: sp.used[s], Quota: q}
+			}
+		}
+		snap.Connectors[c] = cs
+	}
+	return snap, nil
+}
+
+// Report emits a BudgetCapUtilization gauge and the running TokenCostEstimate
+// per connector in snap.
+func Report(ctx context.Context, snap Snapshot, service, state, city string) {
+	for c, cs := range snap.Connectors {
+		obs.BudgetCapGauge(ctx, service, state, string(c), city, cs.Utilization())
+		if cs.SpendUSD > 0 {
+			obs.RecordTokenCostEstimate(ctx, service, state, string(c), city, cs.SpendUSD)
+		}
+	}
+}
+
+// ReportEvery snapshots the Guard and Reports it every interval, as measured
+// by the Guard's clock, until ctx ends. Snapshots that fail to load are
+// skipped until the next tick.
+func (g *Guard) ReportEvery(ctx context.Context, interval time.Duration, service, state, city string) {
+	g.everyInterval(ctx, interval, func(snap Snapshot) {
+		Report(ctx, snap, service, state, city)
+	})
+}
+
+// everyInterval calls report with a fresh snapshot every interval until ctx
+// ends.
+func (g *Guard) everyInterval(ctx context.Context, interval time.Duration, report func(Snapshot)) {
+	for {
+		t := g.clock.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C():
+		}
+		if snap, err := g.Snapshot(ctx); err == nil {
+			report(snap)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package budget
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/clock"
+)
+
+func TestSnapshotAccounting(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	g := NewGuard(refillingConnector(10, 5, time.Hour), WithStore(store, "edinburgh-1"))
+	if err := g.Rebalance(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Acquire(ctx, AcquireOpts{Connector: GoogleText, Split: Primaries, Tokens: 6}); err != nil {
+		t.Fatal(err)
+	}
+	// Pretend an hour passed: one refill of 5 is due.
+	key := StateKey{RunID: "edinburgh-1", Connector: GoogleText}
+	st, _, _ := store.Load(ctx, key)
+	prev := st.Version
+	st.Last = st.Last.Add(-time.Hour)
+	st.Version++
+	if err := store.CompareAndSwap(ctx, key, prev, st); err != nil {
+		t.Fatal(err)
+	}
+	g.Acquire(ctx, AcquireOpts{Connector: Overpass, Split: Primaries})
+
+	snap, err := g.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := snap.Connectors[GoogleText]
+	want := ConnectorSnapshot{Tokens: 9, Capacity: 10, Refilled: 5, Used: 6}
+	if cs.Tokens != want.Tokens || cs.Capacity != want.Capacity || cs.Refilled != want.Refilled || cs.Used != want.Used {
+		t.Fatalf("snapshot = %+v, want %+v", cs, want)
+	}
+	if u := cs.Utilization(); u != 0.4 {
+		t.Fatalf("utilization = %v, want 0.4", u)
+	}
+	if got := cs.Splits[Primaries]; got.Used != 6 || got.Quota != 7 {
+		t.Fatalf("primaries = %+v", got)
+	}
+	if snap.RunID != "edinburgh-1" {
+		t.Fatalf("run id = %q", snap.RunID)
+	}
+	if _, ok := snap.Connectors[Overpass]; ok {
+		t.Fatalf("unconfigured connector in snapshot")
+	}
+	if g.denials[Overpass][DeniedUnknownConnector] != 1 {
+		t.Fatalf("denials = %v", g.denials)
+	}
+}
+
+func TestReportEveryFollowsGuardClock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	clk := clock.NewFake(time.Unix(0, 0))
+	g := NewGuard(singleConnector(10), WithClock(clk))
+	if err := g.Rebalance(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	reports := make(chan Snapshot, 4)
+	done := make(chan struct{})
+	go func() {
+		g.everyInterval(ctx, time.Minute, func(s Snapshot) { reports <- s })
+		close(done)
+	}()
+	for tick := 1; tick <= 2; tick++ {
+		for clk.Timers() < 1 {
+			time.Sleep(time.Millisecond)
+		}
+		clk.Advance(59 * time.Second)
+		select {
+		case <-reports:
+			t.Fatalf("tick %d: reported before the interval elapsed", tick)
+		default:
+		}
+		clk.Advance(time.Second)
+		if snap := <-reports; snap.Connectors[GoogleText].Capacity != 10 {
+			t.Fatalf("tick %d: snapshot %+v", tick, snap.Connectors[GoogleText])
+		}
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,130 @@
+package budget
+
+import "time"
+
+// RebalanceConfig schedules reflowing unused split quota between Rebalance
+// calls. A zero Interval disables it and quotas stay as Rebalance set them.
+type RebalanceConfig struct {
+	Interval time.Duration `yaml:"interval" json:"interval,omitempty"`
+	// Once PrimariesTargetMin primaries are found, a starved secondaries split
+	// may take the primaries' unused quota; at PrimariesTargetMax primaries
+	// are done and hand all unused quota over regardless.
+	PrimariesTargetMin int64 `yaml:"primaries_target_min" json:"primaries_target_min,omitempty"`
+	PrimariesTargetMax int64 `yaml:"primaries_target_max" json:"primaries_target_max,omitempty"`
+}
+
+// splitState is one connector's quota and usage per split, in that
+// connector's own units.
+type splitState struct {
+	quota   map[Split]int64
+	used    map[Split]int64
+	starved map[Split]bool // denied by quota since the last reflow
+}
+
+func newSplitState(tokens int64, ratio float64) *splitState {
+	primaries := int64(float64(tokens) * ratio)
+	return &splitState{
+		quota:   map[Split]int64{Primaries: primaries, Secondaries: tokens - primaries},
+		used:    map[Split]int64{Primaries: 0, Secondaries: 0},
+		starved: make(map[Split]bool),
+	}
+}
+
+// allows reports whether split can take n more tokens, marking the split
+// starved if not. A nil state (no Rebalance yet) allows everything.
+func (s *splitState) allows(split Split, n int64) bool {
+	if s == nil {
+		return true
+	}
+	if s.used[split]+n > s.quota[split] {
+		s.starved[split] = true
+		return false
+	}
+	return true
+}
+
+func (s *splitState) use(split Split, n int64) {
+	if s != nil {
+		s.used[split] += n
+	}
+}
+
+func (s *splitState) release(split Split, n int64) {
+	if s == nil || n < 0 {
+		return
+	}
+	s.used[split] = max(0, s.used[split]-n)
+}
+
+// move hands from's unused quota to to.
+func (s *splitState) move(from, to Split) {
+	spare := s.quota[from] - s.used[from]
+	if spare <= 0 {
+		return
+	}
+	s.quota[from] -= spare
+	s.quota[to] += spare
+}
+
+// SetPrimariesFound records how many primaries the run has found, which
+// decides when primaries' quota may flow to secondaries.
+func (g *Guard) SetPrimariesFound(n int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.primaries = n
+}
+
+func (g *Guard) ratio(c Connector) float64 {
+	if r, ok := g.ratios[c]; ok {
+		return r
+	}
+	return g.splitRatio
+}
+
+// maybeReflow reflows quota if the rebalance interval has passed since the
+// last Rebalance or reflow. Callers hold g.mu.
+func (g *Guard) maybeReflow() {
+	if g.rebalance.Interval <= 0 || g.splits == nil {
+		return
+	}
+	now := g.clock.Now()
+	if now.Sub(g.lastReflow) < g.rebalance.Interval {
+		return
+	}
+	g.reflow()
+	g.lastReflow = now
+	g.wakeHeads()
+}
+
+// reflow moves unused quota, per connector, to a split that ran out since the
+// last reflow:
+//   - starved primaries take secondaries' spare quota until PrimariesTargetMin;
+//   - from PrimariesTargetMin, starved secondaries take primaries' spare quota;
+//   - from PrimariesTargetMax, secondaries take it whether starved or not.
+func (g *Guard) reflow() {
+	cfg := g.rebalance
+	met := cfg.PrimariesTargetMin > 0 && g.primaries >= cfg.PrimariesTargetMin
+	done := cfg.PrimariesTargetMax > 0 && g.primaries >= cfg.PrimariesTargetMax
+	for _, sp := range g.splits {
+		switch {
+		case done || (met && sp.starved[Secondaries]):
+			sp.move(Primaries, Secondaries)
+		case !met && sp.starved[Primaries]:
+			sp.move(Secondaries, Primaries)
+		}
+		sp.starved = make(map[Split]bool)
+	}
+}
+
+// untilReflow is how long a split-denied waiter should sleep before the next
+// scheduled reflow could free quota; zero when none is scheduled. Callers
+// hold g.mu.
+func (g *Guard) untilReflow() time.Duration {
+	if g.rebalance.Interval <= 0 || g.splits == nil {
+		return 0
+	}
+	if d := g.lastReflow.Add(g.rebalance.Interval).Sub(g.clock.Now()); d > 0 {
+		return d
+	}
+	return time.Millisecond
+}
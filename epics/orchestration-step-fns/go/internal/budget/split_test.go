@@ -0,0 +1,121 @@
+package budget
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/clock"
+)
+
+func splitGuard(t *testing.T, clk *clock.Fake, rebalance RebalanceConfig) *Guard {
+	t.Helper()
+	cfg := Config{SplitRatio: 0.7, Rebalance: rebalance, SplitRatios: map[Connector]float64{LLMTokens: 0.5}}
+	cfg.Budgets = map[Connector]struct {
+		Capacity int64         `yaml:"capacity"`
+		Refill   int64         `yaml:"refill"`
+		Period   time.Duration `yaml:"period"`
+	}{
+		GoogleText: {Capacity: 100},
+		LLMTokens:  {Capacity: 200000},
+	}
+	g := NewGuard(cfg, WithClock(clk))
+	if err := g.Rebalance(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func take(t *testing.T, g *Guard, c Connector, split Split, n int64) acquireResult {
+	t.Helper()
+	res, err := g.tryAcquire(context.Background(), AcquireOpts{Connector: c, Split: split, Tokens: n})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func quota(g *Guard, c Connector, split Split) int64 {
+	return g.splits[c].quota[split]
+}
+
+func TestSplitQuotasArePerConnector(t *testing.T) {
+	g := splitGuard(t, clock.NewFake(time.Unix(0, 0)), RebalanceConfig{})
+	if q := quota(g, GoogleText, Primaries); q != 70 {
+		t.Fatalf("google.text primaries quota = %d, want 70", q)
+	}
+	if q := quota(g, LLMTokens, Secondaries); q != 100000 {
+		t.Fatalf("llm.tokens secondaries quota = %d, want 100000", q)
+	}
+	// Spending LLM tokens does not eat into google.text's secondaries quota.
+	if !take(t, g, LLMTokens, Secondaries, 50000).ok {
+		t.Fatal("llm secondaries acquire denied")
+	}
+	if !take(t, g, GoogleText, Secondaries, 30).ok {
+		t.Fatal("google.text secondaries acquire denied")
+	}
+	if res := take(t, g, GoogleText, Secondaries, 1); res.ok || res.reason != DeniedBySplit {
+		t.Fatalf("acquire past quota = %+v, want split denial", res)
+	}
+}
+
+func TestReflowWaitsForInterval(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	g := splitGuard(t, clk, RebalanceConfig{Interval: time.Minute, PrimariesTargetMin: 150, PrimariesTargetMax: 200})
+	g.SetPrimariesFound(160)
+	take(t, g, GoogleText, Secondaries, 30)
+	if res := take(t, g, GoogleText, Secondaries, 5); res.reason != DeniedBySplit || res.retryIn != time.Minute {
+		t.Fatalf("denial = %+v, want split denial retrying at the next reflow", res)
+	}
+
+	clk.Advance(59 * time.Second)
+	if take(t, g, GoogleText, Secondaries, 5).ok {
+		t.Fatal("reflowed before the interval")
+	}
+	clk.Advance(time.Second)
+	if !take(t, g, GoogleText, Secondaries, 5).ok {
+		t.Fatal("starved secondaries did not get primaries' unused quota")
+	}
+	if p, s := quota(g, GoogleText, Primaries), quota(g, GoogleText, Secondaries); p != 0 || s != 100 {
+		t.Fatalf("quotas = %d/%d, want 0/100", p, s)
+	}
+	// llm.tokens secondaries were not starved, so their quota is untouched.
+	if q := quota(g, LLMTokens, Primaries); q != 100000 {
+		t.Fatalf("llm.tokens primaries quota = %d", q)
+	}
+}
+
+func TestReflowProtectsPrimariesBeforeTarget(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	g := splitGuard(t, clk, RebalanceConfig{Interval: time.Minute, PrimariesTargetMin: 150, PrimariesTargetMax: 200})
+	g.SetPrimariesFound(40)
+	take(t, g, GoogleText, Secondaries, 30)
+	take(t, g, GoogleText, Secondaries, 1)
+	clk.Advance(time.Minute)
+	if take(t, g, GoogleText, Secondaries, 1).ok {
+		t.Fatal("secondaries took primaries' quota before primaries reached their target")
+	}
+
+	// Starved primaries may borrow secondaries' spare quota meanwhile.
+	take(t, g, LLMTokens, Primaries, 100000)
+	take(t, g, LLMTokens, Primaries, 1)
+	clk.Advance(time.Minute)
+	if !take(t, g, LLMTokens, Primaries, 1).ok {
+		t.Fatal("starved primaries did not get secondaries' unused quota")
+	}
+}
+
+func TestReflowHandsOverWhenPrimariesDone(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	g := splitGuard(t, clk, RebalanceConfig{Interval: time.Minute, PrimariesTargetMin: 150, PrimariesTargetMax: 200})
+	take(t, g, GoogleText, Primaries, 20)
+	g.SetPrimariesFound(200)
+	clk.Advance(time.Minute)
+	take(t, g, GoogleText, Secondaries, 1) // any acquire runs the scheduled reflow
+	if p, s := quota(g, GoogleText, Primaries), quota(g, GoogleText, Secondaries); p != 20 || s != 80 {
+		t.Fatalf("quotas = %d/%d, want 20/80", p, s)
+	}
+	if p, s := quota(g, LLMTokens, Primaries), quota(g, LLMTokens, Secondaries); p != 0 || s != 200000 {
+		t.Fatalf("llm.tokens quotas = %d/%d, want 0/200000", p, s)
+	}
+}
@@ -0,0 +1,73 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrConflict is returned by Store.CompareAndSwap when the stored version no
+// longer matches; the caller reloads and retries.
+var ErrConflict = errors.New("budget: state changed concurrently")
+
+// StateKey identifies one connector bucket within a run.
+type StateKey struct {
+	RunID     string
+	Connector Connector
+}
+
+// BucketState is the persisted part of a Bucket. Version starts at 1 on the
+// first write and increases by one on every successful CompareAndSwap.
+type BucketState struct {
+	Tokens   int64     `json:"tokens"`
+	Last     time.Time `json:"last"`
+	Refilled int64     `json:"refilled,omitempty"` // tokens added by refills over the run
+	// Capacity the tokens were sized for; Reconfigure moves Tokens by the
+	// change from it exactly once across workers. Set on the first write.
+	Capacity int64 `json:"capacity,omitempty"`
+	Version  int64 `json:"version"`
+}
+
+// Store persists bucket state so every worker in a run draws from the same
+// budget. Implementations must make CompareAndSwap atomic across processes.
+type Store interface {
+	// Load returns the state for key; ok is false when nothing is stored yet.
+	Load(ctx context.Context, key StateKey) (state BucketState, ok bool, err error)
+	// CompareAndSwap stores next only if the current version equals
+	// prevVersion (0 meaning "not stored yet"), and returns ErrConflict otherwise.
+	CompareAndSwap(ctx context.Context, key StateKey, prevVersion int64, next BucketState) error
+}
+
+// MemoryStore is the default Store: state shared by Guards in one process.
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[StateKey]BucketState
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[StateKey]BucketState)}
+}
+
+func (s *MemoryStore) Load(ctx context.Context, key StateKey) (BucketState, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return BucketState{}, false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.states[key]
+	return st, ok, nil
+}
+
+func (s *MemoryStore) CompareAndSwap(ctx context.Context, key StateKey, prevVersion int64, next BucketState) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.states[key].Version != prevVersion {
+		return ErrConflict
+	}
+	s.states[key] = next
+	return nil
+}
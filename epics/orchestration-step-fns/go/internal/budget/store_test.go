@@ -0,0 +1,271 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/clock"
+)
+
+func singleConnector(capacity int64) Config {
+	cfg := Config{SplitRatio: 0.7}
+	cfg.Budgets = map[Connector]struct {
+		Capacity int64         `yaml:"capacity"`
+		Refill   int64         `yaml:"refill"`
+		Period   time.Duration `yaml:"period"`
+	}{
+		GoogleText: {Capacity: capacity, Refill: 0, Period: time.Hour},
+	}
+	return cfg
+}
+
+// drain has n Guards (as separate workers would) take one token at a time
+// until the shared bucket is empty, and returns the total taken.
+func drain(t *testing.T, n int, newGuard func() *Guard) int64 {
+	t.Helper()
+	ctx := context.Background()
+	var taken atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		g := newGuard()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				res, err := g.tryAcquire(ctx, AcquireOpts{Connector: GoogleText, Split: Primaries, Tokens: 1})
+				if err != nil {
+					t.Errorf("acquire: %v", err)
+					return
+				}
+				if !res.ok {
+					return
+				}
+				taken.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	return taken.Load()
+}
+
+func TestFileStoreSharesBudgetAcrossGuards(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	taken := drain(t, 4, func() *Guard {
+		return NewGuard(singleConnector(20), WithStore(store, "run-1"))
+	})
+	if taken != 20 {
+		t.Fatalf("took %d tokens from a 20-token bucket", taken)
+	}
+
+	// Another run starts with its own full bucket.
+	g := NewGuard(singleConnector(20), WithStore(store, "run-2"))
+	res, err := g.tryAcquire(context.Background(), AcquireOpts{Connector: GoogleText, Split: Primaries, Tokens: 20})
+	if err != nil || !res.ok {
+		t.Fatalf("run-2 acquire = %+v, %v", res, err)
+	}
+}
+
+func TestFileStoreConflict(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key := StateKey{RunID: "r", Connector: GoogleText}
+	if err := store.CompareAndSwap(ctx, key, 0, BucketState{Tokens: 5, Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CompareAndSwap(ctx, key, 0, BucketState{Tokens: 4, Version: 1}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("stale write err = %v, want ErrConflict", err)
+	}
+	st, ok, err := store.Load(ctx, key)
+	if err != nil || !ok || st.Tokens != 5 {
+		t.Fatalf("load = %+v, %v, %v", st, ok, err)
+	}
+}
+
+func TestFileStoreLockExcludesConcurrentHolders(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	var holders, maxHolders atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		// A store per goroutine, as separate workers would open the directory.
+		store, err := NewFileStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				unlock, err := store.lock(ctx, "run-1")
+				if err != nil {
+					t.Errorf("lock: %v", err)
+					return
+				}
+				n := holders.Add(1)
+				for {
+					m := maxHolders.Load()
+					if n <= m || maxHolders.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(100 * time.Microsecond)
+				holders.Add(-1)
+				unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if got := maxHolders.Load(); got != 1 {
+		t.Fatalf("%d goroutines held the lock at once", got)
+	}
+}
+
+func TestFileStoreLockIgnoresLeftoverLockfile(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A worker that crashed mid-write leaves its lockfile behind.
+	if err := os.WriteFile(store.path("run-1")+".lock", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	unlock, err := store.lock(ctx, "run-1")
+	if err != nil {
+		t.Fatalf("lock with a leftover lockfile: %v", err)
+	}
+	unlock()
+}
+
+// fakeDynamo evaluates the two condition expressions DynamoStore issues.
+type fakeDynamo struct {
+	mu    sync.Mutex
+	items map[string]map[string]types.AttributeValue
+}
+
+func (f *fakeDynamo) id(key map[string]types.AttributeValue) string {
+	return key["run_id"].(*types.AttributeValueMemberS).Value + "/" + key["connector"].(*types.AttributeValueMemberS).Value
+}
+
+func (f *fakeDynamo) GetItem(ctx context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &dynamodb.GetItemOutput{Item: f.items[f.id(in.Key)]}, nil
+}
+
+func (f *fakeDynamo) PutItem(ctx context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.id(in.Item)
+	cur, exists := f.items[id]
+	switch aws.ToString(in.ConditionExpression) {
+	case "attribute_not_exists(run_id)":
+		if exists {
+			return nil, &types.ConditionalCheckFailedException{}
+		}
+	case "version = :v":
+		want := in.ExpressionAttributeValues[":v"].(*types.AttributeValueMemberN).Value
+		if !exists || cur["version"].(*types.AttributeValueMemberN).Value != want {
+			return nil, &types.ConditionalCheckFailedException{}
+		}
+	}
+	f.items[id] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func TestDynamoStoreSharesBudgetAcrossGuards(t *testing.T) {
+	fake := &fakeDynamo{items: map[string]map[string]types.AttributeValue{}}
+	store := NewDynamoStore(fake, "budgets")
+	taken := drain(t, 4, func() *Guard {
+		return NewGuard(singleConnector(15), WithStore(store, "run-1"))
+	})
+	if taken != 15 {
+		t.Fatalf("took %d tokens from a 15-token bucket", taken)
+	}
+	item := fake.items["run-1/"+string(GoogleText)]
+	if got := item["tokens"].(*types.AttributeValueMemberN).Value; got != "0" {
+		t.Fatalf("stored tokens = %s", got)
+	}
+	if got := item["version"].(*types.AttributeValueMemberN).Value; got != strconv.Itoa(15) {
+		t.Fatalf("stored version = %s", got)
+	}
+}
+
+// contendedStore loses every conditional write, as if other workers always
+// got there first.
+type contendedStore struct {
+	*MemoryStore
+	writes atomic.Int32
+}
+
+func (s *contendedStore) CompareAndSwap(ctx context.Context, key StateKey, prevVersion int64, next BucketState) error {
+	s.writes.Add(1)
+	return ErrConflict
+}
+
+func TestUpdateGivesUpAfterMaxCASAttempts(t *testing.T) {
+	store := &contendedStore{MemoryStore: NewMemoryStore()}
+	clk := clock.NewFake(time.Unix(0, 0))
+	g := NewGuard(singleConnector(10), WithStore(store, "run-1"), WithClock(clk))
+	opts := AcquireOpts{Connector: GoogleText, Split: Primaries, Tokens: 7}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := g.tryAcquire(context.Background(), opts)
+		errc <- err
+	}()
+	for i := 1; i < maxCASAttempts; i++ {
+		for clk.Timers() < 1 {
+			time.Sleep(time.Millisecond)
+		}
+		clk.Advance(casBackoffMax)
+	}
+	err := <-errc
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Attempts != maxCASAttempts || !errors.Is(err, ErrConflict) {
+		t.Fatalf("err = %v, want ConflictError after %d attempts", err, maxCASAttempts)
+	}
+	if got := store.writes.Load(); got != maxCASAttempts {
+		t.Fatalf("made %d conditional writes, want %d", got, maxCASAttempts)
+	}
+	// The split reservation was handed back.
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if !g.splits[GoogleText].allows(Primaries, opts.Tokens) {
+		t.Fatalf("primaries quota still held after the failed acquire")
+	}
+}
+
+func TestDynamoStoreRejectsIncompleteItems(t *testing.T) {
+	item := map[string]types.AttributeValue{
+		"tokens":   number(5),
+		"version":  number(1),
+		"refilled": number(0),
+		"last":     &types.AttributeValueMemberS{Value: time.Unix(0, 0).UTC().Format(time.RFC3339Nano)},
+	}
+	if _, err := decodeDynamoState(item); err == nil || err.Error() != "missing capacity" {
+		t.Fatalf("decode without capacity = %v, want missing capacity", err)
+	}
+	item["capacity"] = number(10)
+	if st, err := decodeDynamoState(item); err != nil || st.Capacity != 10 {
+		t.Fatalf("decode = %+v, %v", st, err)
+	}
+}
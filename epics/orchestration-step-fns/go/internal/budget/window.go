@@ -0,0 +1,98 @@
+package budget
+
+import (
+	"sort"
+	"sync"
+)
+
+// Window is a ring buffer over the last N calls, each recording how many
+// items it saw and how many of those were new. It reports progress over that
+// window only, so a strong start does not mask a run that has stopped finding
+// anything.
+type Window struct {
+	mu         sync.Mutex
+	newUnique  []int64
+	items      []int64
+	next       int
+	count      int
+	minSamples int
+}
+
+// NewWindow keeps the last size calls and reports no progress window until
+// minSamples calls have been recorded. minSamples <= 0 or > size means a full
+// window.
+func NewWindow(size, minSamples int) *Window {
+	if size < 1 {
+		size = 1
+	}
+	if minSamples <= 0 || minSamples > size {
+		minSamples = size
+	}
+	return &Window{
+		newUnique:  make([]int64, size),
+		items:      make([]int64, size),
+		minSamples: minSamples,
+	}
+}
+
+// Record adds one call, evicting the oldest when the window is full.
+func (w *Window) Record(newUnique, items int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.newUnique[w.next] = newUnique
+	w.items[w.next] = items
+	w.next = (w.next + 1) % len(w.items)
+	if w.count < len(w.items) {
+		w.count++
+	}
+}
+
+// Progress sums the calls currently in the window. MinSamples is carried
+// along so EarlyStop holds off on an underfilled window.
+func (w *Window) Progress() ProgressWindow {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	pw := ProgressWindow{LastNCalls: int64(w.count), MinSamples: int64(w.minSamples)}
+	for i := 0; i < w.count; i++ {
+		pw.LastNNewUnique += w.newUnique[i]
+		pw.LastNItems += w.items[i]
+	}
+	return pw
+}
+
+// WindowSet keeps one Window per key, typically per state (SeedPrimaries,
+// TileSweep, ...), so each phase of discovery is judged on its own yield.
+type WindowSet struct {
+	mu         sync.Mutex
+	size       int
+	minSamples int
+	windows    map[string]*Window
+}
+
+func NewWindowSet(size, minSamples int) *WindowSet {
+	return &WindowSet{size: size, minSamples: minSamples, windows: make(map[string]*Window)}
+}
+
+// Window returns the window for key, creating it on first use.
+func (s *WindowSet) Window(key string) *Window {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := s.windows[key]
+	if !ok {
+		w = NewWindow(s.size, s.minSamples)
+		s.windows[key] = w
+	}
+	return w
+}
+
+// Keys lists the keys with a window, sorted.
+func (s *WindowSet) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.windows))
+	for k := range s.windows {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,51 @@
+package budget
+
+import "testing"
+
+func TestWindowSlides(t *testing.T) {
+	w := NewWindow(4, 2)
+	w.Record(1, 0)
+	if w.Progress().Ready() {
+		t.Fatalf("ready after 1 of 2 min samples")
+	}
+	w.Record(1, 0)
+	for i := 0; i < 4; i++ {
+		w.Record(0, 0)
+	}
+	pw := w.Progress()
+	if pw.LastNCalls != 4 || pw.LastNNewUnique != 0 {
+		t.Fatalf("progress = %+v, want the strong start evicted", pw)
+	}
+	if !EarlyStop(pw, 0.05) {
+		t.Fatalf("expected early stop once the window is all misses")
+	}
+}
+
+func TestWindowRateUsesItemsWhenRecorded(t *testing.T) {
+	w := NewWindow(10, 0)
+	for i := 0; i < 10; i++ {
+		w.Record(1, 20)
+	}
+	if got := w.Progress().NewUniqueRate(); got != 0.05 {
+		t.Fatalf("rate = %v, want 10/200", got)
+	}
+}
+
+func TestEarlyStopNeedsMinSamples(t *testing.T) {
+	pw := ProgressWindow{LastNNewUnique: 0, LastNCalls: 10, MinSamples: 50}
+	if EarlyStop(pw, 0.05) {
+		t.Fatalf("early stop on an underfilled window")
+	}
+}
+
+func TestWindowSetKeepsStatesApart(t *testing.T) {
+	s := NewWindowSet(3, 3)
+	s.Window("SeedPrimaries").Record(1, 1)
+	s.Window("TileSweep").Record(0, 1)
+	if got := s.Window("SeedPrimaries").Progress(); got.LastNNewUnique != 1 || got.LastNCalls != 1 {
+		t.Fatalf("SeedPrimaries = %+v", got)
+	}
+	if keys := s.Keys(); len(keys) != 2 || keys[0] != "SeedPrimaries" || keys[1] != "TileSweep" {
+		t.Fatalf("keys = %v", keys)
+	}
+}
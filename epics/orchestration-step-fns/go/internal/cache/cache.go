@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is matched (via errors.Is) by every miss from a RawCache.
+var ErrNotFound = errors.New("cache: key not found")
+
+// NotFoundError is returned by Get when no object is stored under Key.
+type NotFoundError struct {
+	Key string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("cache: key %q not found", e.Key)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// IsNotFound reports whether err is a cache miss.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// Object is a cached raw response with the metadata connectors need for
+// cache-first lookups.
+type Object struct {
+	Body        []byte
+	ContentType string
+	FetchedAt   time.Time
+}
+
+// RawCache stores raw connector responses under the keys built by HTMLKey and
+// JSONKey (and run artifacts such as manifests).
+type RawCache interface {
+	Put(ctx context.Context, key string, obj Object) error
+	Get(ctx context.Context, key string) (Object, error)
+}
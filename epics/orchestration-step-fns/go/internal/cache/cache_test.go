@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testRawCache is the behaviour every RawCache backend must share.
+func testRawCache(t *testing.T, c RawCache) {
+	t.Helper()
+	ctx := context.Background()
+	fetched := time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	key, err := JSONKey("Edinburgh", "google_places", Request{URL: "https://places.example/search?q=cafe&lat=55.9"})
+	require.NoError(t, err)
+
+	_, err = c.Get(ctx, key)
+	require.ErrorIs(t, err, ErrNotFound)
+	var nf *NotFoundError
+	require.True(t, errors.As(err, &nf))
+	require.Equal(t, key, nf.Key)
+
+	obj := Object{Body: []byte(`{"results":[]}`), ContentType: "application/json", FetchedAt: fetched}
+	require.NoError(t, c.Put(ctx, key, obj))
+	got, err := c.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, obj.Body, got.Body)
+	require.Equal(t, "application/json", got.ContentType)
+	require.True(t, fetched.Equal(got.FetchedAt), "fetched_at %v", got.FetchedAt)
+
+	// Overwrites replace body and metadata.
+	html := []byte("<html>menu</html>")
+	htmlKey := HTMLKey("Edinburgh", "www.Example.com", html)
+	require.NoError(t, c.Put(ctx, htmlKey, Object{Body: html, ContentType: "text/html"}))
+	require.NoError(t, c.Put(ctx, htmlKey, Object{Body: html, ContentType: "text/html; charset=utf-8"}))
+	got, err = c.Get(ctx, htmlKey)
+	require.NoError(t, err)
+	require.Equal(t, "text/html; charset=utf-8", got.ContentType)
+}
+
+func TestMemoryCache(t *testing.T) {
+	testRawCache(t, NewMemoryCache())
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// metaSuffix names the sidecar file holding an object's metadata.
+const metaSuffix = ".meta.json"
+
+// FileCache is a RawCache rooted at a local directory. Keys map to relative
+// paths, so the documented raw/html and raw/json layout is kept on disk.
+type FileCache struct {
+	root string
+}
+
+type fileMeta struct {
+	ContentType string    `json:"content_type,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+func NewFileCache(root string) *FileCache {
+	return &FileCache{root: root}
+}
+
+func (c *FileCache) Put(ctx context.Context, key string, obj Object) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	path, err := c.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(fileMeta{ContentType: obj.ContentType, FetchedAt: obj.FetchedAt.UTC()})
+	if err != nil {
+		return err
+	}
+	// Metadata first: a reader that sees the body always finds its metadata.
+	if err := writeAtomic(path+metaSuffix, meta); err != nil {
+		return err
+	}
+	return writeAtomic(path, obj.Body)
+}
+
+func (c *FileCache) Get(ctx context.Context, key string) (Object, error) {
+	if err := ctx.Err(); err != nil {
+		return Object{}, err
+	}
+	path, err := c.path(key)
+	if err != nil {
+		return Object{}, err
+	}
+	body, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Object{}, &NotFoundError{Key: key}
+	}
+	if err != nil {
+		return Object{}, err
+	}
+	raw, err := os.ReadFile(path + metaSuffix)
+	if err != nil {
+		return Object{}, err
+	}
+	var meta fileMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return Object{}, fmt.Errorf("cache: %s: %w", key, err)
+	}
+	return Object{Body: body, ContentType: meta.ContentType, FetchedAt: meta.FetchedAt}, nil
+}
+
+// path resolves key under the root, rejecting keys that would escape it.
+func (c *FileCache) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) || strings.HasSuffix(key, metaSuffix) {
+		return "", fmt.Errorf("cache: invalid key %q", key)
+	}
+	return filepath.Join(c.root, clean), nil
+}
+
+// writeAtomic writes data to a temp file in the target directory and renames
+// it into place so concurrent readers never see a partial object.
+func writeAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,28 @@
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCache(t *testing.T) {
+	testRawCache(t, NewFileCache(t.TempDir()))
+}
+
+func TestFileCache_LayoutAndInvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	c := NewFileCache(root)
+
+	key := HTMLKey("Edinburgh", "example.com", []byte("x"))
+	require.NoError(t, c.Put(ctx, key, Object{Body: []byte("x"), ContentType: "text/html"}))
+	require.FileExists(t, filepath.Join(root, filepath.FromSlash(key)))
+	require.FileExists(t, filepath.Join(root, filepath.FromSlash(key)+metaSuffix))
+
+	for _, bad := range []string{"", "../escape", "/abs/path", "raw/x.json" + metaSuffix} {
+		require.Error(t, c.Put(ctx, bad, Object{}), bad)
+	}
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Request identifies a connector call for caching. Two requests that differ
+// only in query parameter order, scheme/host case, or method case hash the same.
+type Request struct {
+	Method string
+	URL    string
+	Body   []byte
+}
+
+// ContentHash is the hex SHA-256 of a response body.
+func ContentHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestHash is the hex SHA-256 of the canonical form of req.
+func RequestHash(req Request) (string, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return "", fmt.Errorf("request hash: %w", err)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.RawQuery = u.Query().Encode() // sorted by key
+	u.Fragment = ""
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = "GET"
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", method, u.String())
+	h.Write(req.Body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HTMLKey returns raw/html/<city>/<domain>/<content_hash>.html.
+func HTMLKey(city, domain string, body []byte) string {
+	return fmt.Sprintf("raw/html/%s/%s/%s.html", segment(city), segment(strings.TrimPrefix(strings.ToLower(domain), "www.")), ContentHash(body))
+}
+
+// JSONKey returns raw/json/<city>/<source>/<request_hash>.json.
+func JSONKey(city, source string, req Request) (string, error) {
+	hash, err := RequestHash(req)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("raw/json/%s/%s/%s.json", segment(city), segment(source), hash), nil
+}
+
+// segment normalizes a key component: lower case, with anything outside
+// [a-z0-9._-] replaced by "-", so city names like "San Francisco" stay path-safe.
+func segment(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('-')
+		}
+	}
+	out := b.String()
+	if out == "" || out == "." || out == ".." {
+		return "_"
+	}
+	return out
+}
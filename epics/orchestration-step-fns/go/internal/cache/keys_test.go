@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeys_Layout(t *testing.T) {
+	body := []byte("<html></html>")
+	require.Equal(t, "raw/html/san-francisco/example.com/"+ContentHash(body)+".html",
+		HTMLKey("San Francisco", "WWW.example.com", body))
+
+	key, err := JSONKey("Edinburgh", "google_places", Request{URL: "https://places.example/search"})
+	require.NoError(t, err)
+	require.Regexp(t, `^raw/json/edinburgh/google_places/[0-9a-f]{64}\.json$`, key)
+
+	require.Equal(t, "raw/html/_/_/"+ContentHash(nil)+".html", HTMLKey("..", "", nil), "segments cannot traverse")
+}
+
+func TestRequestHash_Canonical(t *testing.T) {
+	base, err := RequestHash(Request{Method: "GET", URL: "https://Places.Example/search?q=cafe&lat=55.9"})
+	require.NoError(t, err)
+
+	same := []Request{
+		{URL: "https://places.example/search?lat=55.9&q=cafe"},
+		{Method: "get", URL: "HTTPS://places.example/search?q=cafe&lat=55.9#frag"},
+	}
+	for _, req := range same {
+		h, err := RequestHash(req)
+		require.NoError(t, err)
+		require.Equal(t, base, h, "%+v", req)
+	}
+
+	different := []Request{
+		{URL: "https://places.example/search?q=bar&lat=55.9"},
+		{Method: "POST", URL: "https://places.example/search?q=cafe&lat=55.9"},
+		{URL: "https://places.example/search?q=cafe&lat=55.9", Body: []byte(`{}`)},
+	}
+	for _, req := range different {
+		h, err := RequestHash(req)
+		require.NoError(t, err)
+		require.NotEqual(t, base, h, "%+v", req)
+	}
+
+	_, err = RequestHash(Request{URL: "://bad"})
+	require.Error(t, err)
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryCache is an in-process RawCache for tests and local runs.
+type MemoryCache struct {
+	mu      sync.RWMutex
+	objects map[string]Object
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{objects: make(map[string]Object)}
+}
+
+func (c *MemoryCache) Put(ctx context.Context, key string, obj Object) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	obj.Body = clone(obj.Body)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.objects[key] = obj
+	return nil
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) (Object, error) {
+	if err := ctx.Err(); err != nil {
+		return Object{}, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	obj, ok := c.objects[key]
+	if !ok {
+		return Object{}, &NotFoundError{Key: key}
+	}
+	obj.Body = clone(obj.Body)
+	return obj, nil
+}
+
+// Keys returns the keys currently stored, in no particular order.
+func (c *MemoryCache) Keys() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys := make([]string, 0, len(c.objects))
+	for k := range c.objects {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func clone(b []byte) []byte {
+	cp := make([]byte, len(b))
+	copy(cp, b)
+	return cp
+}
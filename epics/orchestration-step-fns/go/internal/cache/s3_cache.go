@@ -1,8 +1,94 @@
 package cache
 
-import "context"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"time"
 
-type RawCache interface {
-	Put(ctx context.Context, key string, body []byte) error
-	Get(ctx context.Context, key string) ([]byte, error)
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// fetchedAtMetadata is the S3 user metadata key (x-amz-meta-fetched-at)
+// holding Object.FetchedAt as RFC 3339.
+const fetchedAtMetadata = "fetched-at"
+
+// S3Cache is a RawCache backed by an S3 bucket. For MinIO or other local
+// stand-ins, build the client with BaseEndpoint set and UsePathStyle enabled.
+type S3Cache struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// NewS3Cache stores objects in bucket, optionally below prefix (e.g. an
+// environment name), so a key raw/json/... lands at <prefix>/raw/json/....
+func NewS3Cache(client *s3.Client, bucket, prefix string) *S3Cache {
+	return &S3Cache{client: client, bucket: bucket, prefix: prefix}
+}
+
+func (c *S3Cache) Put(ctx context.Context, key string, obj Object) error {
+	in := &s3.PutObjectInput{
+		Bucket:   aws.String(c.bucket),
+		Key:      aws.String(c.objectKey(key)),
+		Body:     bytes.NewReader(obj.Body),
+		Metadata: map[string]string{fetchedAtMetadata: obj.FetchedAt.UTC().Format(time.RFC3339Nano)},
+	}
+	if obj.ContentType != "" {
+		in.ContentType = aws.String(obj.ContentType)
+	}
+	if _, err := c.client.PutObject(ctx, in); err != nil {
+		return fmt.Errorf("s3 put %s: %w", key, err)
+	}
+	return nil
+}
+
+func (c *S3Cache) Get(ctx context.Context, key string) (Object, error) {
+	out, err := c.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(c.objectKey(key)),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			return Object{}, &NotFoundError{Key: key}
+		}
+		return Object{}, fmt.Errorf("s3 get %s: %w", key, err)
+	}
+	defer out.Body.Close()
+	body, err := io.ReadAll(out.Body)
+	if err != nil {
+		return Object{}, fmt.Errorf("s3 get %s: %w", key, err)
+	}
+	obj := Object{Body: body, ContentType: aws.ToString(out.ContentType)}
+	if v, ok := out.Metadata[fetchedAtMetadata]; ok {
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			obj.FetchedAt = t
+		}
+	}
+	return obj, nil
+}
+
+func (c *S3Cache) objectKey(key string) string {
+	if c.prefix == "" {
+		return key
+	}
+	return path.Join(c.prefix, key)
+}
+
+// isS3NotFound matches NoSuchKey and bare 404s, which some stand-ins return
+// without an error code.
+func isS3NotFound(err error) bool {
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return true
+	}
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound
 }
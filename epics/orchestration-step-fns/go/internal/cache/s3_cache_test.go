@@ -1,7 +1,98 @@
 package cache
 
-import "testing"
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
 
-func TestRawCache_Pending(t *testing.T) {
-	t.Skip("Pending: implement S3-backed cache and in-memory fake with collision tests.")
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeObject struct {
+	body   []byte
+	header http.Header
+}
+
+// fakeS3 is a path-style S3 stand-in (like MinIO) supporting PutObject and
+// GetObject. Objects are keyed by "<bucket>/<key>".
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h := http.Header{}
+		for k, v := range r.Header {
+			if k == "Content-Type" || strings.HasPrefix(k, "X-Amz-Meta-") {
+				h[k] = v
+			}
+		}
+		f.objects[name] = fakeObject{body: body, header: h}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		obj, ok := f.objects[name]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+			return
+		}
+		for k, v := range obj.header {
+			w.Header()[k] = v
+		}
+		w.Write(obj.body)
+	default:
+		http.Error(w, "unsupported", http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestS3Cache(t *testing.T, prefix string) (*S3Cache, *fakeS3) {
+	fake := &fakeS3{objects: make(map[string]fakeObject)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		UsePathStyle: true,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "minio", SecretAccessKey: "minio123"}, nil
+		}),
+	})
+	return NewS3Cache(client, "jaunt-raw", prefix), fake
+}
+
+func TestS3Cache(t *testing.T) {
+	c, _ := newTestS3Cache(t, "")
+	testRawCache(t, c)
+}
+
+func TestS3Cache_PrefixAndMetadata(t *testing.T) {
+	c, fake := newTestS3Cache(t, "dev")
+	ctx := context.Background()
+
+	key := HTMLKey("Edinburgh", "example.com", []byte("<p>hi</p>"))
+	require.NoError(t, c.Put(ctx, key, Object{Body: []byte("<p>hi</p>"), ContentType: "text/html"}))
+
+	fake.mu.Lock()
+	obj, ok := fake.objects["jaunt-raw/dev/"+key]
+	fake.mu.Unlock()
+	require.True(t, ok, "object stored under bucket/prefix/key")
+	require.Equal(t, "text/html", obj.header.Get("Content-Type"))
+	require.NotEmpty(t, obj.header.Get("X-Amz-Meta-Fetched-At"))
 }
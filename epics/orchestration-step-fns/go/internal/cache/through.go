@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	obs "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/observability"
+)
+
+// DefaultTTLs are the per-source freshness windows used by NewThrough. They
+// stay inside the S3 lifecycle expirations in terraform/s3.tf.
+var DefaultTTLs = map[string]time.Duration{
+	"overpass":  7 * 24 * time.Hour,
+	"tavily":    24 * time.Hour,
+	"web.fetch": 30 * 24 * time.Hour,
+}
+
+// FetchFunc performs the real connector call on a cache miss.
+type FetchFunc func(ctx context.Context) (Object, error)
+
+// ThroughStats counts lookups served by a Through.
+type ThroughStats struct {
+	Hits   int64
+	Misses int64
+}
+
+// Through is a cache-aside wrapper around a RawCache: fresh entries are served
+// from the cache, and misses call the connector once no matter how many
+// callers ask for the same key concurrently.
+type Through struct {
+	Cache RawCache
+	// TTLs maps a source to how long its responses stay fresh. Sources
+	// without an entry bypass the cache entirely.
+	TTLs map[string]time.Duration
+	Now  func() time.Time
+
+	group  singleflight.Group
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewThrough wraps c using ttls, or DefaultTTLs when ttls is nil.
+func NewThrough(c RawCache, ttls map[string]time.Duration) *Through {
+	if ttls == nil {
+		ttls = DefaultTTLs
+	}
+	return &Through{Cache: c, TTLs: ttls, Now: time.Now}
+}
+
+// Do returns the object cached under key when it is younger than source's
+// TTL, and otherwise calls fetch and stores the result. Concurrent calls for
+// the same key share one fetch; a caller whose ctx ends stops waiting without
+// cancelling the fetch for the others. A failed cache read is treated as a
+// miss and a failed write is ignored, so the cache never blocks a fetch.
+func (t *Through) Do(ctx context.Context, source, key string, fetch FetchFunc) (Object, error) {
+	ttl, cacheable := t.TTLs[source]
+	if !cacheable {
+		return fetch(ctx)
+	}
+	city := keyCity(key)
+
+	if obj, err := t.Cache.Get(ctx, key); err == nil && t.fresh(obj, ttl) {
+		t.hits.Add(1)
+		obs.CountCacheHit(ctx, "cache", "through", source, city)
+		return obj, nil
+	}
+
+	ch := t.group.DoChan(key, func() (any, error) {
+		// The flight outlives whichever caller started it.
+		ctx := context.WithoutCancel(ctx)
+		// Re-check: another flight may have filled the key since our read.
+		if obj, err := t.Cache.Get(ctx, key); err == nil && t.fresh(obj, ttl) {
+			t.hits.Add(1)
+			obs.CountCacheHit(ctx, "cache", "through", source, city)
+			return obj, nil
+		}
+		t.misses.Add(1)
+		obs.CountCacheMiss(ctx, "cache", "through", source, city)
+		obj, err := fetch(ctx)
+		if err != nil {
+			return Object{}, err
+		}
+		if obj.FetchedAt.IsZero() {
+			obj.FetchedAt = t.Now()
+		}
+		_ = t.Cache.Put(ctx, key, obj)
+		return obj, nil
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return Object{}, res.Err
+		}
+		return res.Val.(Object), nil
+	case <-ctx.Done():
+		return Object{}, ctx.Err()
+	}
+}
+
+// Stats returns the hit and miss counts so far. Calls that joined another
+// caller's in-flight fetch count as neither.
+func (t *Through) Stats() ThroughStats {
+	return ThroughStats{Hits: t.hits.Load(), Misses: t.misses.Load()}
+}
+
+func (t *Through) fresh(obj Object, ttl time.Duration) bool {
+	return !obj.FetchedAt.IsZero() && t.Now().Sub(obj.FetchedAt) < ttl
+}
+
+// keyCity returns the <city> segment of a raw/html or raw/json key.
+func keyCity(key string) string {
+	parts := strings.Split(key, "/")
+	if len(parts) > 2 && parts[0] == "raw" {
+		return parts[2]
+	}
+	return ""
+}
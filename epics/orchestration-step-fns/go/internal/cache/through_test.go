@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestThrough() (*Through, *time.Time) {
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	th := NewThrough(NewMemoryCache(), nil)
+	th.Now = func() time.Time { return now }
+	return th, &now
+}
+
+func TestThrough_TTLPerSource(t *testing.T) {
+	ctx := context.Background()
+	th, now := newTestThrough()
+	calls := 0
+	fetch := func(context.Context) (Object, error) {
+		calls++
+		return Object{Body: []byte(`{"elements":[]}`), ContentType: "application/json"}, nil
+	}
+	key, err := JSONKey("Edinburgh", "tavily", Request{URL: "https://api.tavily.example/search?q=cafes"})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		obj, err := th.Do(ctx, "tavily", key, fetch)
+		require.NoError(t, err)
+		require.Equal(t, `{"elements":[]}`, string(obj.Body))
+	}
+	require.Equal(t, 1, calls)
+	require.Equal(t, ThroughStats{Hits: 2, Misses: 1}, th.Stats())
+
+	// tavily is fresh for a day.
+	*now = now.Add(23 * time.Hour)
+	_, err = th.Do(ctx, "tavily", key, fetch)
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+	*now = now.Add(time.Hour)
+	_, err = th.Do(ctx, "tavily", key, fetch)
+	require.NoError(t, err)
+	require.Equal(t, 2, calls, "expired after 24h")
+
+	// Sources without a TTL are never cached.
+	for i := 0; i < 2; i++ {
+		_, err = th.Do(ctx, "google_places", "raw/json/edinburgh/google_places/x.json", fetch)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 4, calls)
+}
+
+func TestThrough_ErrorsAreNotCached(t *testing.T) {
+	ctx := context.Background()
+	th, _ := newTestThrough()
+	boom := errors.New("overpass 504")
+
+	_, err := th.Do(ctx, "overpass", "raw/json/edinburgh/overpass/a.json", func(context.Context) (Object, error) {
+		return Object{}, boom
+	})
+	require.ErrorIs(t, err, boom)
+
+	obj, err := th.Do(ctx, "overpass", "raw/json/edinburgh/overpass/a.json", func(context.Context) (Object, error) {
+		return Object{Body: []byte("ok")}, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(obj.Body))
+}
+
+func TestThrough_SingleflightDedupesConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	th, _ := newTestThrough()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fetch := func(context.Context) (Object, error) {
+		calls.Add(1)
+		<-release
+		return Object{Body: []byte("<html/>"), ContentType: "text/html"}, nil
+	}
+
+	const callers = 8
+	var wg sync.WaitGroup
+	results := make([]Object, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			obj, err := th.Do(ctx, "web.fetch", "raw/json/edinburgh/web.fetch/page.json", fetch)
+			require.NoError(t, err)
+			results[i] = obj
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond) // let every caller join the flight
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, int32(1), calls.Load())
+	for _, obj := range results {
+		require.Equal(t, "<html/>", string(obj.Body))
+	}
+}
+
+func TestThrough_CallerCancelDoesNotAbortFetch(t *testing.T) {
+	th, _ := newTestThrough()
+	release := make(chan struct{})
+	fetched := make(chan error, 1)
+	fetch := func(ctx context.Context) (Object, error) {
+		<-release
+		fetched <- ctx.Err()
+		return Object{Body: []byte("late")}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := th.Do(ctx, "overpass", "raw/json/edinburgh/overpass/slow.json", fetch)
+		done <- err
+	}()
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	close(release)
+	require.NoError(t, <-fetched, "fetch ran with a live context")
+	require.Eventually(t, func() bool {
+		obj, err := th.Cache.Get(context.Background(), "raw/json/edinburgh/overpass/slow.json")
+		return err == nil && string(obj.Body) == "late"
+	}, time.Second, 5*time.Millisecond)
+}
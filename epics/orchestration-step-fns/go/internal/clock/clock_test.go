@@ -0,0 +1,46 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeTimerFiresOnAdvance(t *testing.T) {
+	f := NewFake(time.Unix(0, 0))
+	tm := f.NewTimer(time.Minute)
+	f.Advance(59 * time.Second)
+	select {
+	case <-tm.C():
+		t.Fatal("fired early")
+	default:
+	}
+	f.Advance(time.Second)
+	select {
+	case at := <-tm.C():
+		if !at.Equal(time.Unix(60, 0)) {
+			t.Fatalf("fired at %v", at)
+		}
+	default:
+		t.Fatal("did not fire at its deadline")
+	}
+	if f.Since(time.Unix(0, 0)) != time.Minute {
+		t.Fatalf("since = %v", f.Since(time.Unix(0, 0)))
+	}
+}
+
+func TestFakeTimerStop(t *testing.T) {
+	f := NewFake(time.Unix(0, 0))
+	tm := f.NewTimer(time.Second)
+	if !tm.Stop() || f.Timers() != 0 {
+		t.Fatal("stop did not remove the timer")
+	}
+	f.Advance(time.Hour)
+	select {
+	case <-tm.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+	if tm.Stop() {
+		t.Fatal("second stop reported active")
+	}
+}
@@ -0,0 +1,238 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CityConfig is the per-city run config every state sees. It starts from
+// defaults.yaml city_defaults and is layered, in order, with
+// config/cities/<city>.yaml, env vars and the StartExecution input's
+// "config" object. Origins records which layer set each value.
+type CityConfig struct {
+	City             string      `yaml:"city" json:"city"`
+	H3Res            int         `yaml:"h3_res" json:"h3_res"`
+	SeedTopN         int         `yaml:"seed_top_n" json:"seed_top_n"`
+	RadiusMPrimary   float64     `yaml:"radius_m_primary" json:"radius_m_primary"`
+	RadiusMSecondary float64     `yaml:"radius_m_secondary" json:"radius_m_secondary"`
+	EarlyStop        EarlyStop   `yaml:"early_stop" json:"early_stop"`
+	Budgets          CityBudgets `yaml:"budgets" json:"budgets"`
+	Sources          Sources     `yaml:"sources" json:"sources"`
+	LLM              LLMConfig   `yaml:"llm" json:"llm"`
+	Refresh          Refresh     `yaml:"refresh" json:"refresh"`
+
+	// Origins maps a field path (e.g. "early_stop.window") to the layer
+	// that set it. Fields no layer set are absent.
+	Origins map[string]Origin `yaml:"-" json:"origins,omitempty"`
+}
+
+type EarlyStop struct {
+	MinNewUniqueRate float64 `yaml:"min_new_unique_rate" json:"min_new_unique_rate"`
+	Window           int     `yaml:"window" json:"window"`
+	MinSamples       int     `yaml:"min_samples" json:"min_samples"`
+}
+
+// CityBudgets are the run-level caps checked by the workflow gates.
+type CityBudgets struct {
+	MaxAPICalls       int     `yaml:"max_api_calls" json:"max_api_calls"`
+	MaxWallClockHours int     `yaml:"max_wall_clock_hours" json:"max_wall_clock_hours"`
+	MaxCostUSD        float64 `yaml:"max_cost_usd" json:"max_cost_usd"`
+}
+
+// Sources toggles the discovery sources used for a city.
+type Sources struct {
+	OSM          bool `yaml:"osm" json:"osm"`
+	OpenTripMap  bool `yaml:"opentripmap" json:"opentripmap"`
+	Wikidata     bool `yaml:"wikidata" json:"wikidata"`
+	Tavily       bool `yaml:"tavily" json:"tavily"`
+	CityOpenData bool `yaml:"city_open_data" json:"city_open_data"`
+}
+
+// LLMConfig controls where the LLM is used. UseTiebreak is one of
+// TiebreakAlways, TiebreakSelective or TiebreakNever.
+type LLMConfig struct {
+	UseExtractor bool   `yaml:"use_extractor" json:"use_extractor"`
+	UseTiebreak  string `yaml:"use_tiebreak" json:"use_tiebreak"`
+}
+
+const (
+	TiebreakAlways    = "always"
+	TiebreakSelective = "selective"
+	TiebreakNever     = "never"
+)
+
+// Refresh is how often primaries and secondaries are re-crawled; each is one
+// of "daily", "weekly" or "monthly".
+type Refresh struct {
+	Primaries   string `yaml:"primaries" json:"primaries"`
+	Secondaries string `yaml:"secondaries" json:"secondaries"`
+}
+
+var refreshCadences = map[string]bool{"daily": true, "weekly": true, "monthly": true}
+
+// Layer names a config source, lowest precedence first.
+type Layer string
+
+const (
+	LayerDefault Layer = "default"
+	LayerCity    Layer = "city"
+	LayerEnv     Layer = "env"
+	LayerInput   Layer = "input"
+)
+
+// Origin is where an effective value came from: a YAML file and line for
+// the default and city layers, a variable name for env.
+type Origin struct {
+	Layer Layer  `json:"layer"`
+	File  string `json:"file,omitempty"`
+	Line  int    `json:"line,omitempty"`
+	Env   string `json:"env,omitempty"`
+}
+
+func (o Origin) String() string {
+	switch {
+	case o.Env != "":
+		return fmt.Sprintf("%s %s", o.Layer, o.Env)
+	case o.File != "" && o.Line > 0:
+		return fmt.Sprintf("%s %s:%d", o.Layer, o.File, o.Line)
+	case o.File != "":
+		return fmt.Sprintf("%s %s", o.Layer, o.File)
+	}
+	return string(o.Layer)
+}
+
+// CityOverlayPath is where the overlay for city lives under dir.
+func CityOverlayPath(dir, city string) string {
+	return filepath.Join(dir, strings.ToLower(city)+".yaml")
+}
+
+// ApplyCityOverlay layers dir/<city>.yaml over rd.CityDefaults. A missing
+// file is not an error; it returns the path applied, or "" if none. Unknown
+// keys in the overlay are rejected with their line.
+func ApplyCityOverlay(rd *RawDefaults, dir, city string) (string, error) {
+	path := CityOverlayPath(dir, city)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if rd.origins == nil {
+		rd.origins = make(map[string]Origin)
+	}
+	if err := overlay(data, &rd.CityDefaults, rd.origins, "city_defaults", Origin{Layer: LayerCity, File: path}); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return path, nil
+}
+
+// ForCity returns the city_defaults, with any overlay and env overrides
+// already applied, as the CityConfig for city. An overlay's own "city" name
+// is kept.
+func (rd RawDefaults) ForCity(city string) CityConfig {
+	c := rd.CityDefaults
+	if c.City == "" {
+		c.City = city
+	}
+	c.Origins = make(map[string]Origin)
+	for field, o := range rd.origins {
+		if rest, ok := strings.CutPrefix(field, "city_defaults."); ok {
+			c.Origins[rest] = o
+		}
+	}
+	return c
+}
+
+// ApplyInput layers the StartExecution input over c: "city" names the city
+// and "config" may hold any CityConfig fields. Unknown fields are rejected.
+// c's Origins are copied first, so a CityConfig shared between runs is not
+// modified through its map.
+func (c *CityConfig) ApplyInput(input map[string]any) error {
+	origins := make(map[string]Origin, len(c.Origins))
+	for k, v := range c.Origins {
+		origins[k] = v
+	}
+	c.Origins = origins
+	if city, ok := input["city"].(string); ok && city != "" {
+		c.City = city
+		c.Origins["city"] = Origin{Layer: LayerInput}
+	}
+	over, ok := input["config"]
+	if !ok || over == nil {
+		return nil
+	}
+	if _, ok := over.(map[string]any); !ok {
+		return fmt.Errorf("input config: expected an object, got %T", over)
+	}
+	data, err := yaml.Marshal(over)
+	if err != nil {
+		return fmt.Errorf("input config: %w", err)
+	}
+	if err := overlay(data, c, c.Origins, "", Origin{Layer: LayerInput}); err != nil {
+		return fmt.Errorf("input config: %w", err)
+	}
+	return nil
+}
+
+// overlay strictly decodes data onto dst and records o as the origin of every
+// value it sets, keyed by its path under prefix. Lines are recorded only when
+// o names a file.
+func overlay(data []byte, dst any, origins map[string]Origin, prefix string, o Origin) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(dst); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	recordOrigins(origins, &root, prefix, o)
+	return nil
+}
+
+// recordOrigins records o against every leaf under n. Sequences count as
+// leaves.
+func recordOrigins(origins map[string]Origin, n *yaml.Node, prefix string, o Origin) {
+	if n.Kind == yaml.DocumentNode {
+		if len(n.Content) > 0 {
+			recordOrigins(origins, n.Content[0], prefix, o)
+		}
+		return
+	}
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i], n.Content[i+1]
+		path := key.Value
+		if prefix != "" {
+			path = prefix + "." + key.Value
+		}
+		if val.Kind == yaml.MappingNode {
+			recordOrigins(origins, val, path, o)
+			continue
+		}
+		at := o
+		if o.File != "" {
+			at.Line = key.Line
+		}
+		origins[path] = at
+	}
+}
+
+// Validate checks c's values, locating each issue by the layer that set it.
+func (c CityConfig) Validate() error {
+	v := validator{origins: c.Origins}
+	v.city(c)
+	return v.err()
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Entry is one effective config value and where it came from. A zero Origin
+// means no layer set the field and it holds its zero value.
+type Entry struct {
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+	Origin Origin `json:"origin"`
+}
+
+func (e Entry) String() string {
+	src := e.Origin.String()
+	if src == "" {
+		src = "unset"
+	}
+	return fmt.Sprintf("%s = %s  # %s", e.Field, e.Value, src)
+}
+
+// Explain flattens rd, with c in place of city_defaults, into one Entry per
+// leaf value sorted by field, each annotated with its origin.
+func Explain(rd RawDefaults, c CityConfig) ([]Entry, error) {
+	rd.CityDefaults = c
+	var root yaml.Node
+	if err := root.Encode(rd); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	flatten(values, &root, "")
+
+	entries := make([]Entry, 0, len(values))
+	for field, v := range values {
+		e := Entry{Field: field, Value: v}
+		if rest, ok := strings.CutPrefix(field, "city_defaults."); ok {
+			e.Origin = c.Origins[rest]
+		} else {
+			e.Origin = rd.origins[field]
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Field < entries[j].Field })
+	return entries, nil
+}
+
+// flatten records every leaf under n by dotted path. Sequences are leaves,
+// written in flow style.
+func flatten(out map[string]string, n *yaml.Node, prefix string) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			flatten(out, c, prefix)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			path := n.Content[i].Value
+			if prefix != "" {
+				path = prefix + "." + path
+			}
+			flatten(out, n.Content[i+1], path)
+		}
+	case yaml.SequenceNode:
+		items := make([]string, len(n.Content))
+		for i, c := range n.Content {
+			items[i] = c.Value
+		}
+		out[prefix] = "[" + strings.Join(items, ", ") + "]"
+	default:
+		out[prefix] = n.Value
+	}
+}
+
+// Change is a field whose effective value differs between two configs; From
+// or To is nil when the field exists on one side only.
+type Change struct {
+	Field string `json:"field"`
+	From  *Entry `json:"from,omitempty"`
+	To    *Entry `json:"to,omitempty"`
+}
+
+func (c Change) String() string {
+	side := func(e *Entry) string {
+		if e == nil {
+			return "(absent)"
+		}
+		if src := e.Origin.String(); src != "" {
+			return fmt.Sprintf("%s (%s)", e.Value, src)
+		}
+		return e.Value
+	}
+	return fmt.Sprintf("%s: %s -> %s", c.Field, side(c.From), side(c.To))
+}
+
+// Diff lists the fields whose values differ between from and to, sorted by
+// field. Origins alone differing is not a change.
+func Diff(from, to []Entry) []Change {
+	index := func(es []Entry) map[string]*Entry {
+		m := make(map[string]*Entry, len(es))
+		for i := range es {
+			m[es[i].Field] = &es[i]
+		}
+		return m
+	}
+	a, b := index(from), index(to)
+	var changes []Change
+	for field, ea := range a {
+		if eb, ok := b[field]; !ok || eb.Value != ea.Value {
+			changes = append(changes, Change{Field: field, From: ea, To: b[field]})
+		}
+	}
+	for field, eb := range b {
+		if _, ok := a[field]; !ok {
+			changes = append(changes, Change{Field: field, To: eb})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+const explainYAML = `version: 1
+city_defaults:
+  seed_top_n: 200
+  early_stop:
+    window: 100
+budgets:
+  google.text:
+    capacity: 10
+    refill: 1
+    period: 1m
+split_ratio: 0.7
+feature_flags:
+  mock_states: [ExtractWithLLM]
+`
+
+func explainIndex(t *testing.T, rd RawDefaults, c CityConfig) map[string]Entry {
+	t.Helper()
+	entries, err := Explain(rd, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := make(map[string]Entry, len(entries))
+	for i, e := range entries {
+		if i > 0 && entries[i-1].Field >= e.Field {
+			t.Fatalf("entries not sorted: %q before %q", entries[i-1].Field, e.Field)
+		}
+		m[e.Field] = e
+	}
+	return m
+}
+
+func TestExplainAnnotatesEachLayer(t *testing.T) {
+	rd, err := ParseDefaults("defaults.yaml", []byte(explainYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BUDGET_SPLIT_RATIO", "0.6")
+	ApplyEnvOverrides(&rd)
+	c := rd.ForCity("Edinburgh")
+	if err := c.ApplyInput(map[string]any{"config": map[string]any{"seed_top_n": 50}}); err != nil {
+		t.Fatal(err)
+	}
+	got := explainIndex(t, rd, c)
+
+	for field, want := range map[string]struct {
+		value, origin string
+	}{
+		"budgets.google.text.capacity":    {"10", "default defaults.yaml:8"},
+		"budgets.google.text.period":      {"1m0s", "default defaults.yaml:10"},
+		"city_defaults.early_stop.window": {"100", "default defaults.yaml:5"},
+		"city_defaults.seed_top_n":        {"50", "input"},
+		"split_ratio":                     {"0.6", "env BUDGET_SPLIT_RATIO"},
+		"feature_flags.mock_states":       {"[ExtractWithLLM]", "default defaults.yaml:13"},
+		"city_defaults.h3_res":            {"0", ""},
+	} {
+		e, ok := got[field]
+		if !ok {
+			t.Fatalf("missing %s", field)
+		}
+		if e.Value != want.value || e.Origin.String() != want.origin {
+			t.Fatalf("%s = %q (%q), want %q (%q)", field, e.Value, e.Origin, want.value, want.origin)
+		}
+	}
+	if s := got["city_defaults.h3_res"].String(); s != "city_defaults.h3_res = 0  # unset" {
+		t.Fatalf("unset entry = %q", s)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	explain := func(name, doc string) []Entry {
+		rd, err := ParseDefaults(name, []byte(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries, err := Explain(rd, rd.ForCity("x"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entries
+	}
+	dev := explain("dev.yaml", explainYAML)
+	prod := explain("prod.yaml", strings.Replace(explainYAML, "capacity: 10", "capacity: 20", 1)+"split_ratios:\n  llm.tokens: 0.5\n")
+
+	changes := Diff(dev, prod)
+	if len(changes) != 2 {
+		t.Fatalf("changes = %v, want capacity and split_ratios", changes)
+	}
+	if c := changes[0]; c.Field != "budgets.google.text.capacity" || c.From.Value != "10" || c.To.Value != "20" {
+		t.Fatalf("changes[0] = %v", c)
+	}
+	if c := changes[1]; c.Field != "split_ratios.llm.tokens" || c.From != nil || c.To.Value != "0.5" {
+		t.Fatalf("changes[1] = %v", c)
+	}
+	if s := changes[1].String(); s != "split_ratios.llm.tokens: (absent) -> 0.5 (default prod.yaml:15)" {
+		t.Fatalf("String() = %q", s)
+	}
+	if changes := Diff(dev, explain("other.yaml", explainYAML)); len(changes) != 0 {
+		t.Fatalf("identical configs differ: %v", changes)
+	}
+}
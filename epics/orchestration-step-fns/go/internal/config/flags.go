@@ -0,0 +1,246 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	b "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/budget"
+)
+
+// FeatureFlags mirrors defaults.yaml feature_flags.
+type FeatureFlags struct {
+	// MockStates run with mock handlers instead of real ones.
+	MockStates []string `yaml:"mock_states" json:"mock_states,omitempty"`
+	// KillSwitches turns a connector off when true. Keys are connector names
+	// or groups (see ConnectorGroups); a connector's own key beats its group's.
+	KillSwitches map[string]bool `yaml:"kill_switches" json:"kill_switches,omitempty"`
+}
+
+// ConnectorGroups are the kill switch groups the execution input uses.
+var ConnectorGroups = map[string][]b.Connector{
+	"google":    {b.GoogleText, b.GoogleNearby, b.GoogleDetails},
+	"web":       {b.WebFetch, b.TavilyAPI},
+	"llm":       {b.LLMTokens},
+	"open_data": {b.Overpass, b.OTM, b.Wiki, b.Nominatim},
+}
+
+// killSwitchNames lists every valid kill switch key: connectors, then groups.
+func killSwitchNames() []string {
+	names := make([]string, 0, len(b.Connectors)+len(ConnectorGroups))
+	for _, c := range b.Connectors {
+		names = append(names, string(c))
+	}
+	return append(names, sortedKeys(ConnectorGroups)...)
+}
+
+func groupOf(c b.Connector) string {
+	for g, cs := range ConnectorGroups {
+		for _, gc := range cs {
+			if gc == c {
+				return g
+			}
+		}
+	}
+	return ""
+}
+
+// Flag names one switchable behaviour; build them with MockStateFlag,
+// ConnectorFlag and SourceFlag.
+type Flag string
+
+const (
+	mockStatePrefix = "mock_state:"
+	connectorPrefix = "connector:"
+	sourcePrefix    = "source:"
+)
+
+// MockStateFlag is enabled when state should run its mock handler.
+func MockStateFlag(state string) Flag { return Flag(mockStatePrefix + state) }
+
+// ConnectorFlag is enabled unless c is killed.
+func ConnectorFlag(c b.Connector) Flag { return Flag(connectorPrefix + string(c)) }
+
+// SourceFlag is enabled when the named source (a Sources yaml key, e.g.
+// "osm") is on.
+func SourceFlag(name string) Flag { return Flag(sourcePrefix + name) }
+
+// Overrides are per-execution flag values, typically read from the execution
+// input with OverridesFromInput. They beat the configured flags.
+type Overrides struct {
+	MockStates   map[string]bool // state -> mocked
+	KillSwitches map[string]bool // connector or group -> killed
+	Sources      map[string]bool // source -> enabled
+}
+
+// OverridesFromInput reads flag overrides from an execution document:
+// top-level "kill_switches", and "feature_flags" with "mock_states" (a list
+// of mocked states, or a state -> bool map), "kill_switches" and "sources".
+// Values of the wrong type are ignored.
+func OverridesFromInput(doc map[string]any) Overrides {
+	var o Overrides
+	ff, _ := doc["feature_flags"].(map[string]any)
+	o.KillSwitches = boolMap(o.KillSwitches, doc["kill_switches"])
+	o.KillSwitches = boolMap(o.KillSwitches, ff["kill_switches"])
+	o.Sources = boolMap(o.Sources, ff["sources"])
+	switch ms := ff["mock_states"].(type) {
+	case []any:
+		o.MockStates = make(map[string]bool, len(ms))
+		for _, s := range ms {
+			if name, ok := s.(string); ok {
+				o.MockStates[name] = true
+			}
+		}
+	case []string:
+		o.MockStates = make(map[string]bool, len(ms))
+		for _, name := range ms {
+			o.MockStates[name] = true
+		}
+	default:
+		o.MockStates = boolMap(nil, ms)
+	}
+	return o
+}
+
+func boolMap(dst map[string]bool, v any) map[string]bool {
+	var src map[string]bool
+	switch m := v.(type) {
+	case map[string]bool:
+		src = m
+	case map[string]any:
+		src = make(map[string]bool, len(m))
+		for k, x := range m {
+			if on, ok := x.(bool); ok {
+				src[k] = on
+			}
+		}
+	}
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]bool, len(src))
+	}
+	for k, on := range src {
+		dst[k] = on
+	}
+	return dst
+}
+
+type overridesKey struct{}
+type cityConfigKey struct{}
+
+// WithOverrides attaches execution overrides to ctx for IsEnabled.
+func WithOverrides(ctx context.Context, o Overrides) context.Context {
+	return context.WithValue(ctx, overridesKey{}, o)
+}
+
+// WithCityConfig attaches the run's CityConfig to ctx; IsEnabled reads
+// source toggles from it.
+func WithCityConfig(ctx context.Context, c *CityConfig) context.Context {
+	return context.WithValue(ctx, cityConfigKey{}, c)
+}
+
+// Flags answers IsEnabled from configured FeatureFlags, overridden by any
+// Overrides on the context. A nil *Flags has no configured flags.
+type Flags struct {
+	mu   sync.RWMutex
+	mock map[string]bool
+	kill map[string]bool
+}
+
+func NewFlags(ff FeatureFlags) *Flags {
+	f := &Flags{}
+	f.Update(ff)
+	return f
+}
+
+// Update swaps in newly loaded flags, e.g. from a Reloader.
+func (f *Flags) Update(ff FeatureFlags) {
+	mock := make(map[string]bool, len(ff.MockStates))
+	for _, s := range ff.MockStates {
+		mock[s] = true
+	}
+	kill := make(map[string]bool, len(ff.KillSwitches))
+	for k, v := range ff.KillSwitches {
+		kill[k] = v
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mock, f.kill = mock, kill
+}
+
+// IsEnabled reports whether flag is on for ctx. Overrides on ctx win over
+// the configured flags; source flags fall back to the CityConfig on ctx.
+// Connectors and sources are enabled, and states real, unless set otherwise.
+func (f *Flags) IsEnabled(ctx context.Context, flag Flag) bool {
+	o, _ := ctx.Value(overridesKey{}).(Overrides)
+	name := string(flag)
+	switch {
+	case strings.HasPrefix(name, mockStatePrefix):
+		state := strings.TrimPrefix(name, mockStatePrefix)
+		if on, ok := o.MockStates[state]; ok {
+			return on
+		}
+		if f == nil {
+			return false
+		}
+		f.mu.RLock()
+		defer f.mu.RUnlock()
+		return f.mock[state]
+	case strings.HasPrefix(name, connectorPrefix):
+		c := b.Connector(strings.TrimPrefix(name, connectorPrefix))
+		if killed, ok := killSwitch(o.KillSwitches, c); ok {
+			return !killed
+		}
+		if f == nil {
+			return true
+		}
+		f.mu.RLock()
+		defer f.mu.RUnlock()
+		if killed, ok := killSwitch(f.kill, c); ok {
+			return !killed
+		}
+		return true
+	case strings.HasPrefix(name, sourcePrefix):
+		src := strings.TrimPrefix(name, sourcePrefix)
+		if on, ok := o.Sources[src]; ok {
+			return on
+		}
+		if c, _ := ctx.Value(cityConfigKey{}).(*CityConfig); c != nil {
+			if on, ok := c.Sources.enabled(src); ok {
+				return on
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// killSwitch looks c up in m by name, then by group.
+func killSwitch(m map[string]bool, c b.Connector) (killed, ok bool) {
+	if killed, ok := m[string(c)]; ok {
+		return killed, true
+	}
+	if g := groupOf(c); g != "" {
+		killed, ok := m[g]
+		return killed, ok
+	}
+	return false, false
+}
+
+func (s Sources) enabled(name string) (on, ok bool) {
+	switch name {
+	case "osm":
+		return s.OSM, true
+	case "opentripmap":
+		return s.OpenTripMap, true
+	case "wikidata":
+		return s.Wikidata, true
+	case "tavily":
+		return s.Tavily, true
+	case "city_open_data":
+		return s.CityOpenData, true
+	}
+	return false, false
+}
@@ -8,101 +8,239 @@ import (
 	"time"
 
 	b "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/budget"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/queue"
 	"gopkg.in/yaml.v3"
 )
 
 // RawDefaults mirrors config/defaults.yaml
 type RawDefaults struct {
-	Version      int `yaml:"version"`
-	CityDefaults struct {
-		EarlyStop struct {
-			MinNewUniqueRate float64 `yaml:"min_new_unique_rate"`
-			Window           int     `yaml:"window"`
-		} `yaml:"early_stop"`
-		Budgets struct {
-			MaxAPICalls       int `yaml:"max_api_calls"`
-			MaxWallClockHours int `yaml:"max_wall_clock_hours"`
-		} `yaml:"budgets"`
-	} `yaml:"city_defaults"`
-	Budgets map[string]struct {
+	Version      int        `yaml:"version"`
+	CityDefaults CityConfig `yaml:"city_defaults"`
+	Budgets      map[string]struct {
 		Capacity int64         `yaml:"capacity"`
 		Refill   int64         `yaml:"refill"`
 		Period   time.Duration `yaml:"period"`
 	} `yaml:"budgets"`
-	SplitRatio  float64        `yaml:"split_ratio"`
-	Concurrency map[string]int `yaml:"concurrency"`
+	Pricing        map[string]b.Price `yaml:"pricing"`
+	SplitRatio     float64            `yaml:"split_ratio"`
+	SplitRatios    map[string]float64 `yaml:"split_ratios"`
+	SplitRebalance b.RebalanceConfig  `yaml:"split_rebalance"`
+	FrontierLanes  queue.LaneConfig   `yaml:"frontier_lanes"`
+	Concurrency    map[string]int     `yaml:"concurrency"`
+	FeatureFlags   FeatureFlags       `yaml:"feature_flags"`
+
+	src       *source           // where the YAML came from, for Validate's line numbers
+	envIssues []Issue           // env overrides that failed to parse
+	origins   map[string]Origin // field path -> layer that set it
 }
 
 func LoadDefaults(path string) (RawDefaults, error) {
-	var rd RawDefaults
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return rd, err
+		return RawDefaults{}, err
+	}
+	return ParseDefaults(path, data)
+}
+
+// ParseDefaults parses a defaults.yaml document; name (a path, or e.g. an
+// SSM parameter) locates its values in origins and validation issues.
+func ParseDefaults(name string, data []byte) (RawDefaults, error) {
+	var rd RawDefaults
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return rd, fmt.Errorf("%s: %w", name, err)
 	}
-	if err := yaml.Unmarshal(data, &rd); err != nil {
-		return rd, err
+	if err := root.Decode(&rd); err != nil {
+		return rd, fmt.Errorf("%s: %w", name, err)
 	}
+	rd.src = &source{file: name, root: &root}
+	rd.origins = make(map[string]Origin)
+	recordOrigins(rd.origins, &root, "", Origin{Layer: LayerDefault, File: name})
 	return rd, nil
 }
 
+// ApplyEnvOverrides overlays environment variables on rd. Values that fail to
+// parse leave the field unchanged and are reported by Validate.
 func ApplyEnvOverrides(rd *RawDefaults) {
+	e := envOverrides{rd: rd}
+
 	// Global ratios and caps
-	if v := os.Getenv("BUDGET_SPLIT_RATIO"); v != "" {
-		if f, err := strconv.ParseFloat(v, 64); err == nil {
-			rd.SplitRatio = f
-		}
-	}
-	if v := os.Getenv("EARLY_STOP_MIN_NEW_UNIQUE_RATE"); v != "" {
-		if f, err := strconv.ParseFloat(v, 64); err == nil {
-			rd.CityDefaults.EarlyStop.MinNewUniqueRate = f
-		}
-	}
-	if v := os.Getenv("EARLY_STOP_WINDOW"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			rd.CityDefaults.EarlyStop.Window = n
-		}
-	}
-	if v := os.Getenv("BUDGET_MAX_API_CALLS"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			rd.CityDefaults.Budgets.MaxAPICalls = n
-		}
-	}
-	if v := os.Getenv("BUDGET_MAX_WALL_CLOCK_HOURS"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			rd.CityDefaults.Budgets.MaxWallClockHours = n
+	e.float("BUDGET_SPLIT_RATIO", "split_ratio", &rd.SplitRatio)
+	e.duration("SPLIT_REBALANCE_INTERVAL", "split_rebalance.interval", &rd.SplitRebalance.Interval)
+	e.int64("SPLIT_REBALANCE_PRIMARIES_TARGET_MIN", "split_rebalance.primaries_target_min", &rd.SplitRebalance.PrimariesTargetMin)
+	e.int64("SPLIT_REBALANCE_PRIMARIES_TARGET_MAX", "split_rebalance.primaries_target_max", &rd.SplitRebalance.PrimariesTargetMax)
+	e.float("FRONTIER_LANES_HIGH_TRUST", "frontier_lanes.high_trust", &rd.FrontierLanes.HighTrust)
+	e.float("FRONTIER_LANES_LOW_TRUST", "frontier_lanes.low_trust", &rd.FrontierLanes.LowTrust)
+	e.float("EARLY_STOP_MIN_NEW_UNIQUE_RATE", "city_defaults.early_stop.min_new_unique_rate", &rd.CityDefaults.EarlyStop.MinNewUniqueRate)
+	e.int("EARLY_STOP_WINDOW", "city_defaults.early_stop.window", &rd.CityDefaults.EarlyStop.Window)
+	e.int("EARLY_STOP_MIN_SAMPLES", "city_defaults.early_stop.min_samples", &rd.CityDefaults.EarlyStop.MinSamples)
+	e.int("BUDGET_MAX_API_CALLS", "city_defaults.budgets.max_api_calls", &rd.CityDefaults.Budgets.MaxAPICalls)
+	e.int("BUDGET_MAX_WALL_CLOCK_HOURS", "city_defaults.budgets.max_wall_clock_hours", &rd.CityDefaults.Budgets.MaxWallClockHours)
+	e.float("BUDGET_MAX_COST_USD", "city_defaults.budgets.max_cost_usd", &rd.CityDefaults.Budgets.MaxCostUSD)
+
+	// Per-city knobs
+	cd := &rd.CityDefaults
+	e.int("CITY_H3_RES", "city_defaults.h3_res", &cd.H3Res)
+	e.int("CITY_SEED_TOP_N", "city_defaults.seed_top_n", &cd.SeedTopN)
+	e.float("CITY_RADIUS_M_PRIMARY", "city_defaults.radius_m_primary", &cd.RadiusMPrimary)
+	e.float("CITY_RADIUS_M_SECONDARY", "city_defaults.radius_m_secondary", &cd.RadiusMSecondary)
+	e.bool("SOURCE_OSM", "city_defaults.sources.osm", &cd.Sources.OSM)
+	e.bool("SOURCE_OPENTRIPMAP", "city_defaults.sources.opentripmap", &cd.Sources.OpenTripMap)
+	e.bool("SOURCE_WIKIDATA", "city_defaults.sources.wikidata", &cd.Sources.Wikidata)
+	e.bool("SOURCE_TAVILY", "city_defaults.sources.tavily", &cd.Sources.Tavily)
+	e.bool("SOURCE_CITY_OPEN_DATA", "city_defaults.sources.city_open_data", &cd.Sources.CityOpenData)
+	e.bool("LLM_USE_EXTRACTOR", "city_defaults.llm.use_extractor", &cd.LLM.UseExtractor)
+	e.string("LLM_USE_TIEBREAK", "city_defaults.llm.use_tiebreak", &cd.LLM.UseTiebreak)
+	e.string("REFRESH_PRIMARIES", "city_defaults.refresh.primaries", &cd.Refresh.Primaries)
+	e.string("REFRESH_SECONDARIES", "city_defaults.refresh.secondaries", &cd.Refresh.Secondaries)
+
+	// Lane weights: FRONTIER_LANE_WEIGHT_<LANE>
+	for _, p := range queue.Priorities {
+		n := rd.FrontierLanes.Weights[p]
+		if e.int("FRONTIER_LANE_WEIGHT_"+normalizeKey(string(p)), "frontier_lanes.weights."+string(p), &n) {
+			if rd.FrontierLanes.Weights == nil {
+				rd.FrontierLanes.Weights = make(map[queue.Priority]int)
+			}
+			rd.FrontierLanes.Weights[p] = n
 		}
 	}
 
 	// Concurrency overrides: CONCURRENCY_<KEY>
 	for k := range rd.Concurrency {
-		envKey := "CONCURRENCY_" + normalizeKey(k)
-		if v := os.Getenv(envKey); v != "" {
-			if n, err := strconv.Atoi(v); err == nil {
-				rd.Concurrency[k] = n
+		n := rd.Concurrency[k]
+		e.int("CONCURRENCY_"+normalizeKey(k), "concurrency."+k, &n)
+		rd.Concurrency[k] = n
+	}
+
+	// Feature flags: MOCK_STATES=<State>,<State> and KILL_SWITCH_<TOKEN|GROUP>
+	var mock string
+	if e.string("MOCK_STATES", "feature_flags.mock_states", &mock) {
+		rd.FeatureFlags.MockStates = nil
+		for _, s := range strings.Split(mock, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				rd.FeatureFlags.MockStates = append(rd.FeatureFlags.MockStates, s)
+			}
+		}
+	}
+	for _, name := range killSwitchNames() {
+		var killed bool
+		if e.bool("KILL_SWITCH_"+normalizeKey(name), "feature_flags.kill_switches."+name, &killed) {
+			if rd.FeatureFlags.KillSwitches == nil {
+				rd.FeatureFlags.KillSwitches = make(map[string]bool)
 			}
+			rd.FeatureFlags.KillSwitches[name] = killed
 		}
 	}
 
 	// Budget token buckets: BUDGET_<TOKEN>_<FIELD>
 	for token, cfg := range rd.Budgets {
 		base := "BUDGET_" + normalizeKey(token)
-		if v := os.Getenv(base + "_CAPACITY"); v != "" {
-			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
-				cfg.Capacity = n
-			}
+		e.int64(base+"_CAPACITY", "budgets."+token+".capacity", &cfg.Capacity)
+		e.int64(base+"_REFILL", "budgets."+token+".refill", &cfg.Refill)
+		e.duration(base+"_PERIOD", "budgets."+token+".period", &cfg.Period)
+		rd.Budgets[token] = cfg
+	}
+
+	// Per-connector split ratios: SPLIT_RATIO_<TOKEN>
+	for token := range rd.SplitRatios {
+		r := rd.SplitRatios[token]
+		e.float("SPLIT_RATIO_"+normalizeKey(token), "split_ratios."+token, &r)
+		rd.SplitRatios[token] = r
+	}
+
+	// Connector prices: PRICE_<TOKEN>_PER_CALL_USD / PRICE_<TOKEN>_PER_TOKEN_USD
+	for token, p := range rd.Pricing {
+		base := "PRICE_" + normalizeKey(token)
+		e.float(base+"_PER_CALL_USD", "pricing."+token+".per_call_usd", &p.PerCallUSD)
+		e.float(base+"_PER_TOKEN_USD", "pricing."+token+".per_token_usd", &p.PerTokenUSD)
+		rd.Pricing[token] = p
+	}
+}
+
+// envOverrides parses environment overrides onto a RawDefaults, recording the
+// variable as the origin of each field it sets and any value that does not
+// parse.
+type envOverrides struct {
+	rd *RawDefaults
+}
+
+// lookup parses key, if set, as the override for field and reports whether
+// it did.
+func (e envOverrides) lookup(key, field, kind string, parse func(string) error) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return false
+	}
+	if err := parse(v); err != nil {
+		e.rd.envIssues = append(e.rd.envIssues, Issue{
+			File:  "env",
+			Field: key,
+			Msg:   fmt.Sprintf("%q is not a valid %s", v, kind),
+		})
+		return false
+	}
+	if e.rd.origins == nil {
+		e.rd.origins = make(map[string]Origin)
+	}
+	e.rd.origins[field] = Origin{Layer: LayerEnv, Env: key}
+	return true
+}
+
+func (e envOverrides) float(key, field string, dst *float64) bool {
+	return e.lookup(key, field, "number", func(v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err == nil {
+			*dst = f
 		}
-		if v := os.Getenv(base + "_REFILL"); v != "" {
-			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
-				cfg.Refill = n
-			}
+		return err
+	})
+}
+
+func (e envOverrides) int(key, field string, dst *int) bool {
+	return e.lookup(key, field, "integer", func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err == nil {
+			*dst = n
 		}
-		if v := os.Getenv(base + "_PERIOD"); v != "" {
-			if d, err := time.ParseDuration(v); err == nil {
-				cfg.Period = d
-			}
+		return err
+	})
+}
+
+func (e envOverrides) int64(key, field string, dst *int64) bool {
+	return e.lookup(key, field, "integer", func(v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err == nil {
+			*dst = n
 		}
-		rd.Budgets[token] = cfg
-	}
+		return err
+	})
+}
+
+func (e envOverrides) duration(key, field string, dst *time.Duration) bool {
+	return e.lookup(key, field, "duration", func(v string) error {
+		d, err := time.ParseDuration(v)
+		if err == nil {
+			*dst = d
+		}
+		return err
+	})
+}
+
+func (e envOverrides) bool(key, field string, dst *bool) bool {
+	return e.lookup(key, field, "boolean", func(v string) error {
+		t, err := strconv.ParseBool(v)
+		if err == nil {
+			*dst = t
+		}
+		return err
+	})
+}
+
+func (e envOverrides) string(key, field string, dst *string) bool {
+	return e.lookup(key, field, "string", func(v string) error {
+		*dst = v
+		return nil
+	})
 }
 
 func normalizeKey(s string) string {
@@ -118,6 +256,7 @@ func BuildBudgetConfig(rd RawDefaults) b.Config {
 			Period   time.Duration `yaml:"period"`
 		}),
 		SplitRatio: rd.SplitRatio,
+		Rebalance:  rd.SplitRebalance,
 	}
 	for token, cfg := range rd.Budgets {
 		out.Budgets[b.Connector(token)] = struct {
@@ -126,17 +265,32 @@ func BuildBudgetConfig(rd RawDefaults) b.Config {
 			Period   time.Duration `yaml:"period"`
 		}{Capacity: cfg.Capacity, Refill: cfg.Refill, Period: cfg.Period}
 	}
+	if len(rd.SplitRatios) > 0 {
+		out.SplitRatios = make(map[b.Connector]float64, len(rd.SplitRatios))
+		for token, r := range rd.SplitRatios {
+			out.SplitRatios[b.Connector(token)] = r
+		}
+	}
+	if len(rd.Pricing) > 0 {
+		out.Prices = make(map[b.Connector]b.Price, len(rd.Pricing))
+		for token, p := range rd.Pricing {
+			out.Prices[b.Connector(token)] = p
+		}
+	}
 	return out
 }
 
 // Human summary for logs/debugging
 func (rd RawDefaults) String() string {
-	return fmt.Sprintf("split_ratio=%.2f, early_stop{rate=%.3f, window=%d}, caps{api_calls=%d, wall_clock_h=%d}, budgets=%d tokens",
+	return fmt.Sprintf("split_ratio=%.2f, early_stop{rate=%.3f, window=%d, min_samples=%d}, caps{api_calls=%d, wall_clock_h=%d, cost_usd=%.2f}, budgets=%d tokens, pricing=%d",
 		rd.SplitRatio,
 		rd.CityDefaults.EarlyStop.MinNewUniqueRate,
 		rd.CityDefaults.EarlyStop.Window,
+		rd.CityDefaults.EarlyStop.MinSamples,
 		rd.CityDefaults.Budgets.MaxAPICalls,
 		rd.CityDefaults.Budgets.MaxWallClockHours,
+		rd.CityDefaults.Budgets.MaxCostUSD,
 		len(rd.Budgets),
+		len(rd.Pricing),
 	)
 }
@@ -1,10 +1,17 @@
 package config
 
 import (
+    "context"
+    "errors"
     "os"
     "path/filepath"
+    "reflect"
+    "strings"
     "testing"
     "time"
+
+    b "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/budget"
+    "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/queue"
 )
 
 func TestLoadAndEnvOverride(t *testing.T) {
@@ -62,3 +69,414 @@ func TestBuildBudgetConfig(t *testing.T) {
         t.Fatalf("expected 1 budget, got %d", len(cfg.Budgets))
     }
 }
+
+func TestPricingAndCostCap(t *testing.T) {
+    path := filepath.Join("..", "..", "..", "..", "..", "config", "defaults.yaml")
+    rd, err := LoadDefaults(path)
+    if err != nil {
+        t.Fatalf("load defaults: %v", err)
+    }
+    if rd.CityDefaults.Budgets.MaxCostUSD != 30 {
+        t.Fatalf("expected max_cost_usd 30, got %f", rd.CityDefaults.Budgets.MaxCostUSD)
+    }
+
+    os.Setenv("BUDGET_MAX_COST_USD", "12.5")
+    defer os.Unsetenv("BUDGET_MAX_COST_USD")
+    os.Setenv("PRICE_LLM_TOKENS_PER_TOKEN_USD", "0.00001")
+    defer os.Unsetenv("PRICE_LLM_TOKENS_PER_TOKEN_USD")
+
+    ApplyEnvOverrides(&rd)
+
+    if rd.CityDefaults.Budgets.MaxCostUSD != 12.5 {
+        t.Fatalf("expected override max_cost_usd 12.5, got %f", rd.CityDefaults.Budgets.MaxCostUSD)
+    }
+    cfg := BuildBudgetConfig(rd)
+    if p := cfg.Prices["llm.tokens"]; p.PerTokenUSD != 0.00001 {
+        t.Fatalf("expected llm.tokens per_token_usd 0.00001, got %+v", p)
+    }
+    if p := cfg.Prices["google.text"]; p.PerCallUSD != 0.032 {
+        t.Fatalf("expected google.text per_call_usd 0.032, got %+v", p)
+    }
+}
+
+func TestSplitRebalanceConfig(t *testing.T) {
+    path := filepath.Join("..", "..", "..", "..", "..", "config", "defaults.yaml")
+    rd, err := LoadDefaults(path)
+    if err != nil {
+        t.Fatalf("load defaults: %v", err)
+    }
+    os.Setenv("SPLIT_REBALANCE_INTERVAL", "90s")
+    defer os.Unsetenv("SPLIT_REBALANCE_INTERVAL")
+    os.Setenv("SPLIT_RATIO_LLM_TOKENS", "0.6")
+    defer os.Unsetenv("SPLIT_RATIO_LLM_TOKENS")
+
+    ApplyEnvOverrides(&rd)
+    cfg := BuildBudgetConfig(rd)
+
+    if cfg.Rebalance.Interval != 90*time.Second {
+        t.Fatalf("expected rebalance interval 90s, got %s", cfg.Rebalance.Interval)
+    }
+    if cfg.Rebalance.PrimariesTargetMin != 150 || cfg.Rebalance.PrimariesTargetMax != 200 {
+        t.Fatalf("expected primaries target 150-200, got %+v", cfg.Rebalance)
+    }
+    if cfg.SplitRatios["llm.tokens"] != 0.6 {
+        t.Fatalf("expected llm.tokens split ratio 0.6, got %f", cfg.SplitRatios["llm.tokens"])
+    }
+}
+
+func TestFrontierLanesConfig(t *testing.T) {
+    path := filepath.Join("..", "..", "..", "..", "..", "config", "defaults.yaml")
+    rd, err := LoadDefaults(path)
+    if err != nil {
+        t.Fatalf("load defaults: %v", err)
+    }
+    if !reflect.DeepEqual(rd.FrontierLanes, queue.DefaultLaneConfig()) {
+        t.Fatalf("expected shipped frontier_lanes to match queue.DefaultLaneConfig, got %+v", rd.FrontierLanes)
+    }
+    os.Setenv("FRONTIER_LANE_WEIGHT_LOW", "2")
+    defer os.Unsetenv("FRONTIER_LANE_WEIGHT_LOW")
+    os.Setenv("FRONTIER_LANES_LOW_TRUST", "0.8")
+    defer os.Unsetenv("FRONTIER_LANES_LOW_TRUST")
+
+    ApplyEnvOverrides(&rd)
+
+    if rd.FrontierLanes.Weights[queue.Low] != 2 || rd.FrontierLanes.Weights[queue.High] != 6 {
+        t.Fatalf("expected low lane weight 2 next to high 6, got %v", rd.FrontierLanes.Weights)
+    }
+    err = rd.Validate()
+    if err == nil || !strings.Contains(err.Error(), "env: FRONTIER_LANES_LOW_TRUST: must not exceed high_trust (overrides frontier_lanes.low_trust)") {
+        t.Fatalf("expected low_trust above high_trust to be reported, got %v", err)
+    }
+}
+
+func TestValidateDefaults(t *testing.T) {
+    path := filepath.Join("..", "..", "..", "..", "..", "config", "defaults.yaml")
+    rd, err := LoadDefaults(path)
+    if err != nil {
+        t.Fatalf("load defaults: %v", err)
+    }
+    ApplyEnvOverrides(&rd)
+    if err := rd.Validate(); err != nil {
+        t.Fatalf("expected shipped defaults to validate, got %v", err)
+    }
+}
+
+func TestValidateReportsFileAndLine(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "bad.yaml")
+    yml := `version: 1
+budgets:
+  google.text:
+    capacity: -5
+    refill: 10
+    period: 0s
+  google.txt:
+    capacity: 10
+split_ratio: 1.5
+split_ratios:
+  llm.tokens: -0.1
+pricing:
+  made.up:
+    per_call_usd: 0.01
+feature_flag: {}
+`
+    if err := os.WriteFile(path, []byte(yml), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    rd, err := LoadDefaults(path)
+    if err != nil {
+        t.Fatalf("load: %v", err)
+    }
+
+    err = rd.Validate()
+    var verr *ValidationError
+    if !errors.As(err, &verr) {
+        t.Fatalf("expected *ValidationError, got %v", err)
+    }
+    want := []string{
+        path + ":15: feature_flag: unknown key",
+        path + ":4: budgets.google.text.capacity: must not be negative",
+        path + ":6: budgets.google.text.period: refill 10 needs a period > 0s",
+        path + ":7: budgets.google.txt: unknown connector",
+        path + ":9: split_ratio: must be between 0 and 1",
+        path + ":11: split_ratios.llm.tokens: must be between 0 and 1",
+        path + ":13: pricing.made.up: unknown connector",
+    }
+    if len(verr.Issues) != len(want) {
+        t.Fatalf("expected %d issues, got %d:\n%v", len(want), len(verr.Issues), err)
+    }
+    for i, w := range want {
+        if got := verr.Issues[i].String(); got != w {
+            t.Fatalf("issue %d: expected %q, got %q", i, w, got)
+        }
+    }
+}
+
+func TestValidateReportsNestedUnknownKeys(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "typos.yaml")
+    yml := `version: 1
+city_defaults:
+  budgets:
+    max_api_call: 5
+  early_stop:
+    window: 10
+budgets:
+  google.text:
+    capacty: 10
+split_rebalance:
+  primaries_target: 100
+feature_flags:
+  kill_switch:
+    tavily: true
+`
+    if err := os.WriteFile(path, []byte(yml), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    rd, err := LoadDefaults(path)
+    if err != nil {
+        t.Fatalf("load: %v", err)
+    }
+
+    err = rd.Validate()
+    var verr *ValidationError
+    if !errors.As(err, &verr) {
+        t.Fatalf("expected *ValidationError, got %v", err)
+    }
+    want := []string{
+        path + ":4: city_defaults.budgets.max_api_call: unknown key",
+        path + ":9: budgets.google.text.capacty: unknown key",
+        path + ":11: split_rebalance.primaries_target: unknown key",
+        path + ":13: feature_flags.kill_switch: unknown key",
+    }
+    if len(verr.Issues) != len(want) {
+        t.Fatalf("expected %d issues, got %d:\n%v", len(want), len(verr.Issues), err)
+    }
+    for i, w := range want {
+        if got := verr.Issues[i].String(); got != w {
+            t.Fatalf("issue %d: expected %q, got %q", i, w, got)
+        }
+    }
+}
+
+func TestValidateEnvOverrides(t *testing.T) {
+    path := filepath.Join("..", "..", "..", "..", "..", "config", "defaults.yaml")
+    rd, err := LoadDefaults(path)
+    if err != nil {
+        t.Fatalf("load defaults: %v", err)
+    }
+    os.Setenv("BUDGET_GOOGLE_TEXT_CAPACITY", "lots")
+    defer os.Unsetenv("BUDGET_GOOGLE_TEXT_CAPACITY")
+    os.Setenv("BUDGET_SPLIT_RATIO", "2")
+    defer os.Unsetenv("BUDGET_SPLIT_RATIO")
+
+    ApplyEnvOverrides(&rd)
+
+    if rd.Budgets["google.text"].Capacity != 1000 {
+        t.Fatalf("expected unparseable override to leave capacity 1000, got %d", rd.Budgets["google.text"].Capacity)
+    }
+    err = rd.Validate()
+    if err == nil {
+        t.Fatalf("expected validation error")
+    }
+    for _, w := range []string{
+        `env: BUDGET_GOOGLE_TEXT_CAPACITY: "lots" is not a valid integer`,
+        `env: BUDGET_SPLIT_RATIO: must be between 0 and 1 (overrides split_ratio)`,
+    } {
+        if !strings.Contains(err.Error(), w) {
+            t.Fatalf("expected %q in:\n%v", w, err)
+        }
+    }
+}
+
+func TestCityConfigLayers(t *testing.T) {
+    path := filepath.Join("..", "..", "..", "..", "..", "config", "defaults.yaml")
+    rd, err := LoadDefaults(path)
+    if err != nil {
+        t.Fatalf("load defaults: %v", err)
+    }
+    dir := t.TempDir()
+    overlayYAML := "city: Lisbon\nseed_top_n: 120\nearly_stop:\n  window: 100\n"
+    if err := os.WriteFile(filepath.Join(dir, "lisbon.yaml"), []byte(overlayYAML), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    overlay, err := ApplyCityOverlay(&rd, dir, "Lisbon")
+    if err != nil {
+        t.Fatalf("overlay: %v", err)
+    }
+    if overlay != filepath.Join(dir, "lisbon.yaml") {
+        t.Fatalf("expected lisbon.yaml applied, got %q", overlay)
+    }
+    os.Setenv("CITY_H3_RES", "8")
+    defer os.Unsetenv("CITY_H3_RES")
+    ApplyEnvOverrides(&rd)
+    if err := rd.Validate(); err != nil {
+        t.Fatalf("validate: %v", err)
+    }
+
+    cc := rd.ForCity("Lisbon")
+    if err := cc.ApplyInput(map[string]any{"config": map[string]any{"radius_m_primary": 750}}); err != nil {
+        t.Fatalf("apply input: %v", err)
+    }
+    if cc.SeedTopN != 120 || cc.H3Res != 8 || cc.RadiusMPrimary != 750 || cc.RadiusMSecondary != 400 || cc.EarlyStop.Window != 100 {
+        t.Fatalf("unexpected merged config: %+v", cc)
+    }
+    if cc.EarlyStop.MinSamples != 50 {
+        t.Fatalf("expected overlay to keep default min_samples 50, got %d", cc.EarlyStop.MinSamples)
+    }
+
+    for field, want := range map[string]string{
+        "radius_m_secondary": "default " + path + ":",
+        "seed_top_n":         "city " + filepath.Join(dir, "lisbon.yaml") + ":2",
+        "early_stop.window":  "city " + filepath.Join(dir, "lisbon.yaml") + ":4",
+        "h3_res":             "env CITY_H3_RES",
+        "radius_m_primary":   "input",
+    } {
+        if got := cc.Origins[field].String(); !strings.HasPrefix(got, want) {
+            t.Fatalf("%s: expected origin %q, got %q", field, want, got)
+        }
+    }
+}
+
+func TestCityOverlayErrors(t *testing.T) {
+    path := filepath.Join("..", "..", "..", "..", "..", "config", "defaults.yaml")
+    rd, err := LoadDefaults(path)
+    if err != nil {
+        t.Fatalf("load defaults: %v", err)
+    }
+    dir := t.TempDir()
+    if overlay, err := ApplyCityOverlay(&rd, dir, "Nowhere"); err != nil || overlay != "" {
+        t.Fatalf("expected missing overlay to be skipped, got %q, %v", overlay, err)
+    }
+
+    bad := "seed_top_n: 10\nradius_m_tertiary: 100\n"
+    if err := os.WriteFile(filepath.Join(dir, "porto.yaml"), []byte(bad), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    if _, err := ApplyCityOverlay(&rd, dir, "Porto"); err == nil || !strings.Contains(err.Error(), "line 2: field radius_m_tertiary not found") {
+        t.Fatalf("expected unknown key error on line 2, got %v", err)
+    }
+
+    if err := os.WriteFile(filepath.Join(dir, "porto.yaml"), []byte("h3_res: 16\n"), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    if _, err := ApplyCityOverlay(&rd, dir, "Porto"); err != nil {
+        t.Fatalf("overlay: %v", err)
+    }
+    want := filepath.Join(dir, "porto.yaml") + ":1: city_defaults.h3_res: must be between 0 and 15"
+    if err := rd.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+        t.Fatalf("expected %q, got %v", want, err)
+    }
+
+    // Both bad cadences are reported, primaries first, on every run.
+    cadences := "h3_res: 8\nrefresh:\n  primaries: hourly\n  secondaries: yearly\n"
+    if err := os.WriteFile(filepath.Join(dir, "porto.yaml"), []byte(cadences), 0o644); err != nil {
+        t.Fatalf("write: %v", err)
+    }
+    if _, err := ApplyCityOverlay(&rd, dir, "Porto"); err != nil {
+        t.Fatalf("overlay: %v", err)
+    }
+    wantIssues := []string{
+        filepath.Join(dir, "porto.yaml") + ":3: city_defaults.refresh.primaries: \"hourly\" is not one of daily, weekly, monthly",
+        filepath.Join(dir, "porto.yaml") + ":4: city_defaults.refresh.secondaries: \"yearly\" is not one of daily, weekly, monthly",
+    }
+    for i := 0; i < 10; i++ {
+        var verr *ValidationError
+        if err := rd.Validate(); !errors.As(err, &verr) || len(verr.Issues) != len(wantIssues) {
+            t.Fatalf("expected %d issues, got %v", len(wantIssues), err)
+        }
+        for j, w := range wantIssues {
+            if got := verr.Issues[j].String(); got != w {
+                t.Fatalf("issue %d: expected %q, got %q", j, w, got)
+            }
+        }
+    }
+}
+
+func TestShippedCityOverlays(t *testing.T) {
+    root := filepath.Join("..", "..", "..", "..", "..", "config")
+    overlays, err := filepath.Glob(filepath.Join(root, "cities", "*.yaml"))
+    if err != nil {
+        t.Fatalf("glob: %v", err)
+    }
+    for _, o := range overlays {
+        rd, err := LoadDefaults(filepath.Join(root, "defaults.yaml"))
+        if err != nil {
+            t.Fatalf("load defaults: %v", err)
+        }
+        city := strings.TrimSuffix(filepath.Base(o), ".yaml")
+        if _, err := ApplyCityOverlay(&rd, filepath.Dir(o), city); err != nil {
+            t.Fatalf("%s: %v", o, err)
+        }
+        if err := rd.Validate(); err != nil {
+            t.Fatalf("%s: %v", o, err)
+        }
+    }
+}
+
+func TestFeatureFlags(t *testing.T) {
+    path := filepath.Join("..", "..", "..", "..", "..", "config", "defaults.yaml")
+    rd, err := LoadDefaults(path)
+    if err != nil {
+        t.Fatalf("load defaults: %v", err)
+    }
+    os.Setenv("KILL_SWITCH_GOOGLE", "true")
+    defer os.Unsetenv("KILL_SWITCH_GOOGLE")
+    os.Setenv("KILL_SWITCH_GOOGLE_DETAILS", "false")
+    defer os.Unsetenv("KILL_SWITCH_GOOGLE_DETAILS")
+    ApplyEnvOverrides(&rd)
+    if err := rd.Validate(); err != nil {
+        t.Fatalf("validate: %v", err)
+    }
+
+    flags := NewFlags(rd.FeatureFlags)
+    ctx := context.Background()
+    if !flags.IsEnabled(ctx, MockStateFlag("ExtractWithLLM")) || flags.IsEnabled(ctx, MockStateFlag("SeedPrimaries")) {
+        t.Fatalf("expected only configured mock_states to be mocked")
+    }
+    if flags.IsEnabled(ctx, ConnectorFlag(b.GoogleText)) {
+        t.Fatalf("expected google group kill switch to disable google.text")
+    }
+    if !flags.IsEnabled(ctx, ConnectorFlag(b.GoogleDetails)) {
+        t.Fatalf("expected google.details switch to beat its group")
+    }
+    if !flags.IsEnabled(ctx, ConnectorFlag(b.TavilyAPI)) {
+        t.Fatalf("expected unswitched connector to be enabled")
+    }
+
+    // Execution input overrides win over config.
+    ctx = WithOverrides(ctx, OverridesFromInput(map[string]any{
+        "kill_switches": map[string]any{"google": false, "llm": true},
+        "feature_flags": map[string]any{
+            "mock_states": []any{"SeedPrimaries"},
+            "sources":     map[string]any{"osm": false},
+        },
+    }))
+    if !flags.IsEnabled(ctx, ConnectorFlag(b.GoogleText)) || flags.IsEnabled(ctx, ConnectorFlag(b.LLMTokens)) {
+        t.Fatalf("expected input kill_switches to override config")
+    }
+    if !flags.IsEnabled(ctx, MockStateFlag("SeedPrimaries")) {
+        t.Fatalf("expected input mock_states to mock SeedPrimaries")
+    }
+    if flags.IsEnabled(ctx, SourceFlag("osm")) {
+        t.Fatalf("expected input to disable osm")
+    }
+
+    // Sources fall back to the run's CityConfig.
+    cc := rd.ForCity("Edinburgh")
+    cc.Sources.Tavily = false
+    ctx = WithCityConfig(context.Background(), &cc)
+    if flags.IsEnabled(ctx, SourceFlag("tavily")) || !flags.IsEnabled(ctx, SourceFlag("wikidata")) {
+        t.Fatalf("expected sources from the city config")
+    }
+}
+
+func TestValidateKillSwitchKeys(t *testing.T) {
+    var rd RawDefaults
+    rd.FeatureFlags.KillSwitches = map[string]bool{"google": true, "overpass": true, "gogle": true}
+    err := rd.Validate()
+    if err == nil || !strings.Contains(err.Error(), "feature_flags.kill_switches.gogle: unknown connector or group") {
+        t.Fatalf("expected unknown kill switch error, got %v", err)
+    }
+    if strings.Count(err.Error(), "kill_switches") != 1 {
+        t.Fatalf("expected only gogle rejected, got %v", err)
+    }
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	b "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/budget"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/clock"
+)
+
+// Subscriber is handed every config a Reloader accepts after the first.
+type Subscriber func(ctx context.Context, rd RawDefaults) error
+
+// BudgetSubscriber resizes g to each new config's budgets.
+func BudgetSubscriber(g *b.Guard) Subscriber {
+	return func(ctx context.Context, rd RawDefaults) error {
+		return g.Reconfigure(ctx, BuildBudgetConfig(rd))
+	}
+}
+
+// FlagsSubscriber swaps each new config's feature flags into f.
+func FlagsSubscriber(f *Flags) Subscriber {
+	return func(ctx context.Context, rd RawDefaults) error {
+		f.Update(rd.FeatureFlags)
+		return nil
+	}
+}
+
+// ErrRejected wraps a reloaded config that failed to parse or validate; the
+// Reloader keeps serving the last good one.
+var ErrRejected = errors.New("config update rejected")
+
+// Reloader keeps the current config from a Source, polling it for changes
+// and pushing validated updates to subscribers, so long-running workers pick
+// up budget and kill switch changes without a restart.
+type Reloader struct {
+	src      Source
+	prepare  func(*RawDefaults) error
+	interval time.Duration
+	clock    clock.Clock
+
+	mu      sync.Mutex
+	current RawDefaults
+	version string
+	subs    []Subscriber
+}
+
+// ReloaderOption customizes a Reloader built by NewReloader.
+type ReloaderOption func(*Reloader)
+
+// WithPrepare runs fn on every parsed document before validation, to layer
+// on what the source does not hold (city overlay, env overrides).
+func WithPrepare(fn func(*RawDefaults) error) ReloaderOption {
+	return func(r *Reloader) {
+		r.prepare = fn
+	}
+}
+
+// WithInterval sets how often Run polls; the default is 30s.
+func WithInterval(d time.Duration) ReloaderOption {
+	return func(r *Reloader) {
+		r.interval = d
+	}
+}
+
+// WithReloadClock drives Run's polling from c instead of the real clock.
+func WithReloadClock(c clock.Clock) ReloaderOption {
+	return func(r *Reloader) {
+		r.clock = c
+	}
+}
+
+func NewReloader(src Source, opts ...ReloaderOption) *Reloader {
+	r := &Reloader{src: src, interval: 30 * time.Second, clock: clock.Real()}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Load reads the initial config. Unlike a later update, an invalid initial
+// config has nothing to fall back to and is returned as an error.
+func (r *Reloader) Load(ctx context.Context) (RawDefaults, error) {
+	rd, version, err := r.fetch(ctx)
+	if err != nil {
+		return RawDefaults{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current, r.version = rd, version
+	return rd, nil
+}
+
+// Current returns the last good config.
+func (r *Reloader) Current() RawDefaults {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Subscribe registers s for every config accepted from now on.
+func (r *Reloader) Subscribe(s Subscriber) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subs = append(r.subs, s)
+}
+
+// Poll checks the source once and reports whether the config changed. A new
+// version that fails to parse or validate is rejected with an error matching
+// ErrRejected, leaving the last good config current; the version is
+// remembered so the same bad document is not reported again. Subscriber
+// errors are returned joined, after every subscriber has run.
+func (r *Reloader) Poll(ctx context.Context) (bool, error) {
+	data, version, err := r.src.Fetch(ctx)
+	if err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	if version == r.version {
+		r.mu.Unlock()
+		return false, nil
+	}
+	r.version = version
+	r.mu.Unlock()
+
+	rd, err := r.parse(data)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s version %s: %w", ErrRejected, r.src.Name(), version, err)
+	}
+	r.mu.Lock()
+	r.current = rd
+	subs := append([]Subscriber(nil), r.subs...)
+	r.mu.Unlock()
+
+	var errs []error
+	for _, s := range subs {
+		if err := s(ctx, rd); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return true, errors.Join(errs...)
+}
+
+// Run polls every interval until ctx is done, handing errors (rejected
+// updates included) to onErr, which may be nil.
+func (r *Reloader) Run(ctx context.Context, onErr func(error)) {
+	for {
+		t := r.clock.NewTimer(r.interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C():
+		}
+		if _, err := r.Poll(ctx); err != nil && onErr != nil {
+			onErr(err)
+		}
+	}
+}
+
+func (r *Reloader) fetch(ctx context.Context) (RawDefaults, string, error) {
+	data, version, err := r.src.Fetch(ctx)
+	if err != nil {
+		return RawDefaults{}, "", err
+	}
+	rd, err := r.parse(data)
+	return rd, version, err
+}
+
+func (r *Reloader) parse(data []byte) (RawDefaults, error) {
+	rd, err := ParseDefaults(r.src.Name(), data)
+	if err != nil {
+		return RawDefaults{}, err
+	}
+	if r.prepare != nil {
+		if err := r.prepare(&rd); err != nil {
+			return RawDefaults{}, err
+		}
+	}
+	if err := rd.Validate(); err != nil {
+		return RawDefaults{}, err
+	}
+	return rd, nil
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	b "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/budget"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/clock"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+const reloadYAML = `version: 1
+budgets:
+  google.text:
+    capacity: %CAP%
+    refill: 0
+    period: 1m
+split_ratio: 0.7
+feature_flags:
+  kill_switches:
+    web: %KILL%
+`
+
+func reloadDoc(capacity, kill string) string {
+	return strings.NewReplacer("%CAP%", capacity, "%KILL%", kill).Replace(reloadYAML)
+}
+
+// fakeSSM is an in-memory Parameter Store that bumps a parameter's version on
+// every put, as SSM does.
+type fakeSSM struct {
+	mu     sync.Mutex
+	params map[string]ssmtypes.Parameter
+}
+
+func (f *fakeSSM) put(name, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p := f.params[name]
+	p.Name, p.Value, p.Version = aws.String(name), aws.String(value), p.Version+1
+	f.params[name] = p
+}
+
+func (f *fakeSSM) GetParameter(ctx context.Context, in *ssm.GetParameterInput, _ ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.params[aws.ToString(in.Name)]
+	if !ok {
+		return nil, &ssmtypes.ParameterNotFound{}
+	}
+	return &ssm.GetParameterOutput{Parameter: &p}, nil
+}
+
+func capacity(t *testing.T, g *b.Guard) int64 {
+	t.Helper()
+	snap, err := g.Snapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snap.Connectors[b.GoogleText].Capacity
+}
+
+func TestReloaderFileSource(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "defaults.yaml")
+	write := func(doc string) {
+		if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(reloadDoc("10", "false"))
+
+	r := NewReloader(NewFileSource(path))
+	rd, err := r.Load(ctx)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	guard := b.NewGuard(BuildBudgetConfig(rd))
+	flags := NewFlags(rd.FeatureFlags)
+	r.Subscribe(BudgetSubscriber(guard))
+	r.Subscribe(FlagsSubscriber(flags))
+
+	if changed, err := r.Poll(ctx); changed || err != nil {
+		t.Fatalf("unchanged file: changed=%v err=%v", changed, err)
+	}
+
+	write(reloadDoc("25", "true"))
+	if changed, err := r.Poll(ctx); !changed || err != nil {
+		t.Fatalf("edited file: changed=%v err=%v", changed, err)
+	}
+	if got := capacity(t, guard); got != 25 {
+		t.Fatalf("capacity after reload = %d, want 25", got)
+	}
+	if flags.IsEnabled(ctx, ConnectorFlag(b.WebFetch)) {
+		t.Fatalf("expected reloaded kill switch to disable web.fetch")
+	}
+
+	// An invalid update is rejected once and the last good config kept.
+	write(reloadDoc("-1", "false"))
+	changed, err := r.Poll(ctx)
+	if changed || !errors.Is(err, ErrRejected) || !strings.Contains(err.Error(), path+":4: budgets.google.text.capacity") {
+		t.Fatalf("invalid file: changed=%v err=%v", changed, err)
+	}
+	if changed, err := r.Poll(ctx); changed || err != nil {
+		t.Fatalf("same invalid file polled again: changed=%v err=%v", changed, err)
+	}
+	if got := r.Current().Budgets["google.text"].Capacity; got != 25 {
+		t.Fatalf("current capacity = %d, want last good 25", got)
+	}
+	if got := capacity(t, guard); got != 25 || flags.IsEnabled(ctx, ConnectorFlag(b.WebFetch)) {
+		t.Fatalf("subscribers saw the rejected config")
+	}
+}
+
+func TestReloaderSSMSource(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeSSM{params: map[string]ssmtypes.Parameter{}}
+	fake.put("/jaunt/dev/defaults", reloadDoc("10", "false"))
+
+	clk := clock.NewFake(time.Unix(0, 0))
+	r := NewReloader(NewSSMSource(fake, "/jaunt/dev/defaults"), WithInterval(time.Minute), WithReloadClock(clk))
+	rd, err := r.Load(ctx)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	guard := b.NewGuard(BuildBudgetConfig(rd))
+	r.Subscribe(BudgetSubscriber(guard))
+	updated := make(chan struct{}, 1)
+	r.Subscribe(func(ctx context.Context, rd RawDefaults) error {
+		updated <- struct{}{}
+		return nil
+	})
+	rejected := make(chan error, 1)
+
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go r.Run(runCtx, func(err error) { rejected <- err })
+
+	tick := func() {
+		for clk.Timers() < 1 {
+			time.Sleep(time.Millisecond)
+		}
+		clk.Advance(time.Minute)
+	}
+
+	fake.put("/jaunt/dev/defaults", reloadDoc("40", "false"))
+	tick()
+	select {
+	case <-updated:
+	case err := <-rejected:
+		t.Fatalf("update rejected: %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("no update after the poll interval")
+	}
+	if got := capacity(t, guard); got != 40 {
+		t.Fatalf("capacity after SSM update = %d, want 40", got)
+	}
+
+	fake.put("/jaunt/dev/defaults", "budgets: [not, a, map]")
+	tick()
+	select {
+	case err := <-rejected:
+		if !errors.Is(err, ErrRejected) || !strings.Contains(err.Error(), "ssm:/jaunt/dev/defaults version 3") {
+			t.Fatalf("rejection = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("bad parameter was not rejected")
+	}
+	if got := capacity(t, guard); got != 40 {
+		t.Fatalf("capacity after rejected update = %d, want 40", got)
+	}
+}
+
+func TestReloaderLoadRejectsInvalidInitialConfig(t *testing.T) {
+	fake := &fakeSSM{params: map[string]ssmtypes.Parameter{}}
+	fake.put("p", reloadDoc("10", "maybe"))
+	if _, err := NewReloader(NewSSMSource(fake, "p")).Load(context.Background()); err == nil {
+		t.Fatal("expected invalid initial config to fail")
+	}
+	if _, err := NewReloader(NewSSMSource(fake, "missing")).Load(context.Background()); err == nil {
+		t.Fatal("expected missing parameter to fail")
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// Source yields defaults.yaml documents for a Reloader.
+type Source interface {
+	// Name locates the document in origins and validation issues.
+	Name() string
+	// Fetch returns the current document and a version that changes
+	// whenever the document does.
+	Fetch(ctx context.Context) (data []byte, version string, err error)
+}
+
+// FileSource watches a file on disk; its version is a hash of the content,
+// so edits are seen however coarse the filesystem's mtimes are.
+type FileSource struct {
+	path string
+}
+
+func NewFileSource(path string) *FileSource {
+	return &FileSource{path: path}
+}
+
+func (s *FileSource) Name() string { return s.path }
+
+func (s *FileSource) Fetch(ctx context.Context) ([]byte, string, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(data)
+	return data, hex.EncodeToString(sum[:]), nil
+}
+
+// SSMAPI is the subset of *ssm.Client used by SSMSource.
+type SSMAPI interface {
+	GetParameter(ctx context.Context, in *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// SSMSource reads the document from an SSM Parameter Store parameter
+// (String or SecureString); its version is the parameter's version.
+type SSMSource struct {
+	client SSMAPI
+	name   string
+}
+
+func NewSSMSource(client SSMAPI, parameter string) *SSMSource {
+	return &SSMSource{client: client, name: parameter}
+}
+
+func (s *SSMSource) Name() string { return "ssm:" + s.name }
+
+func (s *SSMSource) Fetch(ctx context.Context) ([]byte, string, error) {
+	out, err := s.client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(s.name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("ssm get %s: %w", s.name, err)
+	}
+	if out.Parameter == nil || out.Parameter.Value == nil {
+		return nil, "", fmt.Errorf("ssm get %s: no value", s.name)
+	}
+	return []byte(aws.ToString(out.Parameter.Value)), strconv.FormatInt(out.Parameter.Version, 10), nil
+}
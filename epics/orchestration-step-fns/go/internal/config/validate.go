@@ -0,0 +1,311 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	b "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/budget"
+	"gopkg.in/yaml.v3"
+)
+
+// Issue is one problem found by Validate, located by file and line where the
+// value came from YAML, or by variable name (File "env") for env overrides.
+type Issue struct {
+	File  string
+	Line  int
+	Field string
+	Msg   string
+}
+
+func (i Issue) String() string {
+	loc := i.File
+	if i.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", i.File, i.Line)
+	}
+	if loc == "" {
+		return fmt.Sprintf("%s: %s", i.Field, i.Msg)
+	}
+	return fmt.Sprintf("%s: %s: %s", loc, i.Field, i.Msg)
+}
+
+// ValidationError carries every Issue found, so one run reports them all.
+type ValidationError struct {
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Issues))
+	for i, is := range e.Issues {
+		lines[i] = "  " + is.String()
+	}
+	return fmt.Sprintf("%d config issue(s):\n%s", len(e.Issues), strings.Join(lines, "\n"))
+}
+
+// source is the parsed YAML a RawDefaults was decoded from.
+type source struct {
+	file string
+	root *yaml.Node
+}
+
+// mapping returns the document's root mapping, or nil.
+func (s *source) mapping() *yaml.Node {
+	if s == nil || s.root == nil {
+		return nil
+	}
+	n := s.root
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	return n
+}
+
+// unknownKeys walks the mapping n against t's yaml tags and calls report
+// with each key no field of t has, at any depth. Map keys (connectors, lanes
+// and so on) are left to Validate's own checks; their values are walked.
+func unknownKeys(n *yaml.Node, t reflect.Type, path []string, report func(key *yaml.Node, field string)) {
+	if n == nil {
+		return
+	}
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	at := func(k string) []string { return append(append([]string{}, path...), k) }
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			ft, ok := fields[key.Value]
+			if !ok {
+				report(key, strings.Join(at(key.Value), "."))
+				continue
+			}
+			unknownKeys(n.Content[i+1], ft, at(key.Value), report)
+		}
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			unknownKeys(n.Content[i+1], t.Elem(), at(n.Content[i].Value), report)
+		}
+	}
+}
+
+// yamlFields maps the yaml key of each field t decodes to the field's type.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// node finds the key node at path, or nil.
+func (s *source) node(path ...string) *yaml.Node {
+	n := s.mapping()
+	var key *yaml.Node
+	for _, p := range path {
+		if n == nil || n.Kind != yaml.MappingNode {
+			return nil
+		}
+		key = nil
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == p {
+				key, n = n.Content[i], n.Content[i+1]
+				break
+			}
+		}
+		if key == nil {
+			return nil
+		}
+	}
+	return key
+}
+
+// Validate checks rd for unknown keys and connectors, out-of-range values and
+// env overrides that did not parse. It returns a *ValidationError listing
+// every issue, or nil.
+func (rd RawDefaults) Validate() error {
+	v := validator{src: rd.src, origins: rd.origins}
+	if rd.src != nil {
+		v.file = rd.src.file
+	}
+
+	unknownKeys(rd.src.mapping(), reflect.TypeOf(rd), nil, func(key *yaml.Node, field string) {
+		v.issues = append(v.issues, Issue{File: v.file, Line: key.Line, Field: field, Msg: "unknown key"})
+	})
+
+	v.city(rd.CityDefaults, "city_defaults")
+
+	for _, token := range sortedKeys(rd.Budgets) {
+		cfg := rd.Budgets[token]
+		if !v.connector("budgets", token) {
+			continue
+		}
+		v.check(cfg.Capacity < 0, "must not be negative", "budgets", token, "capacity")
+		v.check(cfg.Refill < 0, "must not be negative", "budgets", token, "refill")
+		v.check(cfg.Period < 0, "must not be negative", "budgets", token, "period")
+		v.check(cfg.Refill > 0 && cfg.Period == 0,
+			fmt.Sprintf("refill %d needs a period > 0s", cfg.Refill), "budgets", token, "period")
+	}
+
+	v.check(rd.SplitRatio < 0 || rd.SplitRatio > 1, "must be between 0 and 1", "split_ratio")
+	for _, token := range sortedKeys(rd.SplitRatios) {
+		r := rd.SplitRatios[token]
+		if !v.connector("split_ratios", token) {
+			continue
+		}
+		v.check(r < 0 || r > 1, "must be between 0 and 1", "split_ratios", token)
+	}
+
+	for _, token := range sortedKeys(rd.Pricing) {
+		p := rd.Pricing[token]
+		if !v.connector("pricing", token) {
+			continue
+		}
+		v.check(p.PerCallUSD < 0, "must not be negative", "pricing", token, "per_call_usd")
+		v.check(p.PerTokenUSD < 0, "must not be negative", "pricing", token, "per_token_usd")
+	}
+
+	rb := rd.SplitRebalance
+	v.check(rb.Interval < 0, "must not be negative", "split_rebalance", "interval")
+	v.check(rb.PrimariesTargetMin < 0, "must not be negative", "split_rebalance", "primaries_target_min")
+	v.check(rb.PrimariesTargetMax < 0, "must not be negative", "split_rebalance", "primaries_target_max")
+	v.check(rb.PrimariesTargetMin > 0 && rb.PrimariesTargetMax > 0 && rb.PrimariesTargetMin > rb.PrimariesTargetMax,
+		"must not exceed primaries_target_max", "split_rebalance", "primaries_target_min")
+
+	fl := rd.FrontierLanes
+	for _, p := range sortedKeys(fl.Weights) {
+		if !p.Known() {
+			v.check(true, "unknown lane (high, medium or low)", "frontier_lanes", "weights", string(p))
+			continue
+		}
+		v.check(fl.Weights[p] < 0, "must not be negative", "frontier_lanes", "weights", string(p))
+	}
+	v.check(fl.HighTrust < 0 || fl.HighTrust > 1, "must be between 0 and 1", "frontier_lanes", "high_trust")
+	v.check(fl.LowTrust < 0 || fl.LowTrust > 1, "must be between 0 and 1", "frontier_lanes", "low_trust")
+	v.check(fl.LowTrust > fl.HighTrust, "must not exceed high_trust", "frontier_lanes", "low_trust")
+
+	for _, k := range sortedKeys(rd.FeatureFlags.KillSwitches) {
+		_, group := ConnectorGroups[k]
+		v.check(!group && !b.Connector(k).Known(), "unknown connector or group", "feature_flags", "kill_switches", k)
+	}
+
+	for _, k := range sortedKeys(rd.Concurrency) {
+		v.check(rd.Concurrency[k] < 0, "must not be negative", "concurrency", k)
+	}
+
+	v.issues = append(v.issues, rd.envIssues...)
+	return v.err()
+}
+
+type validator struct {
+	src     *source
+	origins map[string]Origin
+	file    string
+	issues  []Issue
+}
+
+func (v *validator) err() error {
+	if len(v.issues) == 0 {
+		return nil
+	}
+	return &ValidationError{Issues: v.issues}
+}
+
+// city checks a CityConfig found at path at.
+func (v *validator) city(c CityConfig, at ...string) {
+	f := func(field ...string) []string {
+		return append(append([]string{}, at...), field...)
+	}
+	v.check(c.H3Res < 0 || c.H3Res > 15, "must be between 0 and 15", f("h3_res")...)
+	v.check(c.SeedTopN < 0, "must not be negative", f("seed_top_n")...)
+	v.check(c.RadiusMPrimary < 0, "must not be negative", f("radius_m_primary")...)
+	v.check(c.RadiusMSecondary < 0, "must not be negative", f("radius_m_secondary")...)
+	v.check(c.EarlyStop.MinNewUniqueRate < 0 || c.EarlyStop.MinNewUniqueRate > 1,
+		"must be between 0 and 1", f("early_stop", "min_new_unique_rate")...)
+	v.check(c.EarlyStop.Window < 0, "must not be negative", f("early_stop", "window")...)
+	v.check(c.EarlyStop.MinSamples < 0, "must not be negative", f("early_stop", "min_samples")...)
+	v.check(c.Budgets.MaxAPICalls < 0, "must not be negative", f("budgets", "max_api_calls")...)
+	v.check(c.Budgets.MaxWallClockHours < 0, "must not be negative", f("budgets", "max_wall_clock_hours")...)
+	v.check(c.Budgets.MaxCostUSD < 0, "must not be negative", f("budgets", "max_cost_usd")...)
+	switch c.LLM.UseTiebreak {
+	case "", TiebreakAlways, TiebreakSelective, TiebreakNever:
+	default:
+		v.check(true, fmt.Sprintf("%q is not one of always, selective, never", c.LLM.UseTiebreak), f("llm", "use_tiebreak")...)
+	}
+	for _, r := range []struct{ field, cadence string }{
+		{"primaries", c.Refresh.Primaries},
+		{"secondaries", c.Refresh.Secondaries},
+	} {
+		v.check(r.cadence != "" && !refreshCadences[r.cadence],
+			fmt.Sprintf("%q is not one of daily, weekly, monthly", r.cadence), f("refresh", r.field)...)
+	}
+}
+
+// check records msg against the field at path when bad is true, locating it
+// by the layer that set the field: the env var, the input, or the YAML line.
+func (v *validator) check(bad bool, msg string, path ...string) {
+	if !bad {
+		return
+	}
+	field := strings.Join(path, ".")
+	if o, ok := v.origins[field]; ok {
+		switch o.Layer {
+		case LayerEnv:
+			v.issues = append(v.issues, Issue{File: "env", Field: o.Env, Msg: msg + " (overrides " + field + ")"})
+		case LayerInput:
+			v.issues = append(v.issues, Issue{File: "input", Field: "config." + field, Msg: msg})
+		default:
+			v.issues = append(v.issues, Issue{File: o.File, Line: o.Line, Field: field, Msg: msg})
+		}
+		return
+	}
+	is := Issue{File: v.file, Field: field, Msg: msg}
+	if n := v.src.node(path...); n != nil {
+		is.Line = n.Line
+	}
+	v.issues = append(v.issues, is)
+}
+
+// connector records an issue and returns false when token under section is
+// not a known connector.
+func (v *validator) connector(section, token string) bool {
+	if b.Connector(token).Known() {
+		return true
+	}
+	v.check(true, "unknown connector", section, token)
+	return false
+}
+
+func sortedKeys[K ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
@@ -0,0 +1,120 @@
+package frontier
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// RadiusBuckets are the upper bounds, in meters, that maps radii are rounded
+// up to when fingerprinting, so near-identical searches collapse together.
+// Radii above the last bound share its bucket.
+var RadiusBuckets = []int64{50, 100, 250, 500, 1000, 2500, 5000, 10000, 25000, 50000}
+
+func radiusBucket(r int64) int64 {
+	for _, b := range RadiusBuckets {
+		if r <= b {
+			return b
+		}
+	}
+	return RadiusBuckets[len(RadiusBuckets)-1]
+}
+
+// cellSides are, per H3 resolution, the side in meters of a square with the
+// area of an average H3 cell, so a city's h3_res keeps its meaning for
+// gridCell.
+var cellSides = [...]float64{
+	2087450, 780890, 294621, 111326, 42076, 15903, 6011, 2272,
+	859, 325, 123, 46, 18, 6.6, 2.5, 0.9,
+}
+
+const metersPerDegree = 111320
+
+// gridCell names the cell containing lat/lng in a grid of roughly square
+// cells sized for H3 resolution res (clamped to 0-15): bands of equal
+// latitude, each split into columns as wide in meters as the band is tall.
+// It is pure Go, so fingerprinting builds without cgo.
+func gridCell(lat, lng float64, res int) string {
+	res = min(max(res, 0), len(cellSides)-1)
+	side := cellSides[res] / metersPerDegree // band height in degrees
+	row := math.Floor(lat / side)
+	mid := (row + 0.5) * side * math.Pi / 180
+	width := side / math.Max(math.Cos(mid), 1e-6)
+	col := math.Floor((lng + 180) / width)
+	return fmt.Sprintf("%d/%d/%d", res, int64(row), int64(col))
+}
+
+// Fingerprint identifies the work m asks for: the gridCell at resolution res
+// containing its center, its radius bucket and its lower-cased category. Maps
+// messages with equal fingerprints search the same place and are duplicates
+// within a run.
+func (m MapsMessage) Fingerprint(res int) string {
+	cell := gridCell(m.Lat, m.Lng, res)
+	cat := ""
+	if m.Cat != nil {
+		cat = strings.ToLower(strings.TrimSpace(*m.Cat))
+	}
+	return fmt.Sprintf("maps:%s:r%d:%s", cell, radiusBucket(m.Rad), cat)
+}
+
+// Fingerprint identifies the work w asks for: its normalized source URL (see
+// NormalizeURL) and crawl depth.
+func (w WebMessage) Fingerprint() string {
+	return fmt.Sprintf("web:%s:d%d", NormalizeURL(w.SourceURL), w.CrawlDepth)
+}
+
+// Fingerprint returns msg's fingerprint, with maps cells at resolution h3Res.
+func Fingerprint(msg Message, h3Res int) (string, error) {
+	switch m := msg.(type) {
+	case MapsMessage:
+		return m.Fingerprint(h3Res), nil
+	case *MapsMessage:
+		return m.Fingerprint(h3Res), nil
+	case WebMessage:
+		return m.Fingerprint(), nil
+	case *WebMessage:
+		return m.Fingerprint(), nil
+	}
+	return "", fmt.Errorf("%w: %T", ErrUnknownType, msg)
+}
+
+// trackingParams are query parameters that never change the page served.
+var trackingParams = map[string]bool{"fbclid": true, "gclid": true, "mc_cid": true, "mc_eid": true}
+
+// NormalizeURL canonicalizes raw so spellings of the same page compare equal.
+// It lower-cases the scheme and host, drops default ports, fragments,
+// tracking parameters (utm_*, gclid, ...) and trailing slashes, and sorts the
+// query. A URL that does not parse is only trimmed.
+func NormalizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment, u.RawFragment = "", ""
+	u.User = nil
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	q := u.Query()
+	for k := range q {
+		if trackingParams[strings.ToLower(k)] || strings.HasPrefix(strings.ToLower(k), "utm_") {
+			q.Del(k)
+		}
+	}
+	for _, vs := range q {
+		sort.Strings(vs)
+	}
+	u.RawQuery = q.Encode() // Encode sorts by key
+	u.ForceQuery = false
+	return u.String()
+}
@@ -0,0 +1,82 @@
+package frontier
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	for in, want := range map[string]string{
+		"HTTPS://Example.COM:443/Menu/":                       "https://example.com/Menu",
+		"http://example.com:80":                               "http://example.com",
+		"http://example.com:8080/a#top":                       "http://example.com:8080/a",
+		"https://user:pw@example.com/a?b=2&a=1&a=0":           "https://example.com/a?a=0&a=1&b=2",
+		"https://example.com/a?utm_source=x&UTM_Medium=y&q=1": "https://example.com/a?q=1",
+		"https://example.com/?fbclid=abc&gclid=def":           "https://example.com",
+		"  not a url  ":                                       "not a url",
+	} {
+		if got := NormalizeURL(in); got != want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMapsFingerprint(t *testing.T) {
+	cat := "Museum"
+	base := MapsMessage{Envelope: NewEnvelope("maps", "Edinburgh", correlationID), Lat: 55.9533, Lng: -3.1883, Rad: 480, Cat: &cat}
+	fp := base.Fingerprint(9)
+	if fp != "maps:9/19165/33906:r500:museum" {
+		t.Fatalf("fingerprint = %q", fp)
+	}
+
+	near, lower := base, "museum "
+	near.Lat, near.Rad, near.Cat = 55.95331, 500, &lower
+	near.CorrelationID = "another"
+	if got := near.Fingerprint(9); got != fp {
+		t.Errorf("overlapping search fingerprint %q, want %q", got, fp)
+	}
+
+	for name, m := range map[string]MapsMessage{
+		"far":    {Lat: 55.96, Lng: -3.1883, Rad: 480, Cat: &cat},
+		"wider":  {Lat: 55.9533, Lng: -3.1883, Rad: 501, Cat: &cat},
+		"no cat": {Lat: 55.9533, Lng: -3.1883, Rad: 480},
+	} {
+		if got := m.Fingerprint(9); got == fp {
+			t.Errorf("%s: fingerprint collides with base: %q", name, got)
+		}
+	}
+	if base.Fingerprint(5) == fp {
+		t.Error("resolution not part of fingerprint")
+	}
+}
+
+func TestGridCellIsRoughlySquare(t *testing.T) {
+	lat, lng := 59.9139, 10.7522
+	side := cellSides[9] / metersPerDegree
+	cell := gridCell(lat, lng, 9)
+	north := gridCell(lat+side, lng, 9)
+	east := gridCell(lat, lng+side/math.Cos(lat*math.Pi/180), 9)
+	if north == cell || east == cell {
+		t.Errorf("one cell side away stayed in %q: north %q, east %q", cell, north, east)
+	}
+	if gridCell(lat, lng, 20) != gridCell(lat, lng, 15) || gridCell(lat, lng, -1) != gridCell(lat, lng, 0) {
+		t.Error("resolution not clamped to 0-15")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	w := WebMessage{Envelope: NewEnvelope("web", "Edinburgh", correlationID), SourceURL: "https://Example.com/?utm_campaign=x", CrawlDepth: 1}
+	for _, msg := range []Message{w, &w} {
+		if fp, err := Fingerprint(msg, 9); err != nil || fp != "web:https://example.com:d1" {
+			t.Fatalf("Fingerprint(%T) = %q, %v", msg, fp, err)
+		}
+	}
+	w.CrawlDepth = 2
+	if w.Fingerprint() == "web:https://example.com:d1" {
+		t.Error("crawl depth not part of fingerprint")
+	}
+	if _, err := Fingerprint(nil, 9); !errors.Is(err, ErrUnknownType) {
+		t.Fatalf("nil message: %v", err)
+	}
+}
@@ -1,25 +1,42 @@
 package frontier
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/budget"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/schema"
 )
 
+// ErrUnknownType is returned by Decode for a body whose type is neither
+// "maps" nor "web".
+var ErrUnknownType = errors.New("unknown frontier message type")
+
+// Message is a frontier message: MapsMessage or WebMessage.
+type Message interface {
+	Header() Envelope
+	Validate() error
+}
+
 type Envelope struct {
-	Type                  string   `json:"type"` // "maps" or "web"
-	City                  string   `json:"city"`
-	CorrelationID         string   `json:"correlation_id"`
-	BudgetToken           string   `json:"budget_token,omitempty"`
-	TrustScore            *float64 `json:"trust_score,omitempty"`
-	CoordinatesConfidence *float64 `json:"coordinates_confidence,omitempty"`
-	EnqueuedAt            int64    `json:"enqueued_at"`
+	Type                  string       `json:"type"` // "maps" or "web"
+	City                  string       `json:"city"`
+	CorrelationID         string       `json:"correlation_id"`
+	BudgetToken           string       `json:"budget_token,omitempty"`
+	Split                 budget.Split `json:"split,omitempty"`
+	Priority              string       `json:"priority,omitempty"` // "low", "medium" or "high"; overrides the lane split and trust_score imply
+	TrustScore            *float64     `json:"trust_score,omitempty"`
+	CoordinatesConfidence *float64     `json:"coordinates_confidence,omitempty"`
+	EnqueuedAt            int64        `json:"enqueued_at"`
 }
 
 type MapsMessage struct {
 	Envelope
 	Lat float64 `json:"lat"`
 	Lng float64 `json:"lng"`
-	Rad float64 `json:"radius"`
+	Rad int64   `json:"radius"` // meters; the schema allows integers only
 	Cat *string `json:"category,omitempty"`
 }
 
@@ -31,6 +48,9 @@ type WebMessage struct {
 	CrawlDepth int    `json:"crawl_depth"`
 }
 
+// Header returns the envelope shared by every frontier message.
+func (e Envelope) Header() Envelope { return e }
+
 func NewEnvelope(msgType, city, correlationID string) Envelope {
 	return Envelope{
 		Type:          msgType,
@@ -40,28 +60,57 @@ func NewEnvelope(msgType, city, correlationID string) Envelope {
 	}
 }
 
+// Validate checks m against schemas/frontier.maps.json; violations are a
+// *schema.ValidationError naming each offending field.
 func (m MapsMessage) Validate() error {
-	if m.Type != "maps" {
-		return errors.New("type must be 'maps'")
-	}
-	if m.City == "" || m.CorrelationID == "" {
-		return errors.New("city and correlation_id required")
-	}
-	if m.Rad <= 0 {
-		return errors.New("radius must be > 0")
-	}
-	return nil
+	return schema.ValidateValue(schema.FrontierMaps, m)
 }
 
+// Validate checks w against schemas/frontier.web.json; violations are a
+// *schema.ValidationError naming each offending field.
 func (w WebMessage) Validate() error {
-	if w.Type != "web" {
-		return errors.New("type must be 'web'")
+	return schema.ValidateValue(schema.FrontierWeb, w)
+}
+
+// ValidateBody checks a raw JSON body against the schema for its "type".
+// Unlike decoding and calling Validate, it also catches fields the Go types
+// do not model, which decoding would drop.
+func ValidateBody(body []byte) error {
+	var env Envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return err
 	}
-	if w.City == "" || w.CorrelationID == "" {
-		return errors.New("city and correlation_id required")
+	switch env.Type {
+	case "maps":
+		return schema.Validate(schema.FrontierMaps, body)
+	case "web":
+		return schema.Validate(schema.FrontierWeb, body)
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownType, env.Type)
+	}
+}
+
+// Decode parses a JSON body into the message type named by its "type" field.
+// It does not validate the result.
+func Decode(body []byte) (Message, error) {
+	var env Envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return nil, err
 	}
-	if w.SourceURL == "" || w.SourceType == "" {
-		return errors.New("source_url and source_type required")
+	switch env.Type {
+	case "maps":
+		var m MapsMessage
+		if err := json.Unmarshal(body, &m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	case "web":
+		var w WebMessage
+		if err := json.Unmarshal(body, &w); err != nil {
+			return nil, err
+		}
+		return w, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownType, env.Type)
 	}
-	return nil
 }
@@ -0,0 +1,50 @@
+package frontier
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/schema"
+)
+
+const correlationID = "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+
+func TestValidateUsesSchema(t *testing.T) {
+	m := MapsMessage{Envelope: NewEnvelope("maps", "Edinburgh", correlationID), Lat: 55.95, Lng: -3.19, Rad: 500}
+	m.BudgetToken = "google.nearby"
+	if err := m.Validate(); err != nil {
+		t.Fatalf("valid maps message: %v", err)
+	}
+
+	m.Rad = 20
+	var verr *schema.ValidationError
+	if err := m.Validate(); !errors.As(err, &verr) || verr.Issues[0].Path != "radius" {
+		t.Fatalf("radius below minimum: %v", err)
+	}
+
+	w := WebMessage{Envelope: NewEnvelope("web", "Edinburgh", "not-a-uuid"), SourceURL: "https://example.com", SourceName: "Example", SourceType: "html", CrawlDepth: 9}
+	err := w.Validate()
+	if err == nil || !strings.Contains(err.Error(), "correlation_id: must match") || !strings.Contains(err.Error(), "crawl_depth: must be <= 5") {
+		t.Fatalf("invalid web message: %v", err)
+	}
+}
+
+func TestValidateBody(t *testing.T) {
+	body := `{"type":"web","city":"Edinburgh","source_url":"https://example.com","source_name":"Example",` +
+		`"source_type":"html","crawl_depth":1,"correlation_id":"` + correlationID + `","enqueued_at":1700000000}`
+	if err := ValidateBody([]byte(body)); err != nil {
+		t.Fatalf("valid body: %v", err)
+	}
+
+	// Decoding into WebMessage would drop the unknown field; the body check
+	// does not.
+	extra := strings.Replace(body, `"city"`, `"depth":2,"city"`, 1)
+	if err := ValidateBody([]byte(extra)); err == nil || !strings.Contains(err.Error(), "depth: is not allowed") {
+		t.Fatalf("body with unknown field: %v", err)
+	}
+
+	if err := ValidateBody([]byte(`{"type":"rss"}`)); !errors.Is(err, ErrUnknownType) {
+		t.Fatalf("unknown type: %v", err)
+	}
+}
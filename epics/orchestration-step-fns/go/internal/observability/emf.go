@@ -29,11 +29,11 @@ type EMFMetric struct {
 
 // EMFEnvelope represents the complete EMF log structure
 type EMFEnvelope struct {
-	AWSEMFTimestamp int64             `json:"_aws"`
-	CloudWatchLogs  *EMFCloudWatch    `json:"_aws"`
-	Dimensions      [][]string        `json:"_aws"`
-	MetricName      string            `json:"_aws"`
-	Namespace       string            `json:"_aws"`
+	AWSEMFTimestamp int64                  `json:"-"`
+	CloudWatchLogs  *EMFCloudWatch         `json:"_aws"`
+	Dimensions      [][]string             `json:"-"`
+	MetricName      string                 `json:"-"`
+	Namespace       string                 `json:"-"`
 	Metadata        map[string]interface{} `json:",inline"`
 }
 
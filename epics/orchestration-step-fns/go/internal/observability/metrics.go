@@ -164,6 +164,47 @@ func BudgetCapGauge(ctx context.Context, service, state, connector, city string,
 	})
 }
 
+func CountCacheHit(ctx context.Context, service, state, connector, city string) {
+	emitEMF(ctx, &EMFMetric{
+		MetricName:  "CacheHits",
+		Unit:        "Count",
+		Value:       1,
+		Service:     service,
+		State:       state,
+		Connector:   connector,
+		City:        city,
+		RunID:       extractRunID(ctx),
+		Split:       extractSplit(ctx),
+		CorrelationID: FromContext(ctx),
+	})
+}
+
+func CountCacheMiss(ctx context.Context, service, state, connector, city string) {
+	emitEMF(ctx, &EMFMetric{
+		MetricName:  "CacheMisses",
+		Unit:        "Count",
+		Value:       1,
+		Service:     service,
+		State:       state,
+		Connector:   connector,
+		City:        city,
+		RunID:       extractRunID(ctx),
+		Split:       extractSplit(ctx),
+		CorrelationID: FromContext(ctx),
+	})
+}
+
+// RecordStopDecision counts a budget or early-stop guard halting a run,
+// dimensioned by the guard's reason.
+func RecordStopDecision(ctx context.Context, service, state, city, reason string) {
+	EmitCustomEMF(ctx, EMFNamespace, "StopDecision", "Count", 1, map[string]string{
+		"Service": service,
+		"State":   state,
+		"City":    city,
+		"Reason":  reason,
+	})
+}
+
 // Helper functions to extract metadata from context
 func extractRunID(ctx context.Context) string {
 	if runID, ok := ctx.Value("run_id").(string); ok {
@@ -178,3 +219,13 @@ func extractSplit(ctx context.Context) string {
 	}
 	return "unknown"
 }
+
+// CountFrontierDuplicate counts a frontier message dropped before enqueue
+// because its fingerprint was already seen in the run.
+func CountFrontierDuplicate(ctx context.Context, service, msgType, city string) {
+	EmitCustomEMF(ctx, EMFNamespace, "FrontierDuplicatesDropped", "Count", 1, map[string]string{
+		"Service":     service,
+		"MessageType": msgType,
+		"City":        city,
+	})
+}
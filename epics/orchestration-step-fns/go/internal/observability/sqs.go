@@ -82,7 +82,12 @@ func SQSBatchPublishWithCorrelationID(ctx context.Context, sqsClient *sqs.Client
 	// Prepare batch entries
 	entries := make([]types.SendMessageBatchRequestEntry, len(messages))
 	for i, msg := range messages {
-		messageAttributes := WriteCorrelationIDToSQS(msg.MessageAttributes, correlationID)
+		// An entry that already carries its own correlation_id keeps it
+		id := correlationID
+		if attr, ok := msg.MessageAttributes[CorrelationIDAttribute]; ok && attr.StringValue != nil {
+			id = *attr.StringValue
+		}
+		messageAttributes := WriteCorrelationIDToSQS(msg.MessageAttributes, id)
 		
 		entries[i] = types.SendMessageBatchRequestEntry{
 			Id:                &msg.ID,
@@ -0,0 +1,105 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/frontier"
+	obs "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/observability"
+)
+
+// SeenStore is a per-run set of frontier message fingerprints.
+type SeenStore interface {
+	// MarkSeen adds fingerprint to runID's set and reports whether it was
+	// not there yet.
+	MarkSeen(ctx context.Context, runID, fingerprint string) (bool, error)
+	// Forget removes fingerprint, so a message whose enqueue failed can be
+	// retried.
+	Forget(ctx context.Context, runID, fingerprint string) error
+}
+
+// MemorySeenStore keeps seen-sets in process; workers that must share one
+// use DynamoSeenStore.
+type MemorySeenStore struct {
+	mu   sync.Mutex
+	runs map[string]map[string]bool
+}
+
+func NewMemorySeenStore() *MemorySeenStore {
+	return &MemorySeenStore{runs: make(map[string]map[string]bool)}
+}
+
+func (s *MemorySeenStore) MarkSeen(ctx context.Context, runID, fingerprint string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := s.runs[runID]
+	if seen == nil {
+		seen = make(map[string]bool)
+		s.runs[runID] = seen
+	}
+	if seen[fingerprint] {
+		return false, nil
+	}
+	seen[fingerprint] = true
+	return true, nil
+}
+
+func (s *MemorySeenStore) Forget(ctx context.Context, runID, fingerprint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.runs[runID], fingerprint)
+	return nil
+}
+
+// DedupQueue drops frontier messages whose fingerprint (see
+// frontier.Fingerprint) was already enqueued in the run, so discovery states
+// re-emitting overlapping tiles or the same URLs do not repeat work. Every
+// other call goes straight to the wrapped queue.
+type DedupQueue struct {
+	FrontierQueue
+	seen  SeenStore
+	runID string
+	h3Res int
+
+	dropped atomic.Int64
+}
+
+// NewDedupQueue wraps q, fingerprinting maps messages at H3 resolution h3Res
+// (the city's h3_res) and tracking them in seen under runID.
+func NewDedupQueue(q FrontierQueue, seen SeenStore, runID string, h3Res int) *DedupQueue {
+	return &DedupQueue{FrontierQueue: q, seen: seen, runID: runID, h3Res: h3Res}
+}
+
+// Enqueue forwards msg unless its fingerprint was seen before in the run, in
+// which case it is dropped, counted and nil returned. A message the wrapped
+// queue rejects is forgotten again, so a corrected retry goes through.
+func (q *DedupQueue) Enqueue(ctx context.Context, msg frontier.Message) error {
+	fp, err := frontier.Fingerprint(msg, q.h3Res)
+	if err != nil {
+		return fmt.Errorf("%w: got %T", ErrUnsupportedPayload, msg)
+	}
+	fresh, err := q.seen.MarkSeen(ctx, q.runID, fp)
+	if err != nil {
+		return fmt.Errorf("dedup: %w", err)
+	}
+	if !fresh {
+		q.dropped.Add(1)
+		h := msg.Header()
+		obs.CountFrontierDuplicate(ctx, "frontier", h.Type, h.City)
+		return nil
+	}
+	if err := q.FrontierQueue.Enqueue(ctx, msg); err != nil {
+		if ferr := q.seen.Forget(ctx, q.runID, fp); ferr != nil {
+			return fmt.Errorf("%w (and forgetting its fingerprint failed: %v)", err, ferr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Dropped reports how many duplicates Enqueue has dropped.
+func (q *DedupQueue) Dropped() int64 {
+	return q.dropped.Load()
+}
@@ -0,0 +1,74 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBAPI is the subset of *dynamodb.Client used by DynamoSeenStore.
+type DynamoDBAPI interface {
+	PutItem(ctx context.Context, in *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, in *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+// DynamoSeenStore keeps seen-sets in a DynamoDB table with string partition
+// key "run_id" and sort key "fingerprint", so every worker of a run shares
+// one set. Marking is a conditional put, so of two workers racing on the same
+// fingerprint exactly one sees it as new.
+type DynamoSeenStore struct {
+	client DynamoDBAPI
+	table  string
+	// TTL, when set, stamps items with an "expires_at" epoch-seconds
+	// attribute for DynamoDB TTL to reap after the run.
+	TTL time.Duration
+	now func() time.Time
+}
+
+func NewDynamoSeenStore(client DynamoDBAPI, table string) *DynamoSeenStore {
+	return &DynamoSeenStore{client: client, table: table, now: time.Now}
+}
+
+func (s *DynamoSeenStore) MarkSeen(ctx context.Context, runID, fingerprint string) (bool, error) {
+	item := seenKey(runID, fingerprint)
+	if s.TTL > 0 {
+		item["expires_at"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(s.now().Add(s.TTL).Unix(), 10)}
+	}
+	_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(s.table),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(run_id)"),
+	})
+	var exists *types.ConditionalCheckFailedException
+	if errors.As(err, &exists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("dynamodb put %s/%s: %w", runID, fingerprint, err)
+	}
+	return true, nil
+}
+
+func (s *DynamoSeenStore) Forget(ctx context.Context, runID, fingerprint string) error {
+	_, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.table),
+		Key:       seenKey(runID, fingerprint),
+	})
+	if err != nil {
+		return fmt.Errorf("dynamodb delete %s/%s: %w", runID, fingerprint, err)
+	}
+	return nil
+}
+
+func seenKey(runID, fingerprint string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"run_id":      &types.AttributeValueMemberS{Value: runID},
+		"fingerprint": &types.AttributeValueMemberS{Value: fingerprint},
+	}
+}
@@ -0,0 +1,125 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/frontier"
+)
+
+func TestDedupQueue_DropsRepeatsWithinRun(t *testing.T) {
+	ctx := context.Background()
+	inner, _ := newTestQueue(MemoryOptions{})
+	seen := NewMemorySeenStore()
+	q := NewDedupQueue(inner, seen, "run-1", 9)
+
+	require.NoError(t, q.Enqueue(ctx, mapsMessage("Edinburgh")))
+	require.NoError(t, q.Enqueue(ctx, mapsMessage("Edinburgh")))
+	web := &frontier.WebMessage{Envelope: frontier.NewEnvelope("web", "Edinburgh", ""), SourceURL: "https://example.com/", SourceName: "Example", SourceType: "html"}
+	require.NoError(t, q.Enqueue(ctx, web))
+	web2 := *web
+	web2.SourceURL = "https://EXAMPLE.com?utm_source=newsletter"
+	require.NoError(t, q.Enqueue(ctx, web2))
+
+	visible, _, _ := inner.Counts()
+	require.Equal(t, 2, visible)
+	require.EqualValues(t, 2, q.Dropped())
+
+	// A fresh run starts with an empty seen-set.
+	other := NewDedupQueue(inner, seen, "run-2", 9)
+	require.NoError(t, other.Enqueue(ctx, mapsMessage("Edinburgh")))
+	require.Zero(t, other.Dropped())
+}
+
+type failingQueue struct {
+	FrontierQueue
+	err error
+}
+
+func (f *failingQueue) Enqueue(ctx context.Context, msg frontier.Message) error { return f.err }
+
+func TestDedupQueue_ForgetsFailedEnqueue(t *testing.T) {
+	ctx := context.Background()
+	inner, _ := newTestQueue(MemoryOptions{})
+	failing := &failingQueue{FrontierQueue: inner, err: errors.New("throttled")}
+	seen := NewMemorySeenStore()
+
+	require.ErrorIs(t, NewDedupQueue(failing, seen, "run-1", 9).Enqueue(ctx, mapsMessage("Edinburgh")), failing.err)
+
+	q := NewDedupQueue(inner, seen, "run-1", 9)
+	require.NoError(t, q.Enqueue(ctx, mapsMessage("Edinburgh")))
+	require.Zero(t, q.Dropped())
+	visible, _, _ := inner.Counts()
+	require.Equal(t, 1, visible)
+}
+
+// fakeSeenTable evaluates the conditional put DynamoSeenStore issues.
+type fakeSeenTable struct {
+	mu    sync.Mutex
+	items map[string]map[string]types.AttributeValue
+}
+
+func (f *fakeSeenTable) id(key map[string]types.AttributeValue) string {
+	return key["run_id"].(*types.AttributeValueMemberS).Value + "/" + key["fingerprint"].(*types.AttributeValueMemberS).Value
+}
+
+func (f *fakeSeenTable) PutItem(ctx context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.id(in.Item)
+	if _, exists := f.items[id]; exists && aws.ToString(in.ConditionExpression) == "attribute_not_exists(run_id)" {
+		return nil, &types.ConditionalCheckFailedException{}
+	}
+	f.items[id] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeSeenTable) DeleteItem(ctx context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.items, f.id(in.Key))
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func TestDynamoSeenStore_SharedAcrossWorkers(t *testing.T) {
+	ctx := context.Background()
+	table := &fakeSeenTable{items: map[string]map[string]types.AttributeValue{}}
+	store := NewDynamoSeenStore(table, "frontier-seen")
+	store.TTL = time.Hour
+	store.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	var wg sync.WaitGroup
+	fresh := make(chan bool, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := store.MarkSeen(ctx, "run-1", "maps:abc:r500:")
+			require.NoError(t, err)
+			fresh <- ok
+		}()
+	}
+	wg.Wait()
+	close(fresh)
+	var n int
+	for ok := range fresh {
+		if ok {
+			n++
+		}
+	}
+	require.Equal(t, 1, n)
+	require.Equal(t, "1700003600", table.items["run-1/maps:abc:r500:"]["expires_at"].(*types.AttributeValueMemberN).Value)
+
+	require.NoError(t, store.Forget(ctx, "run-1", "maps:abc:r500:"))
+	ok, err := store.MarkSeen(ctx, "run-1", "maps:abc:r500:")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
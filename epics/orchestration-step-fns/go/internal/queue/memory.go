@@ -0,0 +1,289 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/frontier"
+	obs "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/observability"
+)
+
+// DeadLetter is a payload that was routed to the DLQ together with its reason.
+type DeadLetter struct {
+	Payload any
+	Reason  string
+}
+
+// MemoryOptions configures a MemoryQueue. Zero values fall back to SQS defaults.
+type MemoryOptions struct {
+	VisibilityTimeout time.Duration
+	// MaxReceiveCount moves a message to the DLQ when it is received more often
+	// than this (the redrive policy). Zero disables redrive.
+	MaxReceiveCount int
+	// DLQ receives redriven and dead-lettered messages. One is created when nil.
+	DLQ *MemoryQueue
+	Now func() time.Time
+}
+
+type memoryEntry struct {
+	id           string
+	body         any
+	attrs        map[string]string
+	enqueuedAt   time.Time
+	visibleAt    time.Time
+	receiveCount int
+	receipt      string
+}
+
+// MemoryQueue is an in-process FrontierQueue with SQS semantics: visibility
+// timeouts, receive counts, delay seconds, and redrive to a paired DLQ.
+type MemoryQueue struct {
+	mu       sync.Mutex
+	opts     MemoryOptions
+	seq      int64
+	entries  []*memoryEntry
+	receipts map[string]*memoryEntry
+}
+
+func NewMemoryQueue(opts MemoryOptions) *MemoryQueue {
+	if opts.VisibilityTimeout <= 0 {
+		opts.VisibilityTimeout = DefaultVisibilityTimeout
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	q := &MemoryQueue{opts: opts, receipts: make(map[string]*memoryEntry)}
+	if q.opts.DLQ == nil {
+		q.opts.DLQ = &MemoryQueue{
+			opts:     MemoryOptions{VisibilityTimeout: opts.VisibilityTimeout, Now: opts.Now},
+			receipts: make(map[string]*memoryEntry),
+		}
+	}
+	return q
+}
+
+// DLQ returns the paired dead-letter queue.
+func (q *MemoryQueue) DLQ() *MemoryQueue {
+	return q.opts.DLQ
+}
+
+func (q *MemoryQueue) Enqueue(ctx context.Context, msg frontier.Message) error {
+	return q.EnqueueDelayed(ctx, msg, 0)
+}
+
+// EnqueueDelayed adds a message that becomes visible after delay (SQS DelaySeconds).
+// Like SQSQueue, it validates the message and fills a missing correlation_id from ctx.
+func (q *MemoryQueue) EnqueueDelayed(ctx context.Context, msg frontier.Message, delay time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	msg, err := prepare(msg, obs.FromContext(obs.EnsureCorrelationID(ctx)))
+	if err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.push(msg, nil, delay)
+	return nil
+}
+
+// EnqueueRaw adds body unchanged after validating it against its schema;
+// Deliveries carry the same bytes in Body and the decoded message.
+func (q *MemoryQueue) EnqueueRaw(ctx context.Context, body []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := frontier.ValidateBody(body); err != nil {
+		return fmt.Errorf("invalid frontier message: %w", err)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.push(json.RawMessage(append([]byte(nil), body...)), nil, 0)
+	return nil
+}
+
+func (q *MemoryQueue) push(payload any, attrs map[string]string, delay time.Duration) {
+	now := q.opts.Now()
+	q.seq++
+	q.entries = append(q.entries, &memoryEntry{
+		id:         fmt.Sprintf("msg-%d", q.seq),
+		body:       payload,
+		attrs:      attrs,
+		enqueuedAt: now,
+		visibleAt:  now.Add(delay),
+	})
+}
+
+// Dequeue receives up to max visible messages. Each one is hidden for the
+// visibility timeout and must be acknowledged with Ack.
+func (q *MemoryQueue) Dequeue(ctx context.Context, max int) ([]*Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if max > MaxBatch {
+		return nil, ErrBatchTooLarge
+	}
+	if max <= 0 {
+		max = 1
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.opts.Now()
+	var out []*Delivery
+	kept := q.entries[:0]
+	for _, e := range q.entries {
+		if len(out) == max || now.Before(e.visibleAt) {
+			kept = append(kept, e)
+			continue
+		}
+		if q.opts.MaxReceiveCount > 0 && e.receiveCount >= q.opts.MaxReceiveCount && q.opts.DLQ != nil {
+			delete(q.receipts, e.receipt)
+			q.redrive(e, fmt.Sprintf("exceeded maxReceiveCount %d", q.opts.MaxReceiveCount))
+			continue
+		}
+		delete(q.receipts, e.receipt)
+		e.receiveCount++
+		e.visibleAt = now.Add(q.opts.VisibilityTimeout)
+		e.receipt = fmt.Sprintf("%s#%d", e.id, e.receiveCount)
+		q.receipts[e.receipt] = e
+		kept = append(kept, e)
+		out = append(out, q.delivery(e))
+	}
+	q.entries = kept
+	return out, nil
+}
+
+// delivery builds the Delivery for a just-received entry. Payloads that are
+// not frontier messages (dead-lettered documents) keep a nil Message.
+func (q *MemoryQueue) delivery(e *memoryEntry) *Delivery {
+	d := &Delivery{
+		ID:            e.id,
+		ReceiptHandle: e.receipt,
+		Attributes:    copyAttrs(e.attrs),
+		ReceiveCount:  e.receiveCount,
+		EnqueuedAt:    e.enqueuedAt,
+		queue:         q,
+	}
+	switch body := e.body.(type) {
+	case frontier.Message:
+		d.Message = body
+	case json.RawMessage:
+		d.Message, d.DecodeErr = frontier.Decode(body)
+		d.Body = body
+		return d
+	default:
+		d.DecodeErr = fmt.Errorf("%w: %T", frontier.ErrUnknownType, e.body)
+	}
+	d.Body, _ = json.Marshal(e.body)
+	return d
+}
+
+// Ack deletes a received message. Only the receipt from the latest receive is valid.
+func (q *MemoryQueue) Ack(ctx context.Context, receiptHandle string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, ok := q.receipts[receiptHandle]
+	if !ok {
+		return ErrInvalidReceipt
+	}
+	delete(q.receipts, receiptHandle)
+	for i, cur := range q.entries {
+		if cur == e {
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// ChangeVisibility hides an in-flight message for timeout from now; zero makes
+// it visible immediately (SQS ChangeMessageVisibility).
+func (q *MemoryQueue) ChangeVisibility(ctx context.Context, receiptHandle string, timeout time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, ok := q.receipts[receiptHandle]
+	if !ok {
+		return ErrInvalidReceipt
+	}
+	e.visibleAt = q.opts.Now().Add(timeout)
+	return nil
+}
+
+func (q *MemoryQueue) DeadLetter(ctx context.Context, payload any, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if q.opts.DLQ == nil {
+		return ErrNoDLQ
+	}
+	q.opts.DLQ.mu.Lock()
+	defer q.opts.DLQ.mu.Unlock()
+	q.opts.DLQ.push(payload, map[string]string{DeadLetterReasonAttribute: reason}, 0)
+	return nil
+}
+
+// redrive moves an entry to the DLQ, keeping its attributes. Caller holds q.mu.
+func (q *MemoryQueue) redrive(e *memoryEntry, reason string) {
+	attrs := copyAttrs(e.attrs)
+	if attrs == nil {
+		attrs = make(map[string]string)
+	}
+	attrs[DeadLetterReasonAttribute] = reason
+	q.opts.DLQ.mu.Lock()
+	defer q.opts.DLQ.mu.Unlock()
+	q.opts.DLQ.push(e.body, attrs, 0)
+}
+
+// Counts returns the approximate number of visible, in-flight, and delayed messages.
+func (q *MemoryQueue) Counts() (visible, inFlight, delayed int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.opts.Now()
+	for _, e := range q.entries {
+		switch {
+		case !now.Before(e.visibleAt):
+			visible++
+		case e.receiveCount > 0:
+			inFlight++
+		default:
+			delayed++
+		}
+	}
+	return visible, inFlight, delayed
+}
+
+// DeadLetters returns everything currently on the DLQ without receiving it.
+func (q *MemoryQueue) DeadLetters() []DeadLetter {
+	dlq := q.opts.DLQ
+	if dlq == nil {
+		return nil
+	}
+	dlq.mu.Lock()
+	defer dlq.mu.Unlock()
+	out := make([]DeadLetter, len(dlq.entries))
+	for i, e := range dlq.entries {
+		out[i] = DeadLetter{Payload: e.body, Reason: e.attrs[DeadLetterReasonAttribute]}
+	}
+	return out
+}
+
+func copyAttrs(in map[string]string) map[string]string {
+	if in == nil {
+		return nil
+	}
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,177 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/frontier"
+)
+
+type fakeNow struct{ t time.Time }
+
+func (f *fakeNow) Now() time.Time          { return f.t }
+func (f *fakeNow) Advance(d time.Duration) { f.t = f.t.Add(d) }
+
+func newTestQueue(opts MemoryOptions) (*MemoryQueue, *fakeNow) {
+	clock := &fakeNow{t: time.Unix(1700000000, 0)}
+	opts.Now = clock.Now
+	return NewMemoryQueue(opts), clock
+}
+
+func TestMemoryQueue_VisibilityTimeoutRedelivers(t *testing.T) {
+	ctx := context.Background()
+	q, clock := newTestQueue(MemoryOptions{VisibilityTimeout: 30 * time.Second})
+	require.NoError(t, q.Enqueue(ctx, mapsMessage("a")))
+
+	msgs, err := q.Dequeue(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 1, msgs[0].ReceiveCount)
+
+	// Hidden while in flight.
+	msgs2, err := q.Dequeue(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, msgs2)
+	_, inFlight, _ := q.Counts()
+	require.Equal(t, 1, inFlight)
+
+	// Not acked: redelivered after the timeout with a new receipt.
+	clock.Advance(30 * time.Second)
+	again, err := q.Dequeue(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	require.Equal(t, 2, again[0].ReceiveCount)
+	require.Equal(t, msgs[0].ID, again[0].ID)
+	require.NotEqual(t, msgs[0].ReceiptHandle, again[0].ReceiptHandle)
+
+	require.ErrorIs(t, q.Ack(ctx, msgs[0].ReceiptHandle), ErrInvalidReceipt, "stale receipt")
+	require.NoError(t, q.Ack(ctx, again[0].ReceiptHandle))
+
+	clock.Advance(time.Hour)
+	left, err := q.Dequeue(ctx, 10)
+	require.NoError(t, err)
+	require.Empty(t, left)
+}
+
+func TestMemoryQueue_RedriveAfterMaxReceiveCount(t *testing.T) {
+	ctx := context.Background()
+	q, clock := newTestQueue(MemoryOptions{VisibilityTimeout: time.Second, MaxReceiveCount: 2})
+	require.NoError(t, q.Enqueue(ctx, mapsMessage("poison")))
+
+	for i := 1; i <= 2; i++ {
+		msgs, err := q.Dequeue(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, msgs, 1)
+		require.Equal(t, i, msgs[0].ReceiveCount)
+		clock.Advance(time.Second)
+	}
+
+	msgs, err := q.Dequeue(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, msgs)
+
+	dl := q.DeadLetters()
+	require.Len(t, dl, 1)
+	require.Equal(t, "poison", dl[0].Payload.(frontier.Message).Header().City)
+	require.Contains(t, dl[0].Reason, "maxReceiveCount 2")
+
+	fromDLQ, err := q.DLQ().Dequeue(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, fromDLQ, 1)
+	require.Equal(t, 1, fromDLQ[0].ReceiveCount, "receive count restarts on the DLQ")
+	require.Equal(t, "poison", fromDLQ[0].Message.Header().City)
+	require.Contains(t, fromDLQ[0].Attributes[DeadLetterReasonAttribute], "maxReceiveCount")
+}
+
+func TestMemoryQueue_DelaySeconds(t *testing.T) {
+	ctx := context.Background()
+	q, clock := newTestQueue(MemoryOptions{})
+	require.NoError(t, q.EnqueueDelayed(ctx, mapsMessage("later"), 10*time.Second))
+
+	_, _, delayed := q.Counts()
+	require.Equal(t, 1, delayed)
+	msgs, err := q.Dequeue(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, msgs)
+
+	clock.Advance(10 * time.Second)
+	msgs, err = q.Dequeue(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+}
+
+func TestMemoryQueue_BatchReceive(t *testing.T) {
+	ctx := context.Background()
+	q, _ := newTestQueue(MemoryOptions{})
+	for i := 0; i < 15; i++ {
+		require.NoError(t, q.Enqueue(ctx, mapsMessage(fmt.Sprintf("m%d", i))))
+	}
+
+	_, err := q.Dequeue(ctx, 11)
+	require.ErrorIs(t, err, ErrBatchTooLarge)
+
+	first, err := q.Dequeue(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, first, 10)
+	require.Equal(t, "m0", first[0].Message.Header().City)
+
+	rest, err := q.Dequeue(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, rest, 5)
+}
+
+func TestMemoryQueue_DeliveryAckNackExtend(t *testing.T) {
+	ctx := context.Background()
+	q, clock := newTestQueue(MemoryOptions{VisibilityTimeout: 30 * time.Second})
+	require.NoError(t, q.Enqueue(ctx, mapsMessage("retry-me")))
+
+	msgs, err := q.Dequeue(ctx, 1)
+	require.NoError(t, err)
+	require.IsType(t, frontier.MapsMessage{}, msgs[0].Message)
+	require.NoError(t, msgs[0].Nack(ctx, 0))
+
+	msgs, err = q.Dequeue(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1, "visible again immediately after Nack(0)")
+
+	// Extending keeps it hidden past the original timeout.
+	clock.Advance(20 * time.Second)
+	require.NoError(t, msgs[0].ExtendVisibility(ctx, time.Minute))
+	clock.Advance(20 * time.Second)
+	hidden, err := q.Dequeue(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, hidden)
+
+	require.NoError(t, msgs[0].Ack(ctx))
+	require.ErrorIs(t, msgs[0].Ack(ctx), ErrInvalidReceipt)
+	clock.Advance(time.Hour)
+	left, err := q.Dequeue(ctx, 10)
+	require.NoError(t, err)
+	require.Empty(t, left)
+}
+
+func TestMemoryQueue_EnqueueValidatesAndDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	q, _ := newTestQueue(MemoryOptions{})
+
+	bad := mapsMessage("Edinburgh")
+	bad.Rad = 0
+	require.ErrorContains(t, q.Enqueue(ctx, bad), "radius: must be >= 50")
+
+	doc := map[string]any{"city": "Edinburgh"}
+	require.NoError(t, q.DeadLetter(ctx, doc, "validation failed"))
+	dl := q.DeadLetters()
+	require.Equal(t, []DeadLetter{{Payload: doc, Reason: "validation failed"}}, dl)
+
+	// Execution documents are not frontier messages but can still be read.
+	fromDLQ, err := q.DLQ().Dequeue(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, fromDLQ, 1)
+	require.Nil(t, fromDLQ[0].Message)
+	require.ErrorIs(t, fromDLQ[0].DecodeErr, frontier.ErrUnknownType)
+	require.JSONEq(t, `{"city":"Edinburgh"}`, string(fromDLQ[0].Body))
+}
@@ -0,0 +1,226 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/budget"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/frontier"
+)
+
+// Priority names a frontier lane. The names are the values of the frontier
+// schemas' "priority" field.
+type Priority string
+
+const (
+	High   Priority = "high"   // primary seeds
+	Medium Priority = "medium" // expansion
+	Low    Priority = "low"    // tile sweeps and secondaries
+
+	// PriorityAttribute is set on every Delivery from a PriorityQueue to the
+	// lane it came from.
+	PriorityAttribute = "priority"
+)
+
+// Priorities lists the lanes from most to least urgent.
+var Priorities = []Priority{High, Medium, Low}
+
+// Known reports whether p is one of Priorities.
+func (p Priority) Known() bool {
+	for _, k := range Priorities {
+		if p == k {
+			return true
+		}
+	}
+	return false
+}
+
+// LaneConfig decides which lane a message takes and how consumers share
+// Dequeue between lanes.
+type LaneConfig struct {
+	// Weights are the lanes' shares of dequeued messages while every lane has
+	// work; a lane with no work hands its share to the others. A lane weighted
+	// 0 is read only once the weighted lanes are empty.
+	Weights map[Priority]int `yaml:"weights" json:"weights,omitempty"`
+	// HighTrust is the trust_score from which primaries go high; unscored
+	// primaries go high too.
+	HighTrust float64 `yaml:"high_trust" json:"high_trust"`
+	// LowTrust is the trust_score below which any message goes low.
+	LowTrust float64 `yaml:"low_trust" json:"low_trust"`
+}
+
+// DefaultLaneConfig matches frontier_lanes in config/defaults.yaml.
+func DefaultLaneConfig() LaneConfig {
+	return LaneConfig{
+		Weights:   map[Priority]int{High: 6, Medium: 3, Low: 1},
+		HighTrust: 0.7,
+		LowTrust:  0.4,
+	}
+}
+
+// Lane picks msg's lane. An explicit priority on the message wins; without
+// one the lane follows from its split and trust_score:
+//   - secondaries, and anything scored below LowTrust, go low;
+//   - primaries scored at least HighTrust, or unscored, go high;
+//   - everything else (including messages without a split) goes medium.
+func (c LaneConfig) Lane(msg frontier.Message) Priority {
+	env := msg.Header()
+	if p := Priority(env.Priority); p.Known() {
+		return p
+	}
+	trust := env.TrustScore
+	switch {
+	case env.Split == budget.Secondaries, trust != nil && *trust < c.LowTrust:
+		return Low
+	case env.Split == budget.Primaries && (trust == nil || *trust >= c.HighTrust):
+		return High
+	}
+	return Medium
+}
+
+// PriorityQueue is a FrontierQueue over one underlying queue per lane.
+// Enqueue routes by LaneConfig.Lane; Dequeue drains the lanes by smooth
+// weighted round-robin, so with weights 6:3:1 a busy frontier hands out six
+// high messages for every three medium and one low, across calls.
+// Deliveries are acknowledged on the lane they came from.
+type PriorityQueue struct {
+	lanes map[Priority]FrontierQueue
+	cfg   LaneConfig
+
+	mu      sync.Mutex
+	current map[Priority]int // round-robin credit carried between Dequeue calls
+}
+
+// NewPriorityQueue builds a PriorityQueue from a queue for each of
+// Priorities. DeadLetter goes through the medium lane, so the lanes should
+// share a DLQ.
+func NewPriorityQueue(lanes map[Priority]FrontierQueue, cfg LaneConfig) (*PriorityQueue, error) {
+	for _, p := range Priorities {
+		if lanes[p] == nil {
+			return nil, fmt.Errorf("priority queue: no %s lane", p)
+		}
+	}
+	return &PriorityQueue{lanes: lanes, cfg: cfg, current: make(map[Priority]int)}, nil
+}
+
+// NewMemoryPriorityQueue builds a PriorityQueue over MemoryQueues sharing
+// opts.DLQ, which is created when nil.
+func NewMemoryPriorityQueue(opts MemoryOptions, cfg LaneConfig) *PriorityQueue {
+	if opts.DLQ == nil {
+		opts.DLQ = NewMemoryQueue(MemoryOptions{VisibilityTimeout: opts.VisibilityTimeout, Now: opts.Now})
+	}
+	lanes := make(map[Priority]FrontierQueue, len(Priorities))
+	for _, p := range Priorities {
+		lanes[p] = NewMemoryQueue(opts)
+	}
+	q, _ := NewPriorityQueue(lanes, cfg)
+	return q
+}
+
+// NewSQSPriorityQueue builds a PriorityQueue over one SQS queue per lane,
+// urls giving each lane's QueueURL; the rest of opts applies to every lane.
+// Lanes are short-polled whatever opts.WaitTime says, since a long poll on an
+// empty lane would hold up the others; callers back off on an empty Dequeue.
+func NewSQSPriorityQueue(client *sqs.Client, urls map[Priority]string, opts SQSOptions, cfg LaneConfig) (*PriorityQueue, error) {
+	lanes := make(map[Priority]FrontierQueue, len(Priorities))
+	for _, p := range Priorities {
+		if urls[p] == "" {
+			return nil, fmt.Errorf("priority queue: no %s lane URL", p)
+		}
+		o := opts
+		o.QueueURL, o.WaitTime = urls[p], 0
+		lanes[p] = NewSQSQueue(client, o)
+	}
+	return NewPriorityQueue(lanes, cfg)
+}
+
+// Lane returns the queue behind lane p.
+func (q *PriorityQueue) Lane(p Priority) FrontierQueue {
+	return q.lanes[p]
+}
+
+func (q *PriorityQueue) Enqueue(ctx context.Context, msg frontier.Message) error {
+	return q.lanes[q.cfg.Lane(msg)].Enqueue(ctx, msg)
+}
+
+// Dequeue receives up to max messages, sharing them between lanes by weight
+// and topping up from the other lanes when one runs dry. If a lane fails
+// after others returned messages, those are returned and the error is left
+// for the next call to hit.
+func (q *PriorityQueue) Dequeue(ctx context.Context, max int) ([]*Delivery, error) {
+	if max > MaxBatch {
+		return nil, ErrBatchTooLarge
+	}
+	if max <= 0 {
+		max = 1
+	}
+	active := append([]Priority(nil), Priorities...)
+	var out []*Delivery
+	for len(out) < max && len(active) > 0 {
+		share := q.allocate(max-len(out), active)
+		var still []Priority
+		for _, p := range active {
+			n := share[p]
+			if n == 0 {
+				still = append(still, p)
+				continue
+			}
+			ds, err := q.lanes[p].Dequeue(ctx, n)
+			if err != nil {
+				if len(out) > 0 {
+					return out, nil
+				}
+				return nil, fmt.Errorf("%s lane: %w", p, err)
+			}
+			for _, d := range ds {
+				if d.Attributes == nil {
+					d.Attributes = make(map[string]string, 1)
+				}
+				d.Attributes[PriorityAttribute] = string(p)
+			}
+			out = append(out, ds...)
+			if len(ds) == n {
+				still = append(still, p)
+			}
+		}
+		active = still
+	}
+	return out, nil
+}
+
+// allocate hands n slots to the active lanes by smooth weighted round-robin,
+// carrying each lane's credit over to the next call. When no active lane has
+// weight, all slots go to the most urgent one.
+func (q *PriorityQueue) allocate(n int, active []Priority) map[Priority]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	share := make(map[Priority]int, len(active))
+	total := 0
+	for _, p := range active {
+		total += max(0, q.cfg.Weights[p])
+	}
+	if total == 0 {
+		share[active[0]] = n
+		return share
+	}
+	for i := 0; i < n; i++ {
+		var best Priority
+		for _, p := range active {
+			q.current[p] += max(0, q.cfg.Weights[p])
+			if best == "" || q.current[p] > q.current[best] {
+				best = p
+			}
+		}
+		q.current[best] -= total
+		share[best]++
+	}
+	return share
+}
+
+// DeadLetter dead-letters payload through the medium lane.
+func (q *PriorityQueue) DeadLetter(ctx context.Context, payload any, reason string) error {
+	return q.lanes[Medium].DeadLetter(ctx, payload, reason)
+}
@@ -0,0 +1,162 @@
+package queue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/budget"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/frontier"
+)
+
+func laneMessage(split budget.Split, trust *float64) frontier.MapsMessage {
+	m := mapsMessage("Edinburgh")
+	m.Split, m.TrustScore = split, trust
+	return m
+}
+
+func withPriority(m frontier.MapsMessage, p string) frontier.MapsMessage {
+	m.Priority = p
+	return m
+}
+
+func trust(v float64) *float64 { return &v }
+
+func TestLaneConfig_Lane(t *testing.T) {
+	cfg := DefaultLaneConfig()
+	for name, tc := range map[string]struct {
+		msg  frontier.MapsMessage
+		want Priority
+	}{
+		"unscored primary":     {laneMessage(budget.Primaries, nil), High},
+		"trusted primary":      {laneMessage(budget.Primaries, trust(0.7)), High},
+		"middling primary":     {laneMessage(budget.Primaries, trust(0.5)), Medium},
+		"untrusted primary":    {laneMessage(budget.Primaries, trust(0.39)), Low},
+		"trusted secondary":    {laneMessage(budget.Secondaries, trust(0.9)), Low},
+		"no split":             {laneMessage("", nil), Medium},
+		"no split, low trust":  {laneMessage("", trust(0.1)), Low},
+		"no split, high trust": {laneMessage("", trust(0.9)), Medium},
+		"explicit high":        {withPriority(laneMessage("", nil), "high"), High},
+		"explicit medium":      {withPriority(laneMessage(budget.Secondaries, trust(0.1)), "medium"), Medium},
+		"explicit low":         {withPriority(laneMessage(budget.Primaries, nil), "low"), Low},
+	} {
+		require.Equal(t, tc.want, cfg.Lane(tc.msg), name)
+		require.Equal(t, tc.want, cfg.Lane(&tc.msg), name)
+	}
+}
+
+// fill enqueues n messages that land in lane p under DefaultLaneConfig.
+func fill(t *testing.T, q FrontierQueue, p Priority, n int) {
+	t.Helper()
+	msg := map[Priority]frontier.MapsMessage{
+		High:   laneMessage(budget.Primaries, nil),
+		Medium: laneMessage("", nil),
+		Low:    laneMessage(budget.Secondaries, nil),
+	}[p]
+	for i := 0; i < n; i++ {
+		require.NoError(t, q.Enqueue(context.Background(), msg))
+	}
+}
+
+func countLanes(ds []*Delivery) map[Priority]int {
+	out := make(map[Priority]int)
+	for _, d := range ds {
+		out[Priority(d.Attributes[PriorityAttribute])]++
+	}
+	return out
+}
+
+func TestPriorityQueue_WeightedFairShare(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryPriorityQueue(MemoryOptions{}, DefaultLaneConfig())
+	for _, p := range Priorities {
+		fill(t, q, p, 20)
+	}
+
+	batch, err := q.Dequeue(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, map[Priority]int{High: 6, Medium: 3, Low: 1}, countLanes(batch))
+
+	// The share holds across single-message receives too.
+	var singles []*Delivery
+	for i := 0; i < 10; i++ {
+		ds, err := q.Dequeue(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, ds, 1)
+		singles = append(singles, ds...)
+	}
+	require.Equal(t, map[Priority]int{High: 6, Medium: 3, Low: 1}, countLanes(singles))
+	require.Equal(t, High, Priority(singles[0].Attributes[PriorityAttribute]))
+}
+
+func TestPriorityQueue_IdleLanesHandOverTheirShare(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryPriorityQueue(MemoryOptions{}, DefaultLaneConfig())
+	fill(t, q, High, 2)
+	fill(t, q, Low, 20)
+
+	ds, err := q.Dequeue(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, map[Priority]int{High: 2, Low: 8}, countLanes(ds))
+
+	for _, d := range ds {
+		require.NoError(t, d.Ack(ctx))
+	}
+	visible, inFlight, _ := q.Lane(Low).(*MemoryQueue).Counts()
+	require.Equal(t, 12, visible)
+	require.Zero(t, inFlight)
+}
+
+func TestPriorityQueue_ZeroWeightLaneWaitsForTheOthers(t *testing.T) {
+	ctx := context.Background()
+	cfg := DefaultLaneConfig()
+	cfg.Weights = map[Priority]int{High: 1, Medium: 1}
+	q := NewMemoryPriorityQueue(MemoryOptions{}, cfg)
+	fill(t, q, High, 3)
+	fill(t, q, Medium, 3)
+	fill(t, q, Low, 3)
+
+	ds, err := q.Dequeue(ctx, 6)
+	require.NoError(t, err)
+	require.Equal(t, map[Priority]int{High: 3, Medium: 3}, countLanes(ds))
+
+	ds, err = q.Dequeue(ctx, 6)
+	require.NoError(t, err)
+	require.Equal(t, map[Priority]int{Low: 3}, countLanes(ds))
+}
+
+func TestPriorityQueue_SharedDLQ(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryPriorityQueue(MemoryOptions{}, DefaultLaneConfig())
+	require.NoError(t, q.DeadLetter(ctx, map[string]string{"run_id": "r-1"}, "boom"))
+	require.Same(t, q.Lane(High).(*MemoryQueue).DLQ(), q.Lane(Medium).(*MemoryQueue).DLQ())
+	require.Len(t, q.Lane(Low).(*MemoryQueue).DeadLetters(), 1)
+}
+
+func TestSQSPriorityQueue_RoutesAndDrainsLanes(t *testing.T) {
+	ctx := context.Background()
+	base, fake := newTestSQSQueue(t)
+	urls := map[Priority]string{High: frontierURL + "-high", Medium: frontierURL, Low: frontierURL + "-low"}
+
+	_, err := NewSQSPriorityQueue(base.client, map[Priority]string{High: urls[High]}, SQSOptions{}, DefaultLaneConfig())
+	require.ErrorContains(t, err, "no medium lane URL")
+
+	q, err := NewSQSPriorityQueue(base.client, urls, SQSOptions{DLQURL: dlqURL}, DefaultLaneConfig())
+	require.NoError(t, err)
+	fill(t, q, High, 1)
+	fill(t, q, Low, 4)
+	require.Len(t, fake.bodies(urls[High]), 1)
+	require.Len(t, fake.bodies(urls[Low]), 4)
+	require.Empty(t, fake.bodies(urls[Medium]))
+
+	ds, err := q.Dequeue(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, map[Priority]int{High: 1, Low: 4}, countLanes(ds))
+	require.Equal(t, High, Priority(ds[0].Attributes[PriorityAttribute]))
+	for _, d := range ds {
+		require.NoError(t, d.Ack(ctx))
+	}
+	require.Empty(t, fake.bodies(urls[High]))
+	require.Empty(t, fake.bodies(urls[Low]))
+}
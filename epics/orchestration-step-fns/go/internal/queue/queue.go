@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/frontier"
+)
+
+const (
+	// MaxBatch is the SQS limit on messages per receive or send batch.
+	MaxBatch = 10
+	// DefaultVisibilityTimeout matches the SQS queue default.
+	DefaultVisibilityTimeout = 30 * time.Second
+	// DeadLetterReasonAttribute carries the reason a message was dead-lettered.
+	DeadLetterReasonAttribute = "dead_letter_reason"
+)
+
+var (
+	ErrBatchTooLarge  = fmt.Errorf("batch exceeds %d messages", MaxBatch)
+	ErrInvalidReceipt = errors.New("receipt handle is invalid or expired")
+	ErrNoDLQ          = errors.New("queue has no dead-letter queue")
+
+	ErrUnsupportedPayload = errors.New("payload must be a frontier.MapsMessage or frontier.WebMessage")
+)
+
+// FrontierQueue abstracts interactions with the frontier and DLQ.
+// Implementations should be mockable for tests.
+//
+// Delivery is at-least-once: a dequeued message stays hidden for the
+// visibility timeout and is redelivered unless it is acknowledged.
+type FrontierQueue interface {
+	Enqueue(ctx context.Context, msg frontier.Message) error
+	Dequeue(ctx context.Context, max int) ([]*Delivery, error)
+	// DeadLetter takes any payload; the state machine dead-letters whole
+	// execution documents, not only frontier messages.
+	DeadLetter(ctx context.Context, payload any, reason string) error
+}
+
+// RawEnqueuer is implemented by queues that can republish a frontier body
+// byte for byte. Decoding into frontier.Message and enqueueing that drops the
+// schema fields the Go types do not model (priority, metadata, ...), so tools
+// that move messages between queues, like dlq-redrive, use EnqueueRaw.
+type RawEnqueuer interface {
+	// EnqueueRaw validates body with frontier.ValidateBody and sends it
+	// unchanged.
+	EnqueueRaw(ctx context.Context, body []byte) error
+}
+
+// receiptHandler is implemented by every queue that hands out Deliveries.
+type receiptHandler interface {
+	Ack(ctx context.Context, receiptHandle string) error
+	ChangeVisibility(ctx context.Context, receiptHandle string, timeout time.Duration) error
+}
+
+// Delivery is one received message. Exactly one of Ack or Nack should be
+// called once processing finishes; long-running work can call
+// ExtendVisibility to keep the message hidden meanwhile.
+type Delivery struct {
+	ID            string
+	ReceiptHandle string
+	// Message is the decoded body. It is nil when the body is not a frontier
+	// message (an SQS queue read with KeepUndecodable, or a dead-lettered
+	// execution document), in which case DecodeErr says why.
+	Message      frontier.Message
+	DecodeErr    error
+	Body         []byte
+	Attributes   map[string]string
+	ReceiveCount int
+	EnqueuedAt   time.Time
+
+	queue receiptHandler
+}
+
+// Ack deletes the message from the queue.
+func (d *Delivery) Ack(ctx context.Context) error {
+	return d.queue.Ack(ctx, d.ReceiptHandle)
+}
+
+// Nack returns the message to the queue, visible again after delay.
+func (d *Delivery) Nack(ctx context.Context, delay time.Duration) error {
+	return d.queue.ChangeVisibility(ctx, d.ReceiptHandle, delay)
+}
+
+// ExtendVisibility keeps the message hidden for timeout from now.
+func (d *Delivery) ExtendVisibility(ctx context.Context, timeout time.Duration) error {
+	return d.queue.ChangeVisibility(ctx, d.ReceiptHandle, timeout)
+}
+
+// prepare returns a validated copy of m as a MapsMessage or WebMessage value.
+// An empty correlation_id is replaced with correlationID before validation.
+func prepare(m frontier.Message, correlationID string) (frontier.Message, error) {
+	var out frontier.Message
+	switch p := m.(type) {
+	case frontier.MapsMessage:
+		if p.CorrelationID == "" {
+			p.CorrelationID = correlationID
+		}
+		out = p
+	case *frontier.MapsMessage:
+		return prepare(*p, correlationID)
+	case frontier.WebMessage:
+		if p.CorrelationID == "" {
+			p.CorrelationID = correlationID
+		}
+		out = p
+	case *frontier.WebMessage:
+		return prepare(*p, correlationID)
+	default:
+		return nil, fmt.Errorf("%w: got %T", ErrUnsupportedPayload, m)
+	}
+	if err := out.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid frontier message: %w", err)
+	}
+	return out, nil
+}
@@ -1,11 +1,312 @@
 package queue
 
-import "context"
-
-// FrontierQueue abstracts interactions with the frontier and DLQ.
-// Implementations should be mockable for tests.
-type FrontierQueue interface {
-	Enqueue(ctx context.Context, payload any) error
-	Dequeue(ctx context.Context, max int) ([]any, error)
-	DeadLetter(ctx context.Context, payload any, reason string) error
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/frontier"
+	obs "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/observability"
+)
+
+// SQSOptions configures an SQSQueue.
+type SQSOptions struct {
+	// QueueURL is the frontier queue.
+	QueueURL string
+	// DLQURL receives DeadLetter payloads and undecodable messages. Optional.
+	DLQURL string
+	// WaitTime enables long polling on Dequeue (SQS WaitTimeSeconds).
+	WaitTime time.Duration
+	// VisibilityTimeout overrides the queue default for received messages when set.
+	VisibilityTimeout time.Duration
+	// KeepUndecodable returns messages that are not valid frontier messages
+	// from Dequeue instead of moving them to the DLQ. Use it to read a DLQ.
+	KeepUndecodable bool
+}
+
+// SQSQueue is a FrontierQueue backed by SQS. Bodies are the JSON encoding of
+// frontier.MapsMessage and frontier.WebMessage, and every send carries the
+// correlation_id message attribute.
+type SQSQueue struct {
+	client *sqs.Client
+	opts   SQSOptions
+}
+
+func NewSQSQueue(client *sqs.Client, opts SQSOptions) *SQSQueue {
+	return &SQSQueue{client: client, opts: opts}
+}
+
+// Enqueue validates and sends a single frontier message. A missing envelope
+// correlation_id is filled from ctx; the correlation_id attribute always
+// matches the body's.
+func (q *SQSQueue) Enqueue(ctx context.Context, msg frontier.Message) error {
+	ctx = obs.EnsureCorrelationID(ctx)
+	body, id, err := encodeFrontier(msg, obs.FromContext(ctx))
+	if err != nil {
+		return err
+	}
+	_, err = obs.SQSPublishWithCorrelationID(obs.WithCorrelationID(ctx, id), q.client, q.opts.QueueURL, body, nil)
+	if err != nil {
+		return fmt.Errorf("sqs send: %w", err)
+	}
+	return nil
+}
+
+// EnqueueRaw sends body as is after validating it against its schema, with
+// the body's correlation_id as the message attribute.
+func (q *SQSQueue) EnqueueRaw(ctx context.Context, body []byte) error {
+	if err := frontier.ValidateBody(body); err != nil {
+		return fmt.Errorf("invalid frontier message: %w", err)
+	}
+	if m, err := frontier.Decode(body); err == nil && m.Header().CorrelationID != "" {
+		ctx = obs.WithCorrelationID(ctx, m.Header().CorrelationID)
+	}
+	ctx = obs.EnsureCorrelationID(ctx)
+	if _, err := obs.SQSPublishWithCorrelationID(ctx, q.client, q.opts.QueueURL, string(body), nil); err != nil {
+		return fmt.Errorf("sqs send: %w", err)
+	}
+	return nil
+}
+
+// BatchFailure is a batch entry SQS rejected. Index refers to the message
+// slice passed to EnqueueBatch.
+type BatchFailure struct {
+	Index       int
+	Code        string
+	Message     string
+	SenderFault bool
+}
+
+// BatchError reports the entries of an EnqueueBatch call that were not sent;
+// all other entries were accepted.
+type BatchError struct {
+	Failed []BatchFailure
+}
+
+func (e *BatchError) Error() string {
+	parts := make([]string, len(e.Failed))
+	for i, f := range e.Failed {
+		parts[i] = fmt.Sprintf("#%d %s: %s", f.Index, f.Code, f.Message)
+	}
+	return fmt.Sprintf("sqs batch: %d entries failed (%s)", len(e.Failed), strings.Join(parts, "; "))
+}
+
+// EnqueueBatch validates every message up front, then sends them in chunks of
+// MaxBatch. Per-entry rejections are returned as a *BatchError so callers can
+// retry just those messages.
+func (q *SQSQueue) EnqueueBatch(ctx context.Context, msgs []frontier.Message) error {
+	ctx = obs.EnsureCorrelationID(ctx)
+	entries := make([]obs.BatchMessageInput, len(msgs))
+	for i, m := range msgs {
+		body, id, err := encodeFrontier(m, obs.FromContext(ctx))
+		if err != nil {
+			return fmt.Errorf("message %d: %w", i, err)
+		}
+		entries[i] = obs.BatchMessageInput{ID: strconv.Itoa(i), Body: body, MessageAttributes: obs.WriteCorrelationIDToSQS(nil, id)}
+	}
+
+	var failed []BatchFailure
+	for start := 0; start < len(entries); start += MaxBatch {
+		end := min(start+MaxBatch, len(entries))
+		out, err := obs.SQSBatchPublishWithCorrelationID(ctx, q.client, q.opts.QueueURL, entries[start:end])
+		if err != nil {
+			// The whole request failed; report every entry of this chunk and
+			// every chunk not yet attempted.
+			for i := start; i < len(entries); i++ {
+				failed = append(failed, BatchFailure{Index: i, Code: "RequestFailed", Message: err.Error()})
+			}
+			break
+		}
+		for _, f := range out.Failed {
+			idx, convErr := strconv.Atoi(deref(f.Id))
+			if convErr != nil {
+				return fmt.Errorf("sqs batch: unexpected entry id %q", deref(f.Id))
+			}
+			failed = append(failed, BatchFailure{
+				Index:       idx,
+				Code:        deref(f.Code),
+				Message:     deref(f.Message),
+				SenderFault: f.SenderFault,
+			})
+		}
+	}
+	if len(failed) > 0 {
+		return &BatchError{Failed: failed}
+	}
+	return nil
+}
+
+// Dequeue receives up to max messages with their bodies decoded into
+// frontier.MapsMessage or frontier.WebMessage. Unless KeepUndecodable is set,
+// messages that cannot be decoded are moved to the DLQ when one is configured
+// and otherwise left for the queue's redrive policy.
+func (q *SQSQueue) Dequeue(ctx context.Context, max int) ([]*Delivery, error) {
+	if max > MaxBatch {
+		return nil, ErrBatchTooLarge
+	}
+	if max <= 0 {
+		max = 1
+	}
+	in := &sqs.ReceiveMessageInput{
+		QueueUrl:              &q.opts.QueueURL,
+		MaxNumberOfMessages:   int32(max),
+		AttributeNames:        []types.QueueAttributeName{types.QueueAttributeNameAll},
+		MessageAttributeNames: []string{"All"},
+		WaitTimeSeconds:       int32(q.opts.WaitTime / time.Second),
+		VisibilityTimeout:     int32(q.opts.VisibilityTimeout / time.Second),
+	}
+	out, err := q.client.ReceiveMessage(ctx, in)
+	if err != nil {
+		return nil, fmt.Errorf("sqs receive: %w", err)
+	}
+
+	deliveries := make([]*Delivery, 0, len(out.Messages))
+	for _, m := range out.Messages {
+		body := []byte(deref(m.Body))
+		msg, err := frontier.Decode(body)
+		if err != nil && !q.opts.KeepUndecodable {
+			if q.opts.DLQURL != "" {
+				if dlqErr := q.moveToDLQ(ctx, m, err.Error()); dlqErr != nil {
+					return nil, dlqErr
+				}
+			}
+			continue
+		}
+		deliveries = append(deliveries, &Delivery{
+			ID:            deref(m.MessageId),
+			ReceiptHandle: deref(m.ReceiptHandle),
+			Message:       msg,
+			DecodeErr:     err,
+			Body:          body,
+			Attributes:    stringAttributes(m.MessageAttributes),
+			ReceiveCount:  atoi(m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)]),
+			EnqueuedAt:    millis(m.Attributes[string(types.MessageSystemAttributeNameSentTimestamp)]),
+			queue:         q,
+		})
+	}
+	return deliveries, nil
+}
+
+// Ack deletes a received message.
+func (q *SQSQueue) Ack(ctx context.Context, receiptHandle string) error {
+	_, err := q.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      &q.opts.QueueURL,
+		ReceiptHandle: &receiptHandle,
+	})
+	if err != nil {
+		return fmt.Errorf("sqs delete: %w", err)
+	}
+	return nil
+}
+
+// ChangeVisibility hides an in-flight message for timeout from now; zero makes
+// it visible immediately.
+func (q *SQSQueue) ChangeVisibility(ctx context.Context, receiptHandle string, timeout time.Duration) error {
+	_, err := q.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          &q.opts.QueueURL,
+		ReceiptHandle:     &receiptHandle,
+		VisibilityTimeout: int32(timeout / time.Second),
+	})
+	if err != nil {
+		return fmt.Errorf("sqs change visibility: %w", err)
+	}
+	return nil
+}
+
+// DeadLetter sends payload to the DLQ with the reason in the
+// dead_letter_reason attribute. Payloads need not be frontier messages; the
+// state machine dead-letters whole execution documents.
+func (q *SQSQueue) DeadLetter(ctx context.Context, payload any, reason string) error {
+	if q.opts.DLQURL == "" {
+		return ErrNoDLQ
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("dead letter: %w", err)
+	}
+	_, err = obs.SQSPublishWithCorrelationID(ctx, q.client, q.opts.DLQURL, string(body), reasonAttribute(reason))
+	if err != nil {
+		return fmt.Errorf("sqs send to dlq: %w", err)
+	}
+	return nil
+}
+
+// moveToDLQ copies a received message to the DLQ, keeping its attributes, and
+// deletes it from the frontier.
+func (q *SQSQueue) moveToDLQ(ctx context.Context, m types.Message, reason string) error {
+	attrs := reasonAttribute(reason)
+	for k, v := range m.MessageAttributes {
+		if k != DeadLetterReasonAttribute {
+			attrs[k] = v
+		}
+	}
+	_, err := q.client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:          &q.opts.DLQURL,
+		MessageBody:       m.Body,
+		MessageAttributes: attrs,
+	})
+	if err != nil {
+		return fmt.Errorf("sqs send to dlq: %w", err)
+	}
+	return q.Ack(ctx, deref(m.ReceiptHandle))
+}
+
+// encodeFrontier prepares a frontier message and returns its JSON body and
+// the correlation_id the body carries, which correlationID only fills in.
+func encodeFrontier(m frontier.Message, correlationID string) (body, id string, err error) {
+	msg, err := prepare(m, correlationID)
+	if err != nil {
+		return "", "", err
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return "", "", err
+	}
+	return string(data), msg.Header().CorrelationID, nil
+}
+
+func reasonAttribute(reason string) map[string]types.MessageAttributeValue {
+	dataType := "String"
+	return map[string]types.MessageAttributeValue{
+		DeadLetterReasonAttribute: {DataType: &dataType, StringValue: &reason},
+	}
+}
+
+func stringAttributes(in map[string]types.MessageAttributeValue) map[string]string {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		if v.StringValue != nil {
+			out[k] = *v.StringValue
+		}
+	}
+	return out
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func millis(s string) time.Time {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
 }
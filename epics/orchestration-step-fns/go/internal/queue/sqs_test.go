@@ -1,7 +1,334 @@
 package queue
 
-import "testing"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
 
-func TestFrontierQueue_Pending(t *testing.T) {
-	t.Skip("Pending: implement SQS-backed client and an in-memory fake for tests.")
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/frontier"
+	obs "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/observability"
+)
+
+type fakeAttr struct {
+	DataType    string
+	StringValue string `json:",omitempty"`
+}
+
+type fakeMessage struct {
+	id, body, receipt string
+	attrs             map[string]fakeAttr
+	receives          int
+	inFlight          bool
+}
+
+// fakeSQS speaks enough of the SQS JSON protocol (X-Amz-Target: AmazonSQS.*)
+// for the SDK client. Queues are keyed by URL.
+type fakeSQS struct {
+	mu     sync.Mutex
+	seq    int
+	queues map[string][]*fakeMessage
+	// rejectBody makes SendMessageBatch fail entries whose body contains it.
+	rejectBody string
+}
+
+func (f *fakeSQS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var req struct {
+		QueueUrl            string
+		MessageBody         string
+		MessageAttributes   map[string]fakeAttr
+		MaxNumberOfMessages int
+		ReceiptHandle       string
+		Entries             []struct {
+			Id                string
+			MessageBody       string
+			MessageAttributes map[string]fakeAttr
+		}
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var resp any
+	switch strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonSQS.") {
+	case "SendMessage":
+		resp = map[string]string{"MessageId": f.push(req.QueueUrl, req.MessageBody, req.MessageAttributes)}
+	case "SendMessageBatch":
+		var ok, failed []map[string]any
+		for _, e := range req.Entries {
+			if f.rejectBody != "" && strings.Contains(e.MessageBody, f.rejectBody) {
+				failed = append(failed, map[string]any{"Id": e.Id, "Code": "InvalidMessageContents", "Message": "rejected", "SenderFault": true})
+				continue
+			}
+			ok = append(ok, map[string]any{"Id": e.Id, "MessageId": f.push(req.QueueUrl, e.MessageBody, e.MessageAttributes)})
+		}
+		resp = map[string]any{"Successful": ok, "Failed": failed}
+	case "ReceiveMessage":
+		var out []map[string]any
+		for _, m := range f.queues[req.QueueUrl] {
+			if len(out) == req.MaxNumberOfMessages {
+				break
+			}
+			if m.inFlight {
+				continue
+			}
+			m.inFlight = true
+			m.receives++
+			m.receipt = fmt.Sprintf("%s#%d", m.id, m.receives)
+			out = append(out, map[string]any{
+				"MessageId":         m.id,
+				"ReceiptHandle":     m.receipt,
+				"Body":              m.body,
+				"MessageAttributes": m.attrs,
+				"Attributes": map[string]string{
+					"ApproximateReceiveCount": strconv.Itoa(m.receives),
+					"SentTimestamp":           "1700000000000",
+				},
+			})
+		}
+		resp = map[string]any{"Messages": out}
+	case "DeleteMessage":
+		msgs := f.queues[req.QueueUrl]
+		for i, m := range msgs {
+			if m.receipt == req.ReceiptHandle {
+				f.queues[req.QueueUrl] = append(msgs[:i], msgs[i+1:]...)
+				break
+			}
+		}
+		resp = map[string]any{}
+	case "ChangeMessageVisibility":
+		for _, m := range f.queues[req.QueueUrl] {
+			if m.receipt == req.ReceiptHandle {
+				m.inFlight = false
+			}
+		}
+		resp = map[string]any{}
+	default:
+		http.Error(w, "unsupported target", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (f *fakeSQS) push(url, body string, attrs map[string]fakeAttr) string {
+	f.seq++
+	id := fmt.Sprintf("m-%d", f.seq)
+	f.queues[url] = append(f.queues[url], &fakeMessage{id: id, body: body, attrs: attrs})
+	return id
+}
+
+func (f *fakeSQS) bodies(url string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []string
+	for _, m := range f.queues[url] {
+		out = append(out, m.body)
+	}
+	return out
+}
+
+const (
+	frontierURL = "http://sqs.local/000000000000/frontier"
+	dlqURL      = "http://sqs.local/000000000000/frontier-dlq"
+)
+
+func newTestSQSQueue(t *testing.T) (*SQSQueue, *fakeSQS) {
+	fake := &fakeSQS{queues: make(map[string][]*fakeMessage)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	client := sqs.New(sqs.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "test", SecretAccessKey: "test"}, nil
+		}),
+	})
+	return NewSQSQueue(client, SQSOptions{QueueURL: frontierURL, DLQURL: dlqURL}), fake
+}
+
+func mapsMessage(city string) frontier.MapsMessage {
+	return frontier.MapsMessage{Envelope: frontier.NewEnvelope("maps", city, ""), Lat: 55.95, Lng: -3.19, Rad: 500}
+}
+
+func TestSQSQueue_EnqueueDequeueTyped(t *testing.T) {
+	q, _ := newTestSQSQueue(t)
+	ctx := obs.WithCorrelationID(context.Background(), "5d2c8e3a-1b4f-4c6d-8e9f-0a1b2c3d4e51")
+
+	require.NoError(t, q.Enqueue(ctx, mapsMessage("Edinburgh")))
+	web := &frontier.WebMessage{Envelope: frontier.NewEnvelope("web", "Edinburgh", "5d2c8e3a-1b4f-4c6d-8e9f-0a1b2c3d4e52"), SourceURL: "https://example.com", SourceName: "Example", SourceType: "html"}
+	require.NoError(t, q.Enqueue(ctx, web))
+
+	msgs, err := q.Dequeue(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+
+	m, ok := msgs[0].Message.(frontier.MapsMessage)
+	require.True(t, ok, "got %T", msgs[0].Message)
+	require.Equal(t, "5d2c8e3a-1b4f-4c6d-8e9f-0a1b2c3d4e51", m.CorrelationID, "empty correlation_id is filled from ctx")
+	require.Equal(t, int64(500), m.Rad)
+	require.Equal(t, "5d2c8e3a-1b4f-4c6d-8e9f-0a1b2c3d4e51", msgs[0].Attributes[obs.CorrelationIDAttribute])
+	require.Equal(t, 1, msgs[0].ReceiveCount)
+	require.Equal(t, time.UnixMilli(1700000000000), msgs[0].EnqueuedAt)
+
+	w, ok := msgs[1].Message.(frontier.WebMessage)
+	require.True(t, ok, "got %T", msgs[1].Message)
+	require.Equal(t, "5d2c8e3a-1b4f-4c6d-8e9f-0a1b2c3d4e52", w.CorrelationID)
+	require.Equal(t, "5d2c8e3a-1b4f-4c6d-8e9f-0a1b2c3d4e52", msgs[1].Attributes[obs.CorrelationIDAttribute], "attribute follows the body, not ctx")
+
+	require.NoError(t, msgs[0].Ack(ctx))
+	require.NoError(t, msgs[1].Nack(ctx, 0))
+	again, err := q.Dequeue(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	require.Equal(t, 2, again[0].ReceiveCount)
+}
+
+// otherMessage satisfies frontier.Message but is not a type SQSQueue can send.
+type otherMessage struct{ frontier.Envelope }
+
+func (otherMessage) Validate() error { return nil }
+
+func TestSQSQueue_EnqueueRejectsInvalid(t *testing.T) {
+	q, fake := newTestSQSQueue(t)
+	ctx := context.Background()
+
+	bad := mapsMessage("Edinburgh")
+	bad.Rad = 0
+	require.ErrorContains(t, q.Enqueue(ctx, bad), "radius: must be >= 50")
+	require.ErrorIs(t, q.Enqueue(ctx, otherMessage{}), ErrUnsupportedPayload)
+	require.Empty(t, fake.bodies(frontierURL))
+
+	_, err := q.Dequeue(ctx, 11)
+	require.ErrorIs(t, err, ErrBatchTooLarge)
+}
+
+func TestSQSQueue_EnqueueBatchPartialFailure(t *testing.T) {
+	q, fake := newTestSQSQueue(t)
+	fake.rejectBody = "Glasgow"
+	ctx := obs.WithCorrelationID(context.Background(), "5d2c8e3a-1b4f-4c6d-8e9f-0a1b2c3d4e53")
+
+	var batch []frontier.Message
+	for i := 0; i < 12; i++ {
+		city := "Edinburgh"
+		if i == 3 || i == 11 {
+			city = "Glasgow"
+		}
+		batch = append(batch, mapsMessage(city))
+	}
+	err := q.EnqueueBatch(ctx, batch)
+
+	var batchErr *BatchError
+	require.ErrorAs(t, err, &batchErr)
+	require.Len(t, batchErr.Failed, 2)
+	require.Equal(t, 3, batchErr.Failed[0].Index)
+	require.Equal(t, 11, batchErr.Failed[1].Index)
+	require.Equal(t, "InvalidMessageContents", batchErr.Failed[1].Code)
+	require.True(t, batchErr.Failed[1].SenderFault)
+	require.Len(t, fake.bodies(frontierURL), 10)
+
+	invalid := mapsMessage("Edinburgh")
+	invalid.Type = ""
+	require.ErrorContains(t, q.EnqueueBatch(ctx, []frontier.Message{mapsMessage("Edinburgh"), invalid}), "message 1")
+	require.Len(t, fake.bodies(frontierURL), 10, "nothing is sent when any payload is invalid")
+}
+
+func TestSQSQueue_EnqueueBatchKeepsMessageCorrelationIDs(t *testing.T) {
+	q, _ := newTestSQSQueue(t)
+	ctx := obs.WithCorrelationID(context.Background(), "5d2c8e3a-1b4f-4c6d-8e9f-0a1b2c3d4e53")
+	own := mapsMessage("Edinburgh")
+	own.CorrelationID = "5d2c8e3a-1b4f-4c6d-8e9f-0a1b2c3d4e54"
+	require.NoError(t, q.EnqueueBatch(ctx, []frontier.Message{own, mapsMessage("Edinburgh")}))
+
+	msgs, err := q.Dequeue(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	for i, want := range []string{"5d2c8e3a-1b4f-4c6d-8e9f-0a1b2c3d4e54", "5d2c8e3a-1b4f-4c6d-8e9f-0a1b2c3d4e53"} {
+		require.Equal(t, want, msgs[i].Message.Header().CorrelationID, "body %d", i)
+		require.Equal(t, want, msgs[i].Attributes[obs.CorrelationIDAttribute], "attribute %d", i)
+	}
+}
+
+func TestSQSQueue_DeadLetter(t *testing.T) {
+	q, fake := newTestSQSQueue(t)
+	ctx := obs.WithCorrelationID(context.Background(), "5d2c8e3a-1b4f-4c6d-8e9f-0a1b2c3d4e54")
+
+	require.NoError(t, q.DeadLetter(ctx, map[string]any{"city": "Edinburgh"}, "Rank: rank failed"))
+
+	fake.mu.Lock()
+	dead := fake.queues[dlqURL]
+	fake.mu.Unlock()
+	require.Len(t, dead, 1)
+	require.JSONEq(t, `{"city":"Edinburgh"}`, dead[0].body)
+	require.Equal(t, "Rank: rank failed", dead[0].attrs[DeadLetterReasonAttribute].StringValue)
+	require.Equal(t, "5d2c8e3a-1b4f-4c6d-8e9f-0a1b2c3d4e54", dead[0].attrs[obs.CorrelationIDAttribute].StringValue)
+
+	noDLQ := NewSQSQueue(q.client, SQSOptions{QueueURL: frontierURL})
+	require.ErrorIs(t, noDLQ.DeadLetter(ctx, "x", "reason"), ErrNoDLQ)
+}
+
+func TestSQSQueue_UndecodableMovesToDLQ(t *testing.T) {
+	q, fake := newTestSQSQueue(t)
+	ctx := context.Background()
+
+	fake.mu.Lock()
+	fake.push(frontierURL, `{"type":"carrier-pigeon"}`, nil)
+	fake.mu.Unlock()
+	require.NoError(t, q.Enqueue(ctx, mapsMessage("Edinburgh")))
+
+	msgs, err := q.Dequeue(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.IsType(t, frontier.MapsMessage{}, msgs[0].Message)
+
+	fake.mu.Lock()
+	dead := fake.queues[dlqURL]
+	fake.mu.Unlock()
+	require.Len(t, dead, 1)
+	require.Contains(t, dead[0].attrs[DeadLetterReasonAttribute].StringValue, frontier.ErrUnknownType.Error())
+	require.Len(t, fake.bodies(frontierURL), 1, "only the decodable message remains in flight")
+}
+
+func TestSQSQueue_KeepUndecodable(t *testing.T) {
+	q, fake := newTestSQSQueue(t)
+	ctx := context.Background()
+	dlq := NewSQSQueue(q.client, SQSOptions{QueueURL: dlqURL, KeepUndecodable: true})
+
+	require.NoError(t, q.DeadLetter(ctx, map[string]any{"city": "Edinburgh"}, "Rank: rank failed"))
+	got, err := dlq.Dequeue(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Nil(t, got[0].Message)
+	require.ErrorIs(t, got[0].DecodeErr, frontier.ErrUnknownType)
+	require.Equal(t, "Rank: rank failed", got[0].Attributes[DeadLetterReasonAttribute])
+
+	require.NoError(t, got[0].Ack(ctx))
+	require.Empty(t, fake.bodies(dlqURL))
+}
+
+func TestSQSQueue_EnqueueRawKeepsBody(t *testing.T) {
+	q, fake := newTestSQSQueue(t)
+	ctx := context.Background()
+	body := `{"type":"maps","city":"Edinburgh","lat":55.95,"lng":-3.19,"radius":500,"correlation_id":"5d2c8e3a-1b4f-4c6d-8e9f-0a1b2c3d4e51","priority":"low","search_type":"nearby"}`
+
+	require.NoError(t, q.EnqueueRaw(obs.WithCorrelationID(ctx, "5d2c8e3a-1b4f-4c6d-8e9f-0a1b2c3d4e59"), []byte(body)))
+	require.Equal(t, []string{body}, fake.bodies(frontierURL))
+	msgs, err := q.Dequeue(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, "5d2c8e3a-1b4f-4c6d-8e9f-0a1b2c3d4e51", msgs[0].Attributes[obs.CorrelationIDAttribute])
+	require.ErrorContains(t, q.EnqueueRaw(ctx, []byte(`{"type":"maps","city":"Edinburgh"}`)), "invalid frontier message")
 }
@@ -0,0 +1,487 @@
+// Package schema validates documents against the pipeline contracts in
+// schemas/*.json. The schemas are embedded so every binary enforces the same
+// contract; schemas/ here must stay identical to the repository root copy.
+//
+// Only the JSON Schema draft-07 keywords the contracts use are supported;
+// a schema using any other keyword fails to compile rather than being
+// silently under-enforced.
+package schema
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Names of the embedded schemas, the file names without ".json".
+const (
+	FrontierWeb        = "frontier.web"
+	FrontierMaps       = "frontier.maps"
+	CanonicalCandidate = "canonical.candidate"
+	ExtractionWeb      = "extraction.web"
+)
+
+//go:embed schemas/*.json
+var files embed.FS
+
+// compiled holds every embedded schema by name. A schema that does not
+// compile is a build defect, caught by this package's tests.
+var compiled = mustCompileAll()
+
+// Names lists the embedded schemas, sorted.
+func Names() []string {
+	names := make([]string, 0, len(compiled))
+	for name := range compiled {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Source returns the embedded JSON of the named schema.
+func Source(name string) ([]byte, error) {
+	return files.ReadFile("schemas/" + name + ".json")
+}
+
+// Issue is one violation, located by the dotted path of the offending field
+// ("metadata.search_depth", "place_types[2]"); the document root is "".
+type Issue struct {
+	Path string
+	Msg  string
+}
+
+func (i Issue) String() string {
+	if i.Path == "" {
+		return "(root): " + i.Msg
+	}
+	return i.Path + ": " + i.Msg
+}
+
+// ValidationError carries every Issue found in one document.
+type ValidationError struct {
+	Schema string
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Issues))
+	for i, is := range e.Issues {
+		parts[i] = is.String()
+	}
+	return fmt.Sprintf("%s: %d schema issue(s): %s", e.Schema, len(e.Issues), strings.Join(parts, "; "))
+}
+
+// Validate checks the JSON document doc against the named schema. Violations
+// are returned as a *ValidationError.
+func Validate(name string, doc []byte) error {
+	s, ok := compiled[name]
+	if !ok {
+		return fmt.Errorf("unknown schema %q", name)
+	}
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	var issues []Issue
+	s.validate(v, "", &issues)
+	if len(issues) > 0 {
+		return &ValidationError{Schema: name, Issues: issues}
+	}
+	return nil
+}
+
+// ValidateValue checks the JSON encoding of v against the named schema.
+func ValidateValue(name string, v any) error {
+	doc, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return Validate(name, doc)
+}
+
+// node is one compiled (sub)schema.
+type node struct {
+	types      []string
+	constant   any
+	hasConst   bool
+	enum       []any
+	required   []string
+	properties map[string]*node
+	// additional validates properties not in properties; nil allows any,
+	// and closed forbids them outright.
+	additional *node
+	closed     bool
+	items      *node
+
+	minimum, maximum                   *float64
+	exclusiveMinimum, exclusiveMaximum *float64
+	minLength, maxLength               *int
+	minItems, maxItems                 *int
+	uniqueItems                        bool
+	pattern                            *regexp.Regexp
+}
+
+// annotations are keywords that do not constrain a document.
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true,
+	"title": true, "description": true, "default": true, "examples": true,
+}
+
+func mustCompileAll() map[string]*node {
+	out := make(map[string]*node)
+	paths, err := fs.Glob(files, "schemas/*.json")
+	if err != nil {
+		panic(err)
+	}
+	for _, p := range paths {
+		data, err := files.ReadFile(p)
+		if err != nil {
+			panic(err)
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(p, "schemas/"), ".json")
+		s, err := compile(data)
+		if err != nil {
+			panic(fmt.Sprintf("schema %s: %v", name, err))
+		}
+		out[name] = s
+	}
+	return out
+}
+
+func compile(data []byte) (*node, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	return compileNode(raw, "")
+}
+
+func compileNode(raw map[string]any, at string) (*node, error) {
+	n := &node{}
+	for _, kw := range sortedKeys(raw) {
+		v := raw[kw]
+		var err error
+		switch kw {
+		case "type":
+			switch t := v.(type) {
+			case string:
+				n.types = []string{t}
+			case []any:
+				for _, x := range t {
+					s, ok := x.(string)
+					if !ok {
+						return nil, fmt.Errorf("%stype: expected strings", at)
+					}
+					n.types = append(n.types, s)
+				}
+			default:
+				return nil, fmt.Errorf("%stype: expected a string or array", at)
+			}
+		case "const":
+			n.constant, n.hasConst = v, true
+		case "enum":
+			vals, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%senum: expected an array", at)
+			}
+			n.enum = vals
+		case "required":
+			vals, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%srequired: expected an array", at)
+			}
+			for _, x := range vals {
+				s, ok := x.(string)
+				if !ok {
+					return nil, fmt.Errorf("%srequired: expected strings", at)
+				}
+				n.required = append(n.required, s)
+			}
+		case "properties":
+			props, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%sproperties: expected an object", at)
+			}
+			n.properties = make(map[string]*node, len(props))
+			for name, p := range props {
+				if n.properties[name], err = subschema(p, at+"properties."+name+"."); err != nil {
+					return nil, err
+				}
+			}
+		case "additionalProperties":
+			if allow, ok := v.(bool); ok {
+				n.closed = !allow
+				break
+			}
+			n.additional, err = subschema(v, at+"additionalProperties.")
+		case "items":
+			n.items, err = subschema(v, at+"items.")
+		case "minimum":
+			n.minimum, err = number(v, at+kw)
+		case "maximum":
+			n.maximum, err = number(v, at+kw)
+		case "exclusiveMinimum":
+			n.exclusiveMinimum, err = number(v, at+kw)
+		case "exclusiveMaximum":
+			n.exclusiveMaximum, err = number(v, at+kw)
+		case "minLength":
+			n.minLength, err = count(v, at+kw)
+		case "maxLength":
+			n.maxLength, err = count(v, at+kw)
+		case "minItems":
+			n.minItems, err = count(v, at+kw)
+		case "maxItems":
+			n.maxItems, err = count(v, at+kw)
+		case "uniqueItems":
+			n.uniqueItems, _ = v.(bool)
+		case "pattern":
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%spattern: expected a string", at)
+			}
+			if n.pattern, err = regexp.Compile(s); err != nil {
+				return nil, fmt.Errorf("%spattern: %w", at, err)
+			}
+		default:
+			if !annotations[kw] {
+				return nil, fmt.Errorf("%s%s: unsupported keyword", at, kw)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func subschema(v any, at string) (*node, error) {
+	raw, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a schema object", strings.TrimSuffix(at, "."))
+	}
+	return compileNode(raw, at)
+}
+
+func number(v any, at string) (*float64, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a number", at)
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", at, err)
+	}
+	return &f, nil
+}
+
+func count(v any, at string) (*int, error) {
+	f, err := number(v, at)
+	if err != nil {
+		return nil, err
+	}
+	if *f < 0 || *f != math.Trunc(*f) {
+		return nil, fmt.Errorf("%s: expected a non-negative integer", at)
+	}
+	c := int(*f)
+	return &c, nil
+}
+
+func (n *node) validate(v any, path string, issues *[]Issue) {
+	report := func(format string, args ...any) {
+		*issues = append(*issues, Issue{Path: path, Msg: fmt.Sprintf(format, args...)})
+	}
+	if len(n.types) > 0 && !n.typeOK(v) {
+		report("must be %s, got %s", strings.Join(n.types, " or "), typeOf(v))
+		return
+	}
+	if n.hasConst && !equal(v, n.constant) {
+		report("must equal %s", jsonText(n.constant))
+	}
+	if n.enum != nil && !n.inEnum(v) {
+		report("must be one of %s", jsonText(n.enum))
+	}
+
+	switch x := v.(type) {
+	case json.Number:
+		f, _ := x.Float64()
+		switch {
+		case n.minimum != nil && f < *n.minimum:
+			report("must be >= %v", *n.minimum)
+		case n.exclusiveMinimum != nil && f <= *n.exclusiveMinimum:
+			report("must be > %v", *n.exclusiveMinimum)
+		}
+		switch {
+		case n.maximum != nil && f > *n.maximum:
+			report("must be <= %v", *n.maximum)
+		case n.exclusiveMaximum != nil && f >= *n.exclusiveMaximum:
+			report("must be < %v", *n.exclusiveMaximum)
+		}
+	case string:
+		l := utf8.RuneCountInString(x)
+		if n.minLength != nil && l < *n.minLength {
+			report("must be at least %d character(s)", *n.minLength)
+		}
+		if n.maxLength != nil && l > *n.maxLength {
+			report("must be at most %d character(s)", *n.maxLength)
+		}
+		if n.pattern != nil && !n.pattern.MatchString(x) {
+			report("must match %s", n.pattern)
+		}
+	case []any:
+		if n.minItems != nil && len(x) < *n.minItems {
+			report("must have at least %d item(s)", *n.minItems)
+		}
+		if n.maxItems != nil && len(x) > *n.maxItems {
+			report("must have at most %d item(s)", *n.maxItems)
+		}
+		if n.uniqueItems {
+			for i := range x {
+				for j := i + 1; j < len(x); j++ {
+					if equal(x[i], x[j]) {
+						report("items %d and %d must be unique", i, j)
+					}
+				}
+			}
+		}
+		if n.items != nil {
+			for i, item := range x {
+				n.items.validate(item, fmt.Sprintf("%s[%d]", path, i), issues)
+			}
+		}
+	case map[string]any:
+		for _, name := range n.required {
+			if _, ok := x[name]; !ok {
+				*issues = append(*issues, Issue{Path: join(path, name), Msg: "is required"})
+			}
+		}
+		for _, name := range sortedKeys(x) {
+			child := join(path, name)
+			switch p, ok := n.properties[name]; {
+			case ok:
+				p.validate(x[name], child, issues)
+			case n.additional != nil:
+				n.additional.validate(x[name], child, issues)
+			case n.closed:
+				*issues = append(*issues, Issue{Path: child, Msg: "is not allowed"})
+			}
+		}
+	}
+}
+
+func (n *node) typeOK(v any) bool {
+	for _, t := range n.types {
+		switch t {
+		case "integer":
+			if x, ok := v.(json.Number); ok {
+				if f, err := x.Float64(); err == nil && f == math.Trunc(f) {
+					return true
+				}
+			}
+		case typeOf(v):
+			return true
+		}
+	}
+	return false
+}
+
+func (n *node) inEnum(v any) bool {
+	for _, e := range n.enum {
+		if equal(v, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// equal compares decoded JSON values, numbers by value (1 equals 1.0).
+func equal(a, b any) bool {
+	an, aok := a.(json.Number)
+	bn, bok := b.(json.Number)
+	if aok || bok {
+		if !aok || !bok {
+			return false
+		}
+		af, aerr := an.Float64()
+		bf, berr := bn.Float64()
+		return aerr == nil && berr == nil && af == bf
+	}
+	switch x := a.(type) {
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, xv := range x {
+			if yv, ok := y[k]; !ok || !equal(xv, yv) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func jsonText(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
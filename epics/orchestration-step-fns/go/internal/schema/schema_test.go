@@ -0,0 +1,137 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// repoRoot is the repository root, which holds the canonical schemas/ and
+// examples/.
+var repoRoot = filepath.Join("..", "..", "..", "..", "..")
+
+func TestEmbeddedSchemasMatchRepo(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(repoRoot, "schemas", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != len(Names()) {
+		t.Fatalf("repo has %d schemas, embedded %v", len(paths), Names())
+	}
+	for _, p := range paths {
+		want, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Source(strings.TrimSuffix(filepath.Base(p), ".json"))
+		if err != nil {
+			t.Fatalf("%s not embedded: %v", p, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("internal/schema/schemas/%s differs from %s; copy it over", filepath.Base(p), p)
+		}
+	}
+}
+
+// TestExamples validates every examples/<dir>/<name>.example.json against
+// schemas/<dir>.<name>.json, as the validate-schemas workflow does.
+func TestExamples(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(repoRoot, "examples", "*", "*.example.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no examples found")
+	}
+	for _, p := range paths {
+		name := filepath.Base(filepath.Dir(p)) + "." + strings.TrimSuffix(filepath.Base(p), ".example.json")
+		doc, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Validate(name, doc); err != nil {
+			t.Errorf("%s: %v", p, err)
+		}
+	}
+}
+
+func issues(t *testing.T, name, doc string) []string {
+	t.Helper()
+	err := Validate(name, []byte(doc))
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Validate(%s) = %v, want a *ValidationError", name, err)
+	}
+	out := make([]string, len(verr.Issues))
+	for i, is := range verr.Issues {
+		out[i] = is.String()
+	}
+	return out
+}
+
+func TestValidateReportsFieldPaths(t *testing.T) {
+	got := issues(t, FrontierWeb, `{
+		"type": "web",
+		"city": "",
+		"source_url": "https://example.com",
+		"source_type": "blog",
+		"crawl_depth": 6,
+		"correlation_id": "corr-1",
+		"timeout_seconds": 30.5,
+		"metadata": {"domain_authority": "net", "owner": "x"},
+		"extra": true
+	}`)
+	want := []string{
+		`source_name: is required`,
+		`city: must be at least 1 character(s)`,
+		`correlation_id: must match ^[a-f0-9]{8}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{12}$`,
+		`crawl_depth: must be <= 5`,
+		`extra: is not allowed`,
+		`metadata.domain_authority: must be one of ["gov","edu","org","com","unknown"]`,
+		`metadata.owner: is not allowed`,
+		`source_type: must be one of ["html","api","json","xml","csv","pdf"]`,
+		`timeout_seconds: must be integer, got number`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestValidateArraysAndConst(t *testing.T) {
+	got := issues(t, FrontierMaps, `{
+		"type": "web",
+		"city": "Edinburgh",
+		"lat": 91,
+		"lng": -3.19,
+		"radius": 1000.0,
+		"correlation_id": "f47ac10b-58cc-4372-a567-0e02b2c3d480",
+		"place_types": ["museum", 7]
+	}`)
+	want := []string{
+		`lat: must be <= 90`,
+		`place_types[1]: must be string, got number`,
+		`type: must equal "maps"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if got := issues(t, FrontierMaps, `[]`); len(got) != 1 || got[0] != "(root): must be object, got array" {
+		t.Fatalf("root issues = %v", got)
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	if err := Validate("frontier.nope", []byte(`{}`)); err == nil {
+		t.Fatal("expected unknown schema to fail")
+	}
+	if err := Validate(FrontierWeb, []byte(`{`)); err == nil {
+		t.Fatal("expected malformed JSON to fail")
+	}
+	if _, err := compile([]byte(`{"type": "object", "oneOf": []}`)); err == nil || !strings.Contains(err.Error(), "oneOf: unsupported keyword") {
+		t.Fatalf("compile with unsupported keyword = %v", err)
+	}
+}
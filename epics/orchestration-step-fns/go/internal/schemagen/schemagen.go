@@ -0,0 +1,294 @@
+// Package schemagen generates Go structs from the JSON schemas in schemas/.
+// cmd/schemagen wraps it for go:generate; see internal/types.
+//
+// Each schema becomes a struct named after its file ("frontier.maps" ->
+// FrontierMaps), and each nested object with properties a struct named by its
+// path (FrontierMapsMetadata). Required fields are values; optional scalars
+// and objects are pointers, so an absent field and a zero value stay distinct
+// on a round trip. Optional arrays and maps are omitted when empty.
+package schemagen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+// Input is one schema document and the name its types are derived from, the
+// file name without ".json".
+type Input struct {
+	Name string
+	Data []byte
+}
+
+// Generate returns gofmt'd Go source declaring the types of every input in
+// package pkg. Inputs are emitted sorted by name, so output is stable.
+func Generate(pkg string, inputs []Input) ([]byte, error) {
+	sorted := append([]Input(nil), inputs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	g := &generator{}
+	fmt.Fprintf(&g.buf, "// Code generated by schemagen from schemas/*.json. DO NOT EDIT.\n\npackage %s\n", pkg)
+	for _, in := range sorted {
+		var root node
+		if err := json.Unmarshal(in.Data, &root); err != nil {
+			return nil, fmt.Errorf("%s: %w", in.Name, err)
+		}
+		if root.typeName() != "object" || len(root.Properties.keys) == 0 {
+			return nil, fmt.Errorf("%s: root must be an object with properties", in.Name)
+		}
+		g.queue = append(g.queue, pending{name: GoName(in.Name), schema: in.Name, n: &root})
+		for len(g.queue) > 0 {
+			p := g.queue[0]
+			g.queue = g.queue[1:]
+			if err := g.emitStruct(p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return src, nil
+}
+
+// node is the subset of a JSON schema the generator reads.
+type node struct {
+	Title                string          `json:"title"`
+	Description          string          `json:"description"`
+	Type                 any             `json:"type"`
+	Required             []string        `json:"required"`
+	Properties           properties      `json:"properties"`
+	AdditionalProperties json.RawMessage `json:"additionalProperties"`
+	Items                *node           `json:"items"`
+	Enum                 []any           `json:"enum"`
+	Const                any             `json:"const"`
+}
+
+func (n *node) typeName() string {
+	s, _ := n.Type.(string)
+	return s
+}
+
+// properties keeps schema properties in file order, so generated fields read
+// like the schema.
+type properties struct {
+	keys []string
+	m    map[string]*node
+}
+
+func (p *properties) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return fmt.Errorf("properties: expected an object")
+	}
+	p.m = make(map[string]*node)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+		var n node
+		if err := dec.Decode(&n); err != nil {
+			return fmt.Errorf("properties.%s: %w", key, err)
+		}
+		p.keys = append(p.keys, key)
+		p.m[key] = &n
+	}
+	return nil
+}
+
+// pending is a struct still to emit: the object at path ("" for the root)
+// in schema.
+type pending struct {
+	name, schema, path string
+	n                  *node
+}
+
+type generator struct {
+	buf   bytes.Buffer
+	queue []pending
+}
+
+func (g *generator) emitStruct(p pending) error {
+	g.buf.WriteString("\n")
+	doc := fmt.Sprintf("%s mirrors schemas/%s.json", p.name, p.schema)
+	if p.path != "" {
+		doc = fmt.Sprintf("%s is %s in schemas/%s.json", p.name, p.path, p.schema)
+	} else if p.n.Title != "" {
+		doc += " (" + p.n.Title + ")"
+	}
+	writeDoc(&g.buf, "", doc+". "+p.n.Description, "")
+	fmt.Fprintf(&g.buf, "type %s struct {\n", p.name)
+	required := make(map[string]bool, len(p.n.Required))
+	for _, r := range p.n.Required {
+		required[r] = true
+	}
+	for _, key := range p.n.Properties.keys {
+		prop := p.n.Properties.m[key]
+		field := GoName(key)
+		typ, err := g.goType(prop, p.name+field, p.schema, join(p.path, key))
+		if err != nil {
+			return err
+		}
+		tag := key
+		if !required[key] {
+			tag += ",omitempty"
+			if !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && typ != "any" {
+				typ = "*" + typ
+			}
+		}
+		writeDoc(&g.buf, "\t", prop.Description, constraints(prop))
+		fmt.Fprintf(&g.buf, "\t%s %s `json:%q`\n", field, typ, tag)
+	}
+	g.buf.WriteString("}\n")
+	return nil
+}
+
+// goType maps n to a Go type, queueing a struct named name for objects with
+// properties.
+func (g *generator) goType(n *node, name, schema, path string) (string, error) {
+	at := schema + ": " + path
+	switch n.Type.(type) {
+	case nil:
+		return "any", nil
+	case string:
+	default:
+		return "", fmt.Errorf("%s: only single-type schemas are supported", at)
+	}
+	switch n.typeName() {
+	case "string":
+		return "string", nil
+	case "integer":
+		return "int64", nil
+	case "number":
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		if n.Items == nil {
+			return "[]any", nil
+		}
+		elem, err := g.goType(n.Items, singular(name), schema, path+"[]")
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+	case "object":
+		if len(n.Properties.keys) > 0 {
+			g.queue = append(g.queue, pending{name: name, schema: schema, path: path, n: n})
+			return name, nil
+		}
+		var extra node
+		if len(n.AdditionalProperties) > 0 && json.Unmarshal(n.AdditionalProperties, &extra) == nil {
+			elem, err := g.goType(&extra, name+"Value", schema, path+".*")
+			if err != nil {
+				return "", err
+			}
+			return "map[string]" + elem, nil
+		}
+		return "map[string]any", nil
+	}
+	return "", fmt.Errorf("%s: unsupported type %q", at, n.typeName())
+}
+
+// constraints documents the values n admits that its Go type cannot express.
+func constraints(n *node) string {
+	if n.Const != nil {
+		return fmt.Sprintf("Always %s.", jsonText(n.Const))
+	}
+	if len(n.Enum) > 0 {
+		vals := make([]string, len(n.Enum))
+		for i, v := range n.Enum {
+			vals[i] = jsonText(v)
+		}
+		return "One of " + strings.Join(vals, ", ") + "."
+	}
+	return ""
+}
+
+func writeDoc(buf *bytes.Buffer, indent, doc, extra string) {
+	doc = strings.TrimSpace(doc)
+	if doc != "" && !strings.HasSuffix(doc, ".") {
+		doc += "."
+	}
+	if extra != "" {
+		doc = strings.TrimSpace(doc + " " + extra)
+	}
+	if doc == "" {
+		return
+	}
+	for _, line := range wrap(doc, 76-len(indent)) {
+		fmt.Fprintf(buf, "%s// %s\n", indent, line)
+	}
+}
+
+func wrap(s string, width int) []string {
+	var lines []string
+	line := ""
+	for _, w := range strings.Fields(s) {
+		if line != "" && len(line)+1+len(w) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += w
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func jsonText(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// initialisms are name parts written in capitals, per Go naming.
+var initialisms = map[string]bool{
+	"api": true, "html": true, "http": true, "id": true, "json": true,
+	"llm": true, "osm": true, "otm": true, "url": true, "usd": true, "uuid": true,
+}
+
+// GoName turns a schema or property name ("frontier.maps", "source_url")
+// into an exported Go identifier (FrontierMaps, SourceURL).
+func GoName(s string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == '_' || r == '.' || r == '-' }) {
+		if initialisms[part] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// singular names the element type of an array type name.
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss"):
+		return strings.TrimSuffix(name, "s")
+	}
+	return name + "Item"
+}
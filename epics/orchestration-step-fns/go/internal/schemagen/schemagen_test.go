@@ -0,0 +1,58 @@
+package schemagen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGoName(t *testing.T) {
+	for in, want := range map[string]string{
+		"frontier.maps":   "FrontierMaps",
+		"source_url":      "SourceURL",
+		"google_place_id": "GooglePlaceID",
+		"h3_cell":         "H3Cell",
+		"osm_type":        "OSMType",
+	} {
+		if got := GoName(in); got != want {
+			t.Errorf("GoName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := Generate("demo", []Input{{Name: "demo.thing", Data: []byte(`{
+		"title": "Thing",
+		"type": "object",
+		"required": ["id", "parts"],
+		"properties": {
+			"id": {"type": "string", "description": "Identifier"},
+			"kind": {"type": "string", "enum": ["a", "b"]},
+			"count": {"type": "integer"},
+			"parts": {"type": "array", "items": {"type": "object", "properties": {"n": {"type": "number"}}}},
+			"scores": {"type": "object", "additionalProperties": {"type": "number"}},
+			"extra": {"type": "object"}
+		}
+	}`)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"// DemoThing mirrors schemas/demo.thing.json (Thing).",
+		"\t// Identifier.\n\tID string `json:\"id\"`",
+		"\t// One of \"a\", \"b\".\n\tKind *string `json:\"kind,omitempty\"`",
+		"Count *int64 `json:\"count,omitempty\"`",
+		"Parts []DemoThingPart `json:\"parts\"`",
+		"Scores map[string]float64 `json:\"scores,omitempty\"`",
+		"Extra map[string]any `json:\"extra,omitempty\"`",
+		"// DemoThingPart is parts[] in schemas/demo.thing.json.\ntype DemoThingPart struct {",
+		"N *float64 `json:\"n,omitempty\"`",
+	} {
+		if !strings.Contains(strings.Join(strings.Fields(string(src)), " "), strings.Join(strings.Fields(want), " ")) {
+			t.Errorf("generated source lacks %q:\n%s", want, src)
+		}
+	}
+
+	if _, err := Generate("demo", []Input{{Name: "bad", Data: []byte(`{"type": "object", "properties": {"x": {"type": ["string", "null"]}}}`)}}); err == nil || !strings.Contains(err.Error(), "bad: x") {
+		t.Fatalf("multi-type property: %v", err)
+	}
+}
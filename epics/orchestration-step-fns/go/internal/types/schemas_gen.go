@@ -0,0 +1,413 @@
+// Code generated by schemagen from schemas/*.json. DO NOT EDIT.
+
+package types
+
+// CanonicalCandidate mirrors schemas/canonical.candidate.json (Canonical
+// Candidate Entity). Schema for normalized place entities in the data scout
+// pipeline.
+type CanonicalCandidate struct {
+	// Primary name of the place.
+	Name string `json:"name"`
+	// Optional latitude coordinate.
+	Lat *float64 `json:"lat,omitempty"`
+	// Optional longitude coordinate.
+	Lng *float64 `json:"lng,omitempty"`
+	// Optional primary category classification.
+	Category *string `json:"category,omitempty"`
+	// Source system identifier. One of "google", "osm", "otm", "wikidata", "web",
+	// "open_data", "tavily".
+	Source string `json:"source"`
+	// Optional URL where this entity was found.
+	SourceURL *string `json:"source_url,omitempty"`
+	// Confidence scores for different aspects of the entity.
+	Confidences CanonicalCandidateConfidences `json:"confidences"`
+	// Tracking information for data provenance.
+	Lineage CanonicalCandidateLineage `json:"lineage"`
+	// External system identifiers and references.
+	ExternalRefs CanonicalCandidateExternalRefs `json:"external_refs"`
+	// Source-specific additional data.
+	AdditionalContent CanonicalCandidateAdditionalContent `json:"additional_content"`
+	// Confidence score for coordinate accuracy.
+	CoordinatesConfidence float64 `json:"coordinates_confidence"`
+	// Optional structured address information.
+	Address *CanonicalCandidateAddress `json:"address,omitempty"`
+}
+
+// CanonicalCandidateConfidences is confidences in
+// schemas/canonical.candidate.json. Confidence scores for different aspects of
+// the entity.
+type CanonicalCandidateConfidences struct {
+	// Overall confidence score.
+	Overall float64 `json:"overall"`
+	// Confidence in the name accuracy.
+	Name *float64 `json:"name,omitempty"`
+	// Confidence in the location accuracy.
+	Location *float64 `json:"location,omitempty"`
+	// Confidence in the category classification.
+	Category *float64 `json:"category,omitempty"`
+}
+
+// CanonicalCandidateLineage is lineage in schemas/canonical.candidate.json.
+// Tracking information for data provenance.
+type CanonicalCandidateLineage struct {
+	// When this entity was created.
+	CreatedAt string `json:"created_at"`
+	// When this entity was last updated.
+	UpdatedAt *string `json:"updated_at,omitempty"`
+	// Version of the pipeline that created this entity.
+	PipelineVersion string `json:"pipeline_version"`
+	// UUID for tracking this entity through the pipeline.
+	CorrelationID *string `json:"correlation_id,omitempty"`
+	// History of entity merges for reversibility.
+	MergeHistory []CanonicalCandidateLineageMergeHistoryItem `json:"merge_history,omitempty"`
+}
+
+// CanonicalCandidateExternalRefs is external_refs in
+// schemas/canonical.candidate.json. External system identifiers and
+// references.
+type CanonicalCandidateExternalRefs struct {
+	// Google Places API place ID.
+	GooglePlaceID *string `json:"google_place_id,omitempty"`
+	// OpenStreetMap identifier.
+	OSMID *string `json:"osm_id,omitempty"`
+	// OpenStreetMap object type. One of "node", "way", "relation".
+	OSMType *string `json:"osm_type,omitempty"`
+	// OpenTripMap identifier.
+	OTMID *string `json:"otm_id,omitempty"`
+	// Wikidata entity ID.
+	WikidataID *string `json:"wikidata_id,omitempty"`
+	// Wikipedia page URL.
+	WikipediaURL *string `json:"wikipedia_url,omitempty"`
+	// Historical Marker Database ID.
+	HmdbID *string `json:"hmdb_id,omitempty"`
+	// Atlas URL reference.
+	AtlasURL *string `json:"atlas_url,omitempty"`
+	// City portal URL reference.
+	CityPortalURL *string `json:"city_portal_url,omitempty"`
+}
+
+// CanonicalCandidateAdditionalContent is additional_content in
+// schemas/canonical.candidate.json. Source-specific additional data.
+type CanonicalCandidateAdditionalContent struct {
+	Google           *CanonicalCandidateAdditionalContentGoogle           `json:"google,omitempty"`
+	Web              *CanonicalCandidateAdditionalContentWeb              `json:"web,omitempty"`
+	Signals          *CanonicalCandidateAdditionalContentSignals          `json:"signals,omitempty"`
+	ScoreBreakdown   *CanonicalCandidateAdditionalContentScoreBreakdown   `json:"score_breakdown,omitempty"`
+	SecondarySignals *CanonicalCandidateAdditionalContentSecondarySignals `json:"secondary_signals,omitempty"`
+}
+
+// CanonicalCandidateAddress is address in schemas/canonical.candidate.json.
+// Optional structured address information.
+type CanonicalCandidateAddress struct {
+	FormattedAddress *string `json:"formatted_address,omitempty"`
+	StreetNumber     *string `json:"street_number,omitempty"`
+	StreetName       *string `json:"street_name,omitempty"`
+	City             *string `json:"city,omitempty"`
+	State            *string `json:"state,omitempty"`
+	Country          *string `json:"country,omitempty"`
+	PostalCode       *string `json:"postal_code,omitempty"`
+}
+
+// CanonicalCandidateLineageMergeHistoryItem is lineage.merge_history[] in
+// schemas/canonical.candidate.json.
+type CanonicalCandidateLineageMergeHistoryItem struct {
+	MergedAt     string `json:"merged_at"`
+	MergedFromID string `json:"merged_from_id"`
+	Reason       string `json:"reason"`
+}
+
+// CanonicalCandidateAdditionalContentGoogle is additional_content.google in
+// schemas/canonical.candidate.json.
+type CanonicalCandidateAdditionalContentGoogle struct {
+	// Google Places API types.
+	Types        []string                                               `json:"types,omitempty"`
+	OpeningHours *CanonicalCandidateAdditionalContentGoogleOpeningHours `json:"opening_hours,omitempty"`
+	Website      *string                                                `json:"website,omitempty"`
+	Phone        *string                                                `json:"phone,omitempty"`
+	Photos       []CanonicalCandidateAdditionalContentGooglePhoto       `json:"photos,omitempty"`
+}
+
+// CanonicalCandidateAdditionalContentWeb is additional_content.web in
+// schemas/canonical.candidate.json.
+type CanonicalCandidateAdditionalContentWeb struct {
+	SourceURL             *string            `json:"source_url,omitempty"`
+	SourceDomain          *string            `json:"source_domain,omitempty"`
+	ExtractionConfidences map[string]float64 `json:"extraction_confidences,omitempty"`
+	TrustScore            *float64           `json:"trust_score,omitempty"`
+}
+
+// CanonicalCandidateAdditionalContentSignals is additional_content.signals in
+// schemas/canonical.candidate.json.
+type CanonicalCandidateAdditionalContentSignals struct {
+	HasWikipedia *bool    `json:"has_wikipedia,omitempty"`
+	NicheSource  *bool    `json:"niche_source,omitempty"`
+	Novelty      *float64 `json:"novelty,omitempty"`
+	PhotoCount   *int64   `json:"photo_count,omitempty"`
+}
+
+// CanonicalCandidateAdditionalContentScoreBreakdown is
+// additional_content.score_breakdown in schemas/canonical.candidate.json.
+type CanonicalCandidateAdditionalContentScoreBreakdown struct {
+	Popularity    *float64 `json:"popularity,omitempty"`
+	Authority     *float64 `json:"authority,omitempty"`
+	GeoCentrality *float64 `json:"geo_centrality,omitempty"`
+	Novelty       *float64 `json:"novelty,omitempty"`
+	GraphContext  *float64 `json:"graph_context,omitempty"`
+}
+
+// CanonicalCandidateAdditionalContentSecondarySignals is
+// additional_content.secondary_signals in schemas/canonical.candidate.json.
+type CanonicalCandidateAdditionalContentSecondarySignals struct {
+	AdjacencyScore  *float64                                                            `json:"adjacency_score,omitempty"`
+	AnchorDistances []CanonicalCandidateAdditionalContentSecondarySignalsAnchorDistance `json:"anchor_distances,omitempty"`
+}
+
+// CanonicalCandidateAdditionalContentGoogleOpeningHours is
+// additional_content.google.opening_hours in schemas/canonical.candidate.json.
+type CanonicalCandidateAdditionalContentGoogleOpeningHours struct {
+	OpenNow     *bool    `json:"open_now,omitempty"`
+	Periods     []any    `json:"periods,omitempty"`
+	WeekdayText []string `json:"weekday_text,omitempty"`
+}
+
+// CanonicalCandidateAdditionalContentGooglePhoto is
+// additional_content.google.photos[] in schemas/canonical.candidate.json.
+type CanonicalCandidateAdditionalContentGooglePhoto struct {
+	PhotoReference   string   `json:"photo_reference"`
+	HTMLAttributions []string `json:"html_attributions"`
+}
+
+// CanonicalCandidateAdditionalContentSecondarySignalsAnchorDistance is
+// additional_content.secondary_signals.anchor_distances[] in
+// schemas/canonical.candidate.json.
+type CanonicalCandidateAdditionalContentSecondarySignalsAnchorDistance struct {
+	AnchorID       string  `json:"anchor_id"`
+	DistanceMeters float64 `json:"distance_meters"`
+}
+
+// ExtractionWeb mirrors schemas/extraction.web.json (Web Extraction Output).
+// Schema for LLM extraction output from web sources.
+type ExtractionWeb struct {
+	// Metadata about the extraction process.
+	ExtractionMetadata ExtractionWebExtractionMetadata `json:"extraction_metadata"`
+	// Array of extracted place entities.
+	Entities []ExtractionWebEntity `json:"entities"`
+}
+
+// ExtractionWebExtractionMetadata is extraction_metadata in
+// schemas/extraction.web.json. Metadata about the extraction process.
+type ExtractionWebExtractionMetadata struct {
+	// UUID for this extraction.
+	ExtractionID string `json:"extraction_id"`
+	// UUID for tracking across pipeline.
+	CorrelationID *string `json:"correlation_id,omitempty"`
+	// URL of the web source that was extracted.
+	SourceURL string `json:"source_url"`
+	// Domain of the source URL.
+	SourceDomain *string `json:"source_domain,omitempty"`
+	// SHA-256 hash of the source content.
+	ContentHash *string `json:"content_hash,omitempty"`
+	// When the extraction was performed.
+	ExtractedAt string `json:"extracted_at"`
+	// Version of the extraction system.
+	ExtractorVersion string                                    `json:"extractor_version"`
+	ModelInfo        *ExtractionWebExtractionMetadataModelInfo `json:"model_info,omitempty"`
+	// Overall extraction confidence scores.
+	Confidences ExtractionWebExtractionMetadataConfidences `json:"confidences"`
+	// Trust score for the source and extraction.
+	TrustScore *float64 `json:"trust_score,omitempty"`
+}
+
+// ExtractionWebEntity is entities[] in schemas/extraction.web.json.
+type ExtractionWebEntity struct {
+	// Name of the extracted place.
+	Name string `json:"name"`
+	// Optional latitude coordinate.
+	Lat *float64 `json:"lat,omitempty"`
+	// Optional longitude coordinate.
+	Lng *float64 `json:"lng,omitempty"`
+	// Optional category classification.
+	Category *string `json:"category,omitempty"`
+	// Source system identifier (always 'web' for web extractions). Always "web".
+	Source string `json:"source"`
+	// URL where this entity was found.
+	SourceURL string `json:"source_url"`
+	// Optional description of the place.
+	Description *string `json:"description,omitempty"`
+	// Optional address information.
+	Address *ExtractionWebEntityAddress `json:"address,omitempty"`
+	// Optional contact information.
+	Contact *ExtractionWebEntityContact `json:"contact,omitempty"`
+	// Confidence scores for this entity.
+	Confidences ExtractionWebEntityConfidences `json:"confidences"`
+	// Trust score for this specific entity.
+	TrustScore *float64 `json:"trust_score,omitempty"`
+	// Confidence in coordinate accuracy.
+	CoordinatesConfidence *float64 `json:"coordinates_confidence,omitempty"`
+	// Context from the extraction process.
+	ExtractionContext *ExtractionWebEntityExtractionContext `json:"extraction_context,omitempty"`
+}
+
+// ExtractionWebExtractionMetadataModelInfo is extraction_metadata.model_info
+// in schemas/extraction.web.json.
+type ExtractionWebExtractionMetadataModelInfo struct {
+	// One of "openai", "anthropic", "bedrock", "gemini".
+	Provider     string   `json:"provider"`
+	Model        string   `json:"model"`
+	TokensUsed   int64    `json:"tokens_used"`
+	CostEstimate *float64 `json:"cost_estimate,omitempty"`
+}
+
+// ExtractionWebExtractionMetadataConfidences is
+// extraction_metadata.confidences in schemas/extraction.web.json. Overall
+// extraction confidence scores.
+type ExtractionWebExtractionMetadataConfidences struct {
+	// Overall extraction confidence.
+	Overall float64 `json:"overall"`
+	// Confidence in entity detection.
+	EntityDetection *float64 `json:"entity_detection,omitempty"`
+	// Confidence in location data extraction.
+	LocationExtraction *float64 `json:"location_extraction,omitempty"`
+	// Confidence in category assignment.
+	Categorization *float64 `json:"categorization,omitempty"`
+}
+
+// ExtractionWebEntityAddress is entities[].address in
+// schemas/extraction.web.json. Optional address information.
+type ExtractionWebEntityAddress struct {
+	FormattedAddress *string `json:"formatted_address,omitempty"`
+	Street           *string `json:"street,omitempty"`
+	City             *string `json:"city,omitempty"`
+	State            *string `json:"state,omitempty"`
+	Country          *string `json:"country,omitempty"`
+	PostalCode       *string `json:"postal_code,omitempty"`
+}
+
+// ExtractionWebEntityContact is entities[].contact in
+// schemas/extraction.web.json. Optional contact information.
+type ExtractionWebEntityContact struct {
+	Phone   *string `json:"phone,omitempty"`
+	Email   *string `json:"email,omitempty"`
+	Website *string `json:"website,omitempty"`
+}
+
+// ExtractionWebEntityConfidences is entities[].confidences in
+// schemas/extraction.web.json. Confidence scores for this entity.
+type ExtractionWebEntityConfidences struct {
+	// Overall confidence for this entity.
+	Overall float64 `json:"overall"`
+	// Confidence in name extraction.
+	Name *float64 `json:"name,omitempty"`
+	// Confidence in location data.
+	Location *float64 `json:"location,omitempty"`
+	// Confidence in category assignment.
+	Category *float64 `json:"category,omitempty"`
+	// Confidence in address extraction.
+	Address *float64 `json:"address,omitempty"`
+}
+
+// ExtractionWebEntityExtractionContext is entities[].extraction_context in
+// schemas/extraction.web.json. Context from the extraction process.
+type ExtractionWebEntityExtractionContext struct {
+	// Relevant text snippet that mentioned this entity.
+	TextSnippet *string `json:"text_snippet,omitempty"`
+	// Section of the document where this was found.
+	Section *string `json:"section,omitempty"`
+	// CSS selector or XPath for the source element.
+	HTMLSelector *string `json:"html_selector,omitempty"`
+}
+
+// FrontierMaps mirrors schemas/frontier.maps.json (Maps Frontier Message).
+// Schema for maps frontier messages used in the data scout pipeline.
+type FrontierMaps struct {
+	// Message type identifier. Always "maps".
+	Type string `json:"type"`
+	// Target city for data collection.
+	City string `json:"city"`
+	// Latitude coordinate for the search center.
+	Lat float64 `json:"lat"`
+	// Longitude coordinate for the search center.
+	Lng float64 `json:"lng"`
+	// Search radius in meters.
+	Radius int64 `json:"radius"`
+	// Optional place category to filter by.
+	Category *string `json:"category,omitempty"`
+	// UUID for tracking requests across the pipeline.
+	CorrelationID string `json:"correlation_id"`
+	// Budget connector the message spends when processed (e.g. google.nearby).
+	BudgetToken *string `json:"budget_token,omitempty"`
+	// Budget split the work is charged to; with trust_score it picks the frontier
+	// lane when priority is absent. One of "primaries", "secondaries".
+	Split *string `json:"split,omitempty"`
+	// Unix time in seconds at which the producer enqueued the message.
+	EnqueuedAt *int64 `json:"enqueued_at,omitempty"`
+	// Optional trust score for the source (0.0 to 1.0).
+	TrustScore *float64 `json:"trust_score,omitempty"`
+	// Optional confidence score for coordinate accuracy.
+	CoordinatesConfidence *float64 `json:"coordinates_confidence,omitempty"`
+	// Processing priority for this message. One of "low", "medium", "high".
+	Priority *string `json:"priority,omitempty"`
+	// Type of maps API search to perform. One of "nearby", "text", "details".
+	SearchType *string `json:"search_type,omitempty"`
+	// Array of Google Places API place types to include.
+	PlaceTypes []string `json:"place_types,omitempty"`
+	// Additional metadata for the maps search.
+	Metadata *FrontierMapsMetadata `json:"metadata,omitempty"`
+}
+
+// FrontierMapsMetadata is metadata in schemas/frontier.maps.json. Additional
+// metadata for the maps search.
+type FrontierMapsMetadata struct {
+	// Reference place ID for expansion searches.
+	AnchorPlaceID *string `json:"anchor_place_id,omitempty"`
+	// Depth level for expansion searches.
+	SearchDepth *int64 `json:"search_depth,omitempty"`
+	// H3 cell identifier for geographic clustering.
+	H3Cell *string `json:"h3_cell,omitempty"`
+}
+
+// FrontierWeb mirrors schemas/frontier.web.json (Web Frontier Message). Schema
+// for web frontier messages used in the data scout pipeline.
+type FrontierWeb struct {
+	// Message type identifier. Always "web".
+	Type string `json:"type"`
+	// Target city for data collection.
+	City string `json:"city"`
+	// URL of the web source to process.
+	SourceURL string `json:"source_url"`
+	// Human-readable name of the source.
+	SourceName string `json:"source_name"`
+	// Type of content expected at the source URL. One of "html", "api", "json",
+	// "xml", "csv", "pdf".
+	SourceType string `json:"source_type"`
+	// Maximum depth to crawl from the source URL.
+	CrawlDepth int64 `json:"crawl_depth"`
+	// UUID for tracking requests across the pipeline.
+	CorrelationID string `json:"correlation_id"`
+	// Budget connector the message spends when processed (e.g. google.nearby).
+	BudgetToken *string `json:"budget_token,omitempty"`
+	// Budget split the work is charged to; with trust_score it picks the frontier
+	// lane when priority is absent. One of "primaries", "secondaries".
+	Split *string `json:"split,omitempty"`
+	// Unix time in seconds at which the producer enqueued the message.
+	EnqueuedAt *int64 `json:"enqueued_at,omitempty"`
+	// Optional trust score for the source (0.0 to 1.0).
+	TrustScore *float64 `json:"trust_score,omitempty"`
+	// Optional confidence score for coordinate accuracy.
+	CoordinatesConfidence *float64 `json:"coordinates_confidence,omitempty"`
+	// Processing priority for this message. One of "low", "medium", "high".
+	Priority *string `json:"priority,omitempty"`
+	// Timeout for processing this message.
+	TimeoutSeconds *int64 `json:"timeout_seconds,omitempty"`
+	// Additional metadata for the web source.
+	Metadata *FrontierWebMetadata `json:"metadata,omitempty"`
+}
+
+// FrontierWebMetadata is metadata in schemas/frontier.web.json. Additional
+// metadata for the web source.
+type FrontierWebMetadata struct {
+	// One of "gov", "edu", "org", "com", "unknown".
+	DomainAuthority *string `json:"domain_authority,omitempty"`
+	LastUpdated     *string `json:"last_updated,omitempty"`
+	ContentTypeHint *string `json:"content_type_hint,omitempty"`
+}
@@ -0,0 +1,6 @@
+// Package types holds Go structs generated from the pipeline contracts in
+// schemas/*.json. Edit the schemas, not schemas_gen.go, and regenerate with
+// go generate ./internal/types.
+package types
+
+//go:generate go run ../../cmd/schemagen -pkg types -out schemas_gen.go ../schema/schemas
@@ -0,0 +1,112 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/budget"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/frontier"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/schema"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/schemagen"
+)
+
+var examples = filepath.Join("..", "..", "..", "..", "..", "examples")
+
+func TestGeneratedTypesAreUpToDate(t *testing.T) {
+	var inputs []schemagen.Input
+	for _, name := range schema.Names() {
+		data, err := schema.Source(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, schemagen.Input{Name: name, Data: data})
+	}
+	want, err := schemagen.Generate("types", inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("schemas_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("schemas_gen.go is stale; run go generate ./internal/types")
+	}
+}
+
+// roundTrip decodes data strictly into v, encodes it back and checks nothing
+// was lost or changed.
+func roundTrip(t *testing.T, data []byte, v any) {
+	t.Helper()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		t.Fatalf("decode into %T: %v", v, err)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var before, after any
+	if err := json.Unmarshal(data, &before); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(out, &after); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(before, after) {
+		t.Fatalf("%T round trip changed the document:\nin:  %s\nout: %s", v, data, out)
+	}
+}
+
+func TestExamplesRoundTrip(t *testing.T) {
+	for file, v := range map[string]any{
+		"frontier/maps.example.json":       &FrontierMaps{},
+		"frontier/web.example.json":        &FrontierWeb{},
+		"canonical/candidate.example.json": &CanonicalCandidate{},
+		"extraction/web.example.json":      &ExtractionWeb{},
+	} {
+		t.Run(file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join(examples, file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			roundTrip(t, data, v)
+		})
+	}
+}
+
+// TestFrontierMessagesMatchSchemaTypes guards frontier.MapsMessage and
+// WebMessage against drifting from the schemas: every field they write must
+// decode into the generated type and survive the trip back.
+func TestFrontierMessagesMatchSchemaTypes(t *testing.T) {
+	trust, cat := 0.8, "museum"
+	env := frontier.NewEnvelope("maps", "Edinburgh", "f47ac10b-58cc-4372-a567-0e02b2c3d479")
+	env.BudgetToken, env.Split, env.Priority, env.TrustScore, env.CoordinatesConfidence = "google.nearby", budget.Primaries, "high", &trust, &trust
+
+	maps, err := json.Marshal(frontier.MapsMessage{Envelope: env, Lat: 55.95, Lng: -3.19, Rad: 500, Cat: &cat})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roundTrip(t, maps, &FrontierMaps{})
+
+	// A non-integral radius is schema-invalid, so neither side may accept it.
+	frac := bytes.Replace(maps, []byte(`"radius":500`), []byte(`"radius":500.5`), 1)
+	if err := json.Unmarshal(frac, &frontier.MapsMessage{}); err == nil {
+		t.Errorf("frontier.MapsMessage accepted radius 500.5")
+	}
+	if err := json.Unmarshal(frac, &FrontierMaps{}); err == nil {
+		t.Errorf("FrontierMaps accepted radius 500.5")
+	}
+
+	env.Type = "web"
+	web, err := json.Marshal(frontier.WebMessage{Envelope: env, SourceURL: "https://example.com", SourceName: "Example", SourceType: "html", CrawlDepth: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roundTrip(t, web, &FrontierWeb{})
+}
@@ -2,14 +2,20 @@ package workflow
 
 import (
 	"context"
+	"fmt"
 	"time"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/budget"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/clock"
 )
 
 // Stats captures running counters for the current job.
 type Stats struct {
-	APICalls       int
-	NewUniqueItems int
-	TotalItemsSeen int
+	APICalls       int `json:"api_calls"`
+	NewUniqueItems int `json:"new_unique_items"`
+	TotalItemsSeen int `json:"total_items_seen"`
+	// CostUSD is the estimated spend so far, fed from budget.Guard.SpendUSD.
+	CostUSD float64 `json:"cost_usd"`
 	// Optional: future fields (errors, retries, etc.)
 }
 
@@ -18,30 +24,88 @@ type BudgetGuard struct {
 	MaxAPICalls      int
 	MaxWallClock     time.Duration
 	MinNewUniqueRate float64 // e.g., 0.05 means 5% new items vs total seen
+	MaxCostUSD       float64 // estimated spend cap for the run
+	// Windows, when set, judges MinNewUniqueRate over each state's sliding
+	// window instead of over the whole run.
+	Windows *budget.WindowSet
 
 	StartTime time.Time
+	// Clock measures the wall clock against StartTime; nil means real time.
+	Clock clock.Clock
+}
+
+func (b BudgetGuard) clock() clock.Clock {
+	if b.Clock == nil {
+		return clock.Real()
+	}
+	return b.Clock
 }
 
-// ShouldStop decides whether to halt based on configured budgets.
-// This is written to be deterministic and easily unit-testable.
-func (b BudgetGuard) ShouldStop(ctx context.Context, s Stats) bool {
+// StopReason names the guard that tripped.
+type StopReason string
+
+const (
+	StopAPICalls      StopReason = "api_calls"
+	StopWallClock     StopReason = "wall_clock"
+	StopNewUniqueRate StopReason = "new_unique_rate"
+	StopCost          StopReason = "cost"
+)
+
+// Decision is the outcome of a budget check. It is written to $.budget.decision
+// so Step Functions and the run manifest can see which guard tripped.
+type Decision struct {
+	Stop      bool       `json:"stop"`
+	Reason    StopReason `json:"reason,omitempty"`
+	Observed  float64    `json:"observed,omitempty"`
+	Threshold float64    `json:"threshold,omitempty"`
+	Message   string     `json:"message,omitempty"`
+}
+
+func stop(reason StopReason, observed, threshold float64, format string, v ...any) Decision {
+	return Decision{Stop: true, Reason: reason, Observed: observed, Threshold: threshold, Message: fmt.Sprintf(format, v...)}
+}
+
+// ShouldStop decides whether to halt based on configured budgets, reporting
+// the first guard that tripped. This is written to be deterministic and easily
+// unit-testable.
+func (b BudgetGuard) ShouldStop(ctx context.Context, s Stats) Decision {
 	// Guard 1: API budget
 	if b.MaxAPICalls > 0 && s.APICalls >= b.MaxAPICalls {
-		return true
+		return stop(StopAPICalls, float64(s.APICalls), float64(b.MaxAPICalls),
+			"api calls %d reached cap %d", s.APICalls, b.MaxAPICalls)
 	}
 
 	// Guard 2: Wall clock
-	if b.MaxWallClock > 0 && !b.StartTime.IsZero() && time.Since(b.StartTime) >= b.MaxWallClock {
-		return true
+	if b.MaxWallClock > 0 && !b.StartTime.IsZero() {
+		if elapsed := b.clock().Since(b.StartTime); elapsed >= b.MaxWallClock {
+			return stop(StopWallClock, elapsed.Seconds(), b.MaxWallClock.Seconds(),
+				"wall clock %s reached cap %s", elapsed.Round(time.Second), b.MaxWallClock)
+		}
 	}
 
 	// Guard 3: New unique rate
-	if b.MinNewUniqueRate > 0 && s.TotalItemsSeen > 0 {
+	if b.MinNewUniqueRate > 0 && b.Windows != nil {
+		for _, state := range b.Windows.Keys() {
+			pw := b.Windows.Window(state).Progress()
+			if budget.EarlyStop(pw, b.MinNewUniqueRate) {
+				rate := pw.NewUniqueRate()
+				return stop(StopNewUniqueRate, rate, b.MinNewUniqueRate,
+					"%s new unique rate %.3f over last %d calls below %.3f", state, rate, pw.LastNCalls, b.MinNewUniqueRate)
+			}
+		}
+	} else if b.MinNewUniqueRate > 0 && s.TotalItemsSeen > 0 {
 		rate := float64(s.NewUniqueItems) / float64(s.TotalItemsSeen)
 		if rate < b.MinNewUniqueRate {
-			return true
+			return stop(StopNewUniqueRate, rate, b.MinNewUniqueRate,
+				"new unique rate %.3f below %.3f", rate, b.MinNewUniqueRate)
 		}
 	}
 
-	return false
+	// Guard 4: Estimated spend
+	if b.MaxCostUSD > 0 && s.CostUSD >= b.MaxCostUSD {
+		return stop(StopCost, s.CostUSD, b.MaxCostUSD,
+			"estimated cost $%.2f reached cap $%.2f", s.CostUSD, b.MaxCostUSD)
+	}
+
+	return Decision{}
 }
@@ -2,9 +2,12 @@ package workflow
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/budget"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/clock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -13,17 +16,24 @@ func TestBudgetGuard_APICallLimit(t *testing.T) {
 		MaxAPICalls: 10,
 		StartTime:   time.Now().Add(-1 * time.Minute),
 	}
-	require.True(t, bg.ShouldStop(context.Background(), Stats{APICalls: 10}))
-	require.True(t, bg.ShouldStop(context.Background(), Stats{APICalls: 11}))
-	require.False(t, bg.ShouldStop(context.Background(), Stats{APICalls: 9}))
+	require.True(t, bg.ShouldStop(context.Background(), Stats{APICalls: 10}).Stop)
+	require.True(t, bg.ShouldStop(context.Background(), Stats{APICalls: 11}).Stop)
+	require.False(t, bg.ShouldStop(context.Background(), Stats{APICalls: 9}).Stop)
 }
 
 func TestBudgetGuard_WallClock(t *testing.T) {
+	clk := clock.NewFake(time.Date(2025, 5, 1, 8, 0, 0, 0, time.UTC))
 	bg := BudgetGuard{
-		MaxWallClock: 100 * time.Millisecond,
-		StartTime:    time.Now().Add(-200 * time.Millisecond),
+		MaxWallClock: 6 * time.Hour,
+		StartTime:    clk.Now(),
+		Clock:        clk,
 	}
-	require.True(t, bg.ShouldStop(context.Background(), Stats{}))
+	clk.Advance(6*time.Hour - time.Second)
+	require.False(t, bg.ShouldStop(context.Background(), Stats{}).Stop)
+	clk.Advance(time.Second)
+	d := bg.ShouldStop(context.Background(), Stats{})
+	require.Equal(t, StopWallClock, d.Reason)
+	require.Equal(t, "wall clock 6h0m0s reached cap 6h0m0s", d.Message)
 }
 
 func TestBudgetGuard_MinNewUniqueRate(t *testing.T) {
@@ -31,11 +41,74 @@ func TestBudgetGuard_MinNewUniqueRate(t *testing.T) {
 		MinNewUniqueRate: 0.10,
 		StartTime:        time.Now(),
 	}
-	require.True(t, bg.ShouldStop(context.Background(), Stats{NewUniqueItems: 9, TotalItemsSeen: 100}))
-	require.False(t, bg.ShouldStop(context.Background(), Stats{NewUniqueItems: 10, TotalItemsSeen: 100}))
+	require.True(t, bg.ShouldStop(context.Background(), Stats{NewUniqueItems: 9, TotalItemsSeen: 100}).Stop)
+	require.False(t, bg.ShouldStop(context.Background(), Stats{NewUniqueItems: 10, TotalItemsSeen: 100}).Stop)
 }
 
 func TestBudgetGuard_ZeroOrUnsetDoesNotStop(t *testing.T) {
 	bg := BudgetGuard{}
-	require.False(t, bg.ShouldStop(context.Background(), Stats{}))
+	require.False(t, bg.ShouldStop(context.Background(), Stats{}).Stop)
+}
+
+func TestBudgetGuard_MaxCost(t *testing.T) {
+	bg := BudgetGuard{MaxCostUSD: 30}
+	d := bg.ShouldStop(context.Background(), Stats{CostUSD: 30.5})
+	require.Equal(t, Decision{Stop: true, Reason: StopCost, Observed: 30.5, Threshold: 30, Message: "estimated cost $30.50 reached cap $30.00"}, d)
+	require.False(t, bg.ShouldStop(context.Background(), Stats{CostUSD: 29.99}).Stop)
+}
+
+func TestBudgetGuard_DecisionReasons(t *testing.T) {
+	ctx := context.Background()
+	d := BudgetGuard{MaxAPICalls: 1}.ShouldStop(ctx, Stats{APICalls: 1})
+	require.Equal(t, StopAPICalls, d.Reason)
+	require.Equal(t, "api calls 1 reached cap 1", d.Message)
+
+	clk := clock.NewFake(time.Unix(0, 0))
+	d = BudgetGuard{MaxWallClock: time.Hour, StartTime: clk.Now().Add(-90 * time.Minute), Clock: clk}.ShouldStop(ctx, Stats{})
+	require.Equal(t, StopWallClock, d.Reason)
+	require.Equal(t, 5400.0, d.Observed)
+	require.Equal(t, 3600.0, d.Threshold)
+
+	d = BudgetGuard{MinNewUniqueRate: 0.5}.ShouldStop(ctx, Stats{NewUniqueItems: 1, TotalItemsSeen: 10})
+	require.Equal(t, StopNewUniqueRate, d.Reason)
+	require.Equal(t, 0.1, d.Observed)
+
+	require.Equal(t, Decision{}, BudgetGuard{}.ShouldStop(ctx, Stats{}))
+}
+
+func TestDecision_JSON(t *testing.T) {
+	body, err := json.Marshal(Decision{Stop: true, Reason: StopAPICalls, Observed: 5000, Threshold: 5000, Message: "api calls 5000 reached cap 5000"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"stop":true,"reason":"api_calls","observed":5000,"threshold":5000,"message":"api calls 5000 reached cap 5000"}`, string(body))
+
+	body, err = json.Marshal(Decision{})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"stop":false}`, string(body))
+}
+
+func TestBudgetGuard_SlidingWindowPerState(t *testing.T) {
+	ctx := context.Background()
+	windows := budget.NewWindowSet(200, 50)
+	bg := BudgetGuard{MinNewUniqueRate: 0.05, Windows: windows}
+
+	// A strong start keeps the all-history rate high...
+	seed := windows.Window(string(StateSeedPrimaries))
+	for i := 0; i < 200; i++ {
+		seed.Record(10, 10)
+	}
+	stats := Stats{NewUniqueItems: 2000, TotalItemsSeen: 2000}
+	tiles := windows.Window(string(StateTileSweep))
+	for i := 0; i < 49; i++ {
+		tiles.Record(0, 10)
+		stats.TotalItemsSeen += 10
+	}
+	require.False(t, bg.ShouldStop(ctx, stats).Stop, "below min samples")
+
+	// ...but a stalled TileSweep window trips once it has enough samples.
+	tiles.Record(0, 10)
+	stats.TotalItemsSeen += 10
+	d := bg.ShouldStop(ctx, stats)
+	require.Equal(t, StopNewUniqueRate, d.Reason)
+	require.Equal(t, "TileSweep new unique rate 0.000 over last 50 calls below 0.050", d.Message)
+	require.False(t, BudgetGuard{MinNewUniqueRate: 0.05}.ShouldStop(ctx, stats).Stop, "all-history rate stays high")
 }
@@ -0,0 +1,449 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/asl"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/budget"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/cache"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/clock"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/config"
+	obs "github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/observability"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/queue"
+)
+
+// ErrDeadLettered is returned by RunCity when a failed task was routed to the DLQ
+// (fail_fast was set), mirroring the SendToDLQ branch of the ASL.
+var ErrDeadLettered = errors.New("execution sent to DLQ")
+
+// Env bundles the dependencies handed to every state handler.
+type Env struct {
+	City  string
+	RunID string
+	Queue queue.FrontierQueue
+	Cache cache.RawCache
+	Stats *Stats
+	// Windows holds per-state sliding progress windows; nil when the runner
+	// judges progress over the whole run.
+	Windows *budget.WindowSet
+	// Config is the run's effective city config, or nil when the runner has
+	// none.
+	Config *config.CityConfig
+	// Flags answers feature flag and kill switch checks; the ctx handed to
+	// handlers carries the execution's overrides. Nil means no configured
+	// flags, which IsEnabled handles.
+	Flags *config.Flags
+	// Budget is the runner's budget.Guard, or nil when it has none.
+	Budget *budget.Guard
+}
+
+// PrimariesFound reports the run's primaries count to the budget guard and
+// rebalances its split quotas, so secondaries get primaries' spare quota as
+// soon as the split_rebalance targets are reached. SeedPrimaries calls it
+// with its count. It does nothing without a guard.
+func (e *Env) PrimariesFound(ctx context.Context, n int64) error {
+	if e.Budget == nil {
+		return nil
+	}
+	e.Budget.SetPrimariesFound(n)
+	if err := e.Budget.Rebalance(ctx); err != nil {
+		return fmt.Errorf("budget rebalance: %w", err)
+	}
+	return nil
+}
+
+// RecordProgress counts one call by state that saw items items, newUnique of
+// them new, in both the run totals and the state's sliding window.
+func (e *Env) RecordProgress(state StateName, newUnique, items int) {
+	e.Stats.NewUniqueItems += newUnique
+	e.Stats.TotalItemsSeen += items
+	if e.Windows != nil {
+		e.Windows.Window(string(state)).Record(int64(newUnique), int64(items))
+	}
+}
+
+// Handler executes a single Task state. It receives the execution document and
+// returns the value stored at the state's ResultPath.
+type Handler func(ctx context.Context, env *Env, doc map[string]any) (any, error)
+
+// Logger is satisfied by *log.Logger and *observability.CorrelationLogger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Transition records a single state entry (or task attempt) during a run.
+type Transition struct {
+	State   StateName `json:"state"`
+	Attempt int       `json:"attempt,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Execution is the outcome of a single local run.
+type Execution struct {
+	RunID        string
+	City         string
+	Doc          map[string]any
+	History      []Transition
+	Stats        Stats
+	StoppedBy    StateName // gate that short-circuited to Finalize, if any
+	Decision     Decision  // why StoppedBy stopped the run
+	DeadLettered bool
+	Config       *config.CityConfig // effective config, when the runner has one
+}
+
+// LocalRunner walks the city job state machine in process, following
+// terraform/sfn/definition.asl.json state by state.
+type LocalRunner struct {
+	Handlers map[StateName]Handler
+	Queue    queue.FrontierQueue
+	Cache    cache.RawCache
+	Guard    BudgetGuard
+	// Budget, when set, has its Snapshot recorded in the run manifest and
+	// its estimated spend checked against Guard.MaxCostUSD at BudgetGate.
+	Budget *budget.Guard
+	// Config, when set, is the city config before the input layer. Each run
+	// layers the input's "config" object on a copy, exposes it as Env.Config
+	// and $.config, and takes Guard's thresholds from it.
+	Config *config.CityConfig
+	// Flags picks mock handlers for states flagged in mock_states and is
+	// handed to handlers as Env.Flags.
+	Flags *config.Flags
+
+	// EarlyStopRate is the literal threshold on $.tile_sweep.new_unique_rate in EarlyStopGate.
+	EarlyStopRate float64
+	// FailFast seeds $.orchestrator.fail_fast; when true, caught errors go to SendToDLQ.
+	FailFast bool
+
+	Sleep  func(time.Duration)
+	Clock  clock.Clock // timestamps and, unless Guard.Clock is set, the wall-clock cap
+	Logger Logger
+}
+
+// NewLocalRunner returns a runner with mock handlers for every task state.
+// Override entries in Handlers to plug in real implementations.
+func NewLocalRunner(q queue.FrontierQueue, c cache.RawCache, guard BudgetGuard) *LocalRunner {
+	handlers := make(map[StateName]Handler, len(taskStates))
+	for _, s := range TaskStates() {
+		handlers[s] = MockHandler(s)
+	}
+	return &LocalRunner{
+		Handlers:      handlers,
+		Queue:         q,
+		Cache:         c,
+		Guard:         guard,
+		EarlyStopRate: 0.05,
+		Sleep:         time.Sleep,
+		Clock:         clock.Real(),
+	}
+}
+
+// MockHandler mimics lambdas/mock-go: it records one API call and returns
+// status "ok" with no items and a new_unique_rate of 0.2. As SeedPrimaries it
+// reports its (empty) item count as the primaries found.
+func MockHandler(state StateName) Handler {
+	return func(ctx context.Context, env *Env, doc map[string]any) (any, error) {
+		env.Stats.APICalls++
+		if state == StateSeedPrimaries {
+			if err := env.PrimariesFound(ctx, 0); err != nil {
+				return nil, err
+			}
+		}
+		return map[string]any{
+			"state":           string(state),
+			"status":          "ok",
+			"items":           []any{},
+			"new_unique_rate": 0.2,
+		}, nil
+	}
+}
+
+// RunCity runs a full city job and returns ErrDeadLettered if the run ended in SendToDLQ.
+func (r *LocalRunner) RunCity(ctx context.Context, city string) error {
+	exec, err := r.Run(ctx, map[string]any{"city": city})
+	if err != nil {
+		return err
+	}
+	if exec.DeadLettered {
+		return fmt.Errorf("%w: city %s", ErrDeadLettered, city)
+	}
+	return nil
+}
+
+// Run executes the state machine against the given StartExecution input.
+func (r *LocalRunner) Run(ctx context.Context, input map[string]any) (*Execution, error) {
+	doc := make(map[string]any, len(input))
+	for k, v := range input {
+		doc[k] = v
+	}
+	city, _ := doc["city"].(string)
+	now := r.Clock.Now()
+	exec := &Execution{
+		RunID: fmt.Sprintf("local-%s-%d", strings.ToLower(city), now.UnixNano()),
+		City:  city,
+		Doc:   doc,
+	}
+	guard := r.Guard
+	if r.Config != nil {
+		cc := *r.Config
+		if err := cc.ApplyInput(input); err != nil {
+			return exec, err
+		}
+		if err := cc.Validate(); err != nil {
+			return exec, fmt.Errorf("invalid config: %w", err)
+		}
+		exec.Config = &cc
+		doc["config"] = cc
+		ctx = config.WithCityConfig(ctx, &cc)
+		guard.MaxAPICalls = cc.Budgets.MaxAPICalls
+		guard.MaxWallClock = time.Duration(cc.Budgets.MaxWallClockHours) * time.Hour
+		guard.MinNewUniqueRate = cc.EarlyStop.MinNewUniqueRate
+		guard.MaxCostUSD = cc.Budgets.MaxCostUSD
+	}
+	if guard.Clock == nil {
+		guard.Clock = r.Clock
+	}
+	if guard.StartTime.IsZero() {
+		guard.StartTime = now
+	}
+	env := &Env{City: city, RunID: exec.RunID, Queue: r.Queue, Cache: r.Cache, Stats: &exec.Stats, Windows: guard.Windows, Config: exec.Config, Flags: r.Flags, Budget: r.Budget}
+
+	state := StateInitialize
+	for state != "" {
+		if err := ctx.Err(); err != nil {
+			return exec, err
+		}
+		exec.History = append(exec.History, Transition{State: state, At: r.Clock.Now()})
+		r.logf("state=%s run_id=%s", state, exec.RunID)
+
+		switch state {
+		case StateInitialize:
+			doc["orchestrator"] = map[string]any{
+				"run_id":     exec.RunID,
+				"start_time": guard.StartTime.UTC().Format(time.RFC3339),
+				"fail_fast":  r.FailFast,
+			}
+			state = StateInitBudget
+		case StateInitBudget:
+			doc["budget"] = r.budgetDoc(guard, exec.Stats)
+			state = StateInitMetrics
+		case StateInitMetrics:
+			doc["metrics"] = map[string]any{"new_unique_rate": 1.0}
+			state = StateDiscoverWebSources
+		case StateEarlyStopGate:
+			// Progress and wall-clock guards; API budget is checked at BudgetGate.
+			progress := BudgetGuard{MaxWallClock: guard.MaxWallClock, MinNewUniqueRate: guard.MinNewUniqueRate, Windows: guard.Windows, StartTime: guard.StartTime, Clock: guard.Clock}
+			var d Decision
+			if rate, ok := lookupFloat(doc, "tile_sweep", "new_unique_rate"); ok && rate < r.EarlyStopRate {
+				d = stop(StopNewUniqueRate, rate, r.EarlyStopRate, "tile sweep new unique rate %.3f below %.3f", rate, r.EarlyStopRate)
+			} else {
+				d = progress.ShouldStop(ctx, exec.Stats)
+			}
+			if r.decide(ctx, exec, state, d) {
+				state = StateFinalize
+			} else {
+				state = StateBudgetGate
+			}
+		case StateBudgetGate:
+			if r.Budget != nil {
+				exec.Stats.CostUSD = r.Budget.SpendUSD()
+			}
+			doc["budget"] = r.budgetDoc(guard, exec.Stats)
+			spend := BudgetGuard{MaxAPICalls: guard.MaxAPICalls, MaxCostUSD: guard.MaxCostUSD}
+			if r.decide(ctx, exec, state, spend.ShouldStop(ctx, exec.Stats)) {
+				state = StateFinalize
+			} else {
+				state = StateWebFetch
+			}
+		case StateToDLQOrContinue:
+			if failFast, _ := lookup(doc, "orchestrator", "fail_fast").(bool); failFast {
+				state = StateSendToDLQ
+			} else {
+				state = StateFinalize
+			}
+		case StateSendToDLQ:
+			exec.DeadLettered = true
+			if err := r.Queue.DeadLetter(ctx, doc, dlqReason(doc)); err != nil {
+				return exec, fmt.Errorf("send to dlq: %w", err)
+			}
+			state = ""
+		case StateFinalize:
+			if err := r.writeManifest(ctx, exec); err != nil {
+				return exec, err
+			}
+			state = ""
+		default:
+			spec, ok := taskStates[state]
+			if !ok {
+				return exec, fmt.Errorf("unknown state %q", state)
+			}
+			state = r.runTask(ctx, exec, env, state, spec)
+		}
+	}
+	return exec, nil
+}
+
+// runTask invokes the state handler with the ASL retry policy and returns the next state.
+// Errors the policy does not match, and exhausted retries, are caught into
+// $.errors.<State> and routed to ToDLQOrContinue.
+// Flag overrides are re-read from the document on every task, so an earlier
+// state can kill a connector for the rest of the run.
+func (r *LocalRunner) runTask(ctx context.Context, exec *Execution, env *Env, state StateName, spec taskSpec) StateName {
+	ctx = config.WithOverrides(ctx, config.OverridesFromInput(exec.Doc))
+	h, ok := r.Handlers[state]
+	if !ok || r.Flags.IsEnabled(ctx, config.MockStateFlag(string(state))) {
+		h = MockHandler(state)
+	}
+	var err error
+	var name string
+	for attempt := 0; ; attempt++ {
+		var res any
+		res, err = h(ctx, env, exec.Doc)
+		if err == nil {
+			exec.Doc[spec.ResultKey] = res
+			return spec.Next
+		}
+		exec.History = append(exec.History, Transition{State: state, Attempt: attempt + 1, Error: err.Error(), At: r.Clock.Now()})
+		r.logf("state=%s attempt=%d error=%v", state, attempt+1, err)
+		name = errorName(err)
+		if attempt >= spec.Retry.MaxAttempts || ctx.Err() != nil || !asl.ErrorMatches(spec.Retry.ErrorEquals, name) {
+			break
+		}
+		r.Sleep(backoff(spec.Retry, attempt))
+	}
+	errs, _ := exec.Doc["errors"].(map[string]any)
+	if errs == nil {
+		errs = make(map[string]any)
+		exec.Doc["errors"] = errs
+	}
+	errs[string(state)] = map[string]any{"Error": name, "Cause": err.Error()}
+	return StateToDLQOrContinue
+}
+
+// errorName is the ASL error a handler error is reported as, named the way
+// the asl interpreter names it: a *asl.TaskError keeps its name, a deadline
+// is States.Timeout and anything else States.TaskFailed.
+func errorName(err error) string {
+	var te *asl.TaskError
+	switch {
+	case errors.As(err, &te):
+		return te.Name
+	case errors.Is(err, context.DeadlineExceeded):
+		return asl.ErrorTimeout
+	}
+	return asl.ErrorTaskFailed
+}
+
+func (r *LocalRunner) budgetDoc(guard BudgetGuard, s Stats) map[string]any {
+	out := map[string]any{"api_calls_remaining": guard.MaxAPICalls - s.APICalls}
+	if guard.MaxWallClock > 0 {
+		out["wall_clock_remaining_seconds"] = int64((guard.MaxWallClock - guard.clock().Since(guard.StartTime)).Seconds())
+	}
+	if guard.MaxCostUSD > 0 {
+		out["cost_usd_remaining"] = guard.MaxCostUSD - s.CostUSD
+	}
+	return out
+}
+
+// decide records a gate's Decision at $.budget.decision and, when it stops
+// the run, on the Execution and as an EMF metric. It reports d.Stop.
+func (r *LocalRunner) decide(ctx context.Context, exec *Execution, state StateName, d Decision) bool {
+	if b, ok := exec.Doc["budget"].(map[string]any); ok {
+		b["decision"] = d
+	}
+	if !d.Stop {
+		return false
+	}
+	exec.StoppedBy, exec.Decision = state, d
+	r.logf("state=%s run_id=%s stop reason=%s: %s", state, exec.RunID, d.Reason, d.Message)
+	obs.RecordStopDecision(ctx, "cityjob", string(state), exec.City, string(d.Reason))
+	return true
+}
+
+// writeManifest stores manifests/<city>/<run_id>.json in the raw cache.
+func (r *LocalRunner) writeManifest(ctx context.Context, exec *Execution) error {
+	if r.Cache == nil {
+		return nil
+	}
+	manifest := map[string]any{
+		"run_id":        exec.RunID,
+		"city":          exec.City,
+		"stats":         exec.Stats,
+		"stopped_by":    exec.StoppedBy,
+		"stop_decision": exec.Decision,
+		"dead_lettered": exec.DeadLettered,
+		"errors":        exec.Doc["errors"],
+		"history":       exec.History,
+	}
+	if exec.Config != nil {
+		manifest["config"] = exec.Config
+	}
+	if r.Budget != nil {
+		snap, err := r.Budget.Snapshot(ctx)
+		if err != nil {
+			return fmt.Errorf("budget snapshot: %w", err)
+		}
+		manifest["budget"] = snap
+	}
+	body, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	key := fmt.Sprintf("manifests/%s/%s.json", strings.ToLower(exec.City), exec.RunID)
+	obj := cache.Object{Body: body, ContentType: "application/json", FetchedAt: r.Clock.Now()}
+	if err := r.Cache.Put(ctx, key, obj); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
+
+func (r *LocalRunner) logf(format string, v ...interface{}) {
+	if r.Logger != nil {
+		r.Logger.Printf(format, v...)
+	}
+}
+
+func backoff(p RetryPolicy, attempt int) time.Duration {
+	rate := p.BackoffRate
+	if rate < 1 {
+		rate = 1
+	}
+	return time.Duration(float64(p.Interval) * math.Pow(rate, float64(attempt)))
+}
+
+func dlqReason(doc map[string]any) string {
+	errs, _ := doc["errors"].(map[string]any)
+	parts := make([]string, 0, len(errs))
+	for state, e := range errs {
+		cause, _ := lookup(e, "Cause").(string)
+		parts = append(parts, state+": "+cause)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func lookup(v any, path ...string) any {
+	for _, p := range path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[p]
+	}
+	return v
+}
+
+func lookupFloat(v any, path ...string) (float64, bool) {
+	switch n := lookup(v, path...).(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,241 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/asl"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/budget"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/cache"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/config"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/queue"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRunner(q queue.FrontierQueue) (*LocalRunner, *[]time.Duration) {
+	var sleeps []time.Duration
+	r := NewLocalRunner(q, cache.NewMemoryCache(), BudgetGuard{})
+	r.Sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	return r, &sleeps
+}
+
+func TestLocalRunner_RetryThenSucceed(t *testing.T) {
+	r, sleeps := newTestRunner(queue.NewMemoryQueue(queue.MemoryOptions{}))
+	calls := 0
+	r.Handlers[StateWebFetch] = func(ctx context.Context, env *Env, doc map[string]any) (any, error) {
+		calls++
+		if calls < 3 {
+			return nil, errors.New("transient")
+		}
+		return map[string]any{"fetched": 1}, nil
+	}
+
+	exec, err := r.Run(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second}, *sleeps)
+	require.Contains(t, exec.Doc, "web_fetch")
+	require.NotContains(t, exec.Doc, "errors")
+}
+
+func TestLocalRunner_CatchContinuesToFinalize(t *testing.T) {
+	r, sleeps := newTestRunner(queue.NewMemoryQueue(queue.MemoryOptions{}))
+	calls := 0
+	r.Handlers[StateSeedPrimaries] = func(ctx context.Context, env *Env, doc map[string]any) (any, error) {
+		calls++
+		return nil, errors.New("boom")
+	}
+
+	exec, err := r.Run(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	require.Equal(t, 4, calls, "first attempt plus MaxAttempts retries")
+	require.Equal(t, []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second}, *sleeps)
+	require.Equal(t, "boom", lookup(exec.Doc, "errors", "SeedPrimaries", "Cause"))
+	require.False(t, exec.DeadLettered)
+	require.Equal(t, []StateName{StateToDLQOrContinue, StateFinalize}, visited(exec)[len(visited(exec))-2:])
+}
+
+func TestLocalRunner_RetryMatchesErrorEquals(t *testing.T) {
+	r, sleeps := newTestRunner(queue.NewMemoryQueue(queue.MemoryOptions{}))
+	var errs []error
+	r.Handlers[StateExtractWithLLM] = func(ctx context.Context, env *Env, doc map[string]any) (any, error) {
+		err := errs[0]
+		errs = errs[1:]
+		return nil, err
+	}
+
+	// A throttled extraction is retried, and so is a plain failure.
+	errs = []error{&asl.TaskError{Name: "ThrottlingException", Cause: "slow down"}, errors.New("bad json"), nil}
+	exec, err := r.Run(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	require.Empty(t, errs)
+	require.Equal(t, []time.Duration{2 * time.Second, 4 * time.Second}, *sleeps)
+	require.NotContains(t, exec.Doc, "errors")
+
+	// A timed-out one is not: ExtractWithLLM's retrier leaves States.Timeout out.
+	*sleeps = nil
+	errs = []error{fmt.Errorf("invoke model: %w", context.DeadlineExceeded)}
+	exec, err = r.Run(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	require.Empty(t, errs)
+	require.Empty(t, *sleeps)
+	require.Equal(t, asl.ErrorTimeout, lookup(exec.Doc, "errors", "ExtractWithLLM", "Error"))
+}
+
+func TestLocalRunner_FailFastSendsToDLQ(t *testing.T) {
+	q := queue.NewMemoryQueue(queue.MemoryOptions{})
+	r, _ := newTestRunner(q)
+	r.FailFast = true
+	r.Handlers[StateRank] = func(ctx context.Context, env *Env, doc map[string]any) (any, error) {
+		return nil, errors.New("rank failed")
+	}
+
+	err := r.RunCity(context.Background(), "Edinburgh")
+	require.ErrorIs(t, err, ErrDeadLettered)
+
+	dl := q.DeadLetters()
+	require.Len(t, dl, 1)
+	require.Equal(t, "Rank: rank failed", dl[0].Reason)
+	doc, ok := dl[0].Payload.(map[string]any)
+	require.True(t, ok)
+	require.Equal(t, "Edinburgh", doc["city"])
+}
+
+func TestLocalRunner_ContextCancelled(t *testing.T) {
+	r, _ := newTestRunner(queue.NewMemoryQueue(queue.MemoryOptions{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	r.Handlers[StateDiscoverTargets] = func(ctx context.Context, env *Env, doc map[string]any) (any, error) {
+		cancel()
+		return map[string]any{}, nil
+	}
+
+	_, err := r.Run(ctx, map[string]any{"city": "Edinburgh"})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestLocalRunner_ManifestIncludesBudgetSnapshot(t *testing.T) {
+	c := cache.NewMemoryCache()
+	r := NewLocalRunner(queue.NewMemoryQueue(queue.MemoryOptions{}), c, BudgetGuard{})
+	cfg := budget.Config{SplitRatio: 0.7}
+	cfg.Budgets = map[budget.Connector]struct {
+		Capacity int64         `yaml:"capacity"`
+		Refill   int64         `yaml:"refill"`
+		Period   time.Duration `yaml:"period"`
+	}{budget.TavilyAPI: {Capacity: 50}}
+	r.Budget = budget.NewGuard(cfg)
+
+	exec, err := r.Run(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	obj, err := c.Get(context.Background(), "manifests/edinburgh/"+exec.RunID+".json")
+	require.NoError(t, err)
+	var manifest struct {
+		Budget budget.Snapshot `json:"budget"`
+	}
+	require.NoError(t, json.Unmarshal(obj.Body, &manifest))
+	require.Equal(t, int64(50), manifest.Budget.Connectors[budget.TavilyAPI].Tokens)
+}
+
+func TestLocalRunner_PrimariesTargetGrowsSecondariesQuota(t *testing.T) {
+	r, _ := newTestRunner(queue.NewMemoryQueue(queue.MemoryOptions{}))
+	cfg := budget.Config{SplitRatio: 0.7, Rebalance: budget.RebalanceConfig{Interval: time.Minute, PrimariesTargetMin: 150, PrimariesTargetMax: 200}}
+	cfg.Budgets = map[budget.Connector]struct {
+		Capacity int64         `yaml:"capacity"`
+		Refill   int64         `yaml:"refill"`
+		Period   time.Duration `yaml:"period"`
+	}{budget.GoogleText: {Capacity: 100}}
+	r.Budget = budget.NewGuard(cfg)
+	require.NoError(t, r.Budget.Rebalance(context.Background()))
+	var found int64
+	r.Handlers[StateSeedPrimaries] = func(ctx context.Context, env *Env, doc map[string]any) (any, error) {
+		return map[string]any{}, env.PrimariesFound(ctx, found)
+	}
+	secondaries := func() int64 {
+		snap, err := r.Budget.Snapshot(context.Background())
+		require.NoError(t, err)
+		return snap.Connectors[budget.GoogleText].Splits[budget.Secondaries].Quota
+	}
+
+	found = 120
+	_, err := r.Run(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	require.Equal(t, int64(30), secondaries(), "below target, primaries keep their quota")
+
+	found = 200
+	exec, err := r.Run(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	require.Equal(t, int64(100), secondaries(), "at target, primaries' spare quota moves to secondaries")
+	require.NotContains(t, exec.Doc, "errors")
+}
+
+func TestLocalRunner_InputConfigLayersOverCityConfig(t *testing.T) {
+	r, _ := newTestRunner(queue.NewMemoryQueue(queue.MemoryOptions{}))
+	base := config.CityConfig{H3Res: 9, SeedTopN: 200, Budgets: config.CityBudgets{MaxAPICalls: 5000}}
+	r.Config = &base
+	var seen *config.CityConfig
+	r.Handlers[StateSeedPrimaries] = func(ctx context.Context, env *Env, doc map[string]any) (any, error) {
+		seen = env.Config
+		env.Stats.APICalls += 10
+		return map[string]any{}, nil
+	}
+
+	exec, err := r.Run(context.Background(), map[string]any{
+		"city":   "Edinburgh",
+		"config": map[string]any{"seed_top_n": 50, "budgets": map[string]any{"max_api_calls": 5}},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, seen)
+	require.Equal(t, "Edinburgh", seen.City)
+	require.Equal(t, 9, seen.H3Res)
+	require.Equal(t, 50, seen.SeedTopN)
+	require.Equal(t, config.LayerInput, seen.Origins["seed_top_n"].Layer)
+	require.Equal(t, 200, base.SeedTopN, "the runner's config is not modified")
+	require.Empty(t, base.Origins)
+	require.Equal(t, StateBudgetGate, exec.StoppedBy, "input max_api_calls drives the gate")
+}
+
+func TestLocalRunner_InvalidInputConfig(t *testing.T) {
+	r, _ := newTestRunner(queue.NewMemoryQueue(queue.MemoryOptions{}))
+	r.Config = &config.CityConfig{H3Res: 9}
+
+	_, err := r.Run(context.Background(), map[string]any{"city": "Edinburgh", "config": map[string]any{"h3_res": 20}})
+	require.ErrorContains(t, err, "input: config.h3_res: must be between 0 and 15")
+
+	_, err = r.Run(context.Background(), map[string]any{"city": "Edinburgh", "config": map[string]any{"h3_resolution": 8}})
+	require.ErrorContains(t, err, "h3_resolution")
+}
+
+func TestLocalRunner_FlagsMockStatesAndKillSwitches(t *testing.T) {
+	r, _ := newTestRunner(queue.NewMemoryQueue(queue.MemoryOptions{}))
+	r.Flags = config.NewFlags(config.FeatureFlags{MockStates: []string{string(StateRank)}})
+	r.Handlers[StateRank] = func(ctx context.Context, env *Env, doc map[string]any) (any, error) {
+		return nil, errors.New("real Rank should be mocked")
+	}
+	var webEnabled []bool
+	r.Handlers[StateDiscoverWebSources] = func(ctx context.Context, env *Env, doc map[string]any) (any, error) {
+		webEnabled = append(webEnabled, env.Flags.IsEnabled(ctx, config.ConnectorFlag(budget.TavilyAPI)))
+		// A circuit breaker tripping mid-run kills the web group for later states.
+		doc["kill_switches"] = map[string]any{"web": true}
+		return map[string]any{}, nil
+	}
+	r.Handlers[StateWebFetch] = func(ctx context.Context, env *Env, doc map[string]any) (any, error) {
+		webEnabled = append(webEnabled, env.Flags.IsEnabled(ctx, config.ConnectorFlag(budget.WebFetch)))
+		return map[string]any{}, nil
+	}
+
+	exec, err := r.Run(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	require.NotContains(t, exec.Doc, "errors")
+	require.Equal(t, []bool{true, false}, webEnabled)
+
+	// The input can turn a configured mock back into the real handler.
+	exec, err = r.Run(context.Background(), map[string]any{
+		"city":          "Edinburgh",
+		"feature_flags": map[string]any{"mock_states": map[string]any{string(StateRank): false}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "real Rank should be mocked", lookup(exec.Doc, "errors", "Rank", "Cause"))
+}
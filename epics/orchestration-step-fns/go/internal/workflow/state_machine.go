@@ -1,9 +1,92 @@
 package workflow
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/asl"
+)
 
 // Runner represents a local simulation harness for the Step Functions flow.
 // Implementations should model transitions and call external dependencies via interfaces.
 type Runner interface {
 	RunCity(ctx context.Context, city string) error
 }
+
+// StateName identifies a state in terraform/sfn/definition.asl.json.
+type StateName string
+
+const (
+	StateInitialize         StateName = "Initialize"
+	StateInitBudget         StateName = "InitBudget"
+	StateInitMetrics        StateName = "InitMetrics"
+	StateDiscoverWebSources StateName = "DiscoverWebSources"
+	StateDiscoverTargets    StateName = "DiscoverTargets"
+	StateSeedPrimaries      StateName = "SeedPrimaries"
+	StateExpandNeighbors    StateName = "ExpandNeighbors"
+	StateTileSweep          StateName = "TileSweep"
+	StateEarlyStopGate      StateName = "EarlyStopGate"
+	StateBudgetGate         StateName = "BudgetGate"
+	StateWebFetch           StateName = "WebFetch"
+	StateExtractWithLLM     StateName = "ExtractWithLLM"
+	StateGeocodeValidate    StateName = "GeocodeValidate"
+	StateDedupeCanonicalize StateName = "DedupeCanonicalize"
+	StatePersist            StateName = "Persist"
+	StateRank               StateName = "Rank"
+	StateToDLQOrContinue    StateName = "ToDLQOrContinue"
+	StateSendToDLQ          StateName = "SendToDLQ"
+	StateFinalize           StateName = "Finalize"
+)
+
+// RetryPolicy mirrors a single ASL Retry entry. ErrorEquals lists the ASL
+// error names it retries, matched as by asl.ErrorMatches. MaxAttempts counts
+// retries after the first attempt, as in Step Functions.
+type RetryPolicy struct {
+	ErrorEquals []string
+	Interval    time.Duration
+	BackoffRate float64
+	MaxAttempts int
+}
+
+// taskSpec describes a Task state: where its result lands and how it retries.
+// Every task catches States.ALL into $.errors.<State> and goes to ToDLQOrContinue.
+type taskSpec struct {
+	ResultKey string
+	Retry     RetryPolicy
+	Next      StateName
+}
+
+var (
+	defaultRetry = RetryPolicy{ErrorEquals: []string{asl.ErrorAll}, Interval: 2 * time.Second, BackoffRate: 2.0, MaxAttempts: 3}
+	fastRetry    = RetryPolicy{ErrorEquals: []string{asl.ErrorAll}, Interval: 1 * time.Second, BackoffRate: 2.0, MaxAttempts: 3}
+	fetchRetry   = RetryPolicy{ErrorEquals: []string{asl.ErrorAll}, Interval: 1 * time.Second, BackoffRate: 2.0, MaxAttempts: 4}
+	// llmRetry leaves States.Timeout alone: a timed-out extraction is not retried.
+	llmRetry = RetryPolicy{
+		ErrorEquals: []string{"ThrottlingException", "ServiceUnavailableException", asl.ErrorTaskFailed},
+		Interval:    2 * time.Second, BackoffRate: 2.0, MaxAttempts: 3,
+	}
+)
+
+// taskStates is the Task portion of definition.asl.json;
+// TestTaskStatesMatchDefinition keeps the two in sync.
+var taskStates = map[StateName]taskSpec{
+	StateDiscoverWebSources: {ResultKey: "web_sources", Retry: defaultRetry, Next: StateDiscoverTargets},
+	StateDiscoverTargets:    {ResultKey: "targets", Retry: defaultRetry, Next: StateSeedPrimaries},
+	StateSeedPrimaries:      {ResultKey: "primaries", Retry: defaultRetry, Next: StateExpandNeighbors},
+	StateExpandNeighbors:    {ResultKey: "neighbors", Retry: defaultRetry, Next: StateTileSweep},
+	StateTileSweep:          {ResultKey: "tile_sweep", Retry: defaultRetry, Next: StateEarlyStopGate},
+	StateWebFetch:           {ResultKey: "web_fetch", Retry: fetchRetry, Next: StateExtractWithLLM},
+	StateExtractWithLLM:     {ResultKey: "extracted", Retry: llmRetry, Next: StateGeocodeValidate},
+	StateGeocodeValidate:    {ResultKey: "geocoded", Retry: fastRetry, Next: StateDedupeCanonicalize},
+	StateDedupeCanonicalize: {ResultKey: "canonical", Retry: fastRetry, Next: StatePersist},
+	StatePersist:            {ResultKey: "persisted", Retry: fastRetry, Next: StateRank},
+	StateRank:               {ResultKey: "ranked", Retry: fastRetry, Next: StateFinalize},
+}
+
+// TaskStates returns the Task state names in execution order.
+func TaskStates() []StateName {
+	return []StateName{
+		StateDiscoverWebSources, StateDiscoverTargets, StateSeedPrimaries, StateExpandNeighbors, StateTileSweep,
+		StateWebFetch, StateExtractWithLLM, StateGeocodeValidate, StateDedupeCanonicalize, StatePersist, StateRank,
+	}
+}
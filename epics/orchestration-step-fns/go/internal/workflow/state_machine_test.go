@@ -1,7 +1,152 @@
 package workflow
 
-import "testing"
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
 
-func TestRunner_Simulation_Pending(t *testing.T) {
-	t.Skip("Pending: implement a local Runner with in-memory queue/cache fakes and verify transitions and budget guard short-circuit.")
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/asl"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/budget"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/cache"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/clock"
+	"github.com/Sreeram-ganesan/jaunt-data-scout/epics/orchestration-step-fns/go/internal/queue"
+	"github.com/stretchr/testify/require"
+)
+
+func visited(exec *Execution) []StateName {
+	var out []StateName
+	for _, t := range exec.History {
+		if t.Attempt == 0 {
+			out = append(out, t.State)
+		}
+	}
+	return out
+}
+
+func TestRunner_Simulation_FullPath(t *testing.T) {
+	c := cache.NewMemoryCache()
+	r := NewLocalRunner(queue.NewMemoryQueue(queue.MemoryOptions{}), c, BudgetGuard{MaxAPICalls: 100})
+
+	exec, err := r.Run(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	require.Equal(t, []StateName{
+		StateInitialize, StateInitBudget, StateInitMetrics,
+		StateDiscoverWebSources, StateDiscoverTargets, StateSeedPrimaries, StateExpandNeighbors, StateTileSweep,
+		StateEarlyStopGate, StateBudgetGate,
+		StateWebFetch, StateExtractWithLLM, StateGeocodeValidate, StateDedupeCanonicalize, StatePersist, StateRank,
+		StateFinalize,
+	}, visited(exec))
+	require.Equal(t, 11, exec.Stats.APICalls)
+	require.Empty(t, exec.StoppedBy)
+	require.Contains(t, exec.Doc, "ranked")
+	require.Len(t, c.Keys(), 1, "manifest written at Finalize")
+}
+
+func TestRunner_Simulation_BudgetGuardShortCircuits(t *testing.T) {
+	r := NewLocalRunner(queue.NewMemoryQueue(queue.MemoryOptions{}), cache.NewMemoryCache(), BudgetGuard{MaxAPICalls: 5})
+
+	exec, err := r.Run(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	require.Equal(t, StateBudgetGate, exec.StoppedBy)
+	require.Equal(t, StopAPICalls, exec.Decision.Reason)
+	require.Equal(t, exec.Decision, exec.Doc["budget"].(map[string]any)["decision"])
+	require.Equal(t, StateFinalize, exec.History[len(exec.History)-1].State)
+	require.NotContains(t, exec.Doc, "web_fetch")
+}
+
+func TestRunner_Simulation_EarlyStopOnLowNewUniqueRate(t *testing.T) {
+	r := NewLocalRunner(queue.NewMemoryQueue(queue.MemoryOptions{}), cache.NewMemoryCache(), BudgetGuard{})
+	r.Handlers[StateTileSweep] = func(ctx context.Context, env *Env, doc map[string]any) (any, error) {
+		return map[string]any{"new_unique_rate": 0.01}, nil
+	}
+
+	exec, err := r.Run(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	require.Equal(t, StateEarlyStopGate, exec.StoppedBy)
+	require.Equal(t, Decision{Stop: true, Reason: StopNewUniqueRate, Observed: 0.01, Threshold: 0.05,
+		Message: "tile sweep new unique rate 0.010 below 0.050"}, exec.Decision)
+}
+
+func TestRunner_Simulation_WallClockGuard(t *testing.T) {
+	// Start at a realistic instant: from Unix(0), a gate reading real time
+	// instead of r.Clock would be past the cap whether or not time passed.
+	clk := clock.NewFake(time.Now())
+	guard := BudgetGuard{MaxWallClock: 6 * time.Hour}
+	r := NewLocalRunner(queue.NewMemoryQueue(queue.MemoryOptions{}), cache.NewMemoryCache(), guard)
+	r.Clock = clk
+	var advance time.Duration
+	r.Handlers[StateTileSweep] = func(ctx context.Context, env *Env, doc map[string]any) (any, error) {
+		clk.Advance(advance)
+		return map[string]any{"status": "ok"}, nil
+	}
+
+	exec, err := r.Run(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	require.Empty(t, exec.StoppedBy, "no fake time passed")
+
+	advance = 7 * time.Hour
+	exec, err = r.Run(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	require.Equal(t, StateEarlyStopGate, exec.StoppedBy)
+	require.Equal(t, StopWallClock, exec.Decision.Reason)
+}
+
+func TestRunner_Simulation_EarlyStopOnStalledStateWindow(t *testing.T) {
+	guard := BudgetGuard{MinNewUniqueRate: 0.05, Windows: budget.NewWindowSet(20, 10)}
+	r := NewLocalRunner(queue.NewMemoryQueue(queue.MemoryOptions{}), cache.NewMemoryCache(), guard)
+	r.Handlers[StateTileSweep] = func(ctx context.Context, env *Env, doc map[string]any) (any, error) {
+		for i := 0; i < 10; i++ {
+			env.RecordProgress(StateTileSweep, 0, 5)
+		}
+		return map[string]any{"status": "ok"}, nil
+	}
+
+	exec, err := r.Run(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	require.Equal(t, StateEarlyStopGate, exec.StoppedBy)
+	require.Equal(t, StopNewUniqueRate, exec.Decision.Reason)
+	require.Equal(t, 50, exec.Stats.TotalItemsSeen)
+}
+
+func TestRunner_Simulation_CostCapShortCircuits(t *testing.T) {
+	cfg := budget.Config{Prices: map[budget.Connector]budget.Price{budget.TavilyAPI: {PerCallUSD: 20}}}
+	cfg.Budgets = map[budget.Connector]struct {
+		Capacity int64         `yaml:"capacity"`
+		Refill   int64         `yaml:"refill"`
+		Period   time.Duration `yaml:"period"`
+	}{budget.TavilyAPI: {Capacity: 10}}
+	r := NewLocalRunner(queue.NewMemoryQueue(queue.MemoryOptions{}), cache.NewMemoryCache(), BudgetGuard{MaxCostUSD: 30})
+	r.Budget = budget.NewGuard(cfg)
+	r.Handlers[StateDiscoverWebSources] = func(ctx context.Context, env *Env, doc map[string]any) (any, error) {
+		for i := 0; i < 2; i++ {
+			if err := r.Budget.Acquire(ctx, budget.AcquireOpts{Connector: budget.TavilyAPI, Split: budget.Primaries}); err != nil {
+				return nil, err
+			}
+		}
+		return map[string]any{"status": "ok"}, nil
+	}
+
+	exec, err := r.Run(context.Background(), map[string]any{"city": "Edinburgh"})
+	require.NoError(t, err)
+	require.Equal(t, StateBudgetGate, exec.StoppedBy)
+	require.Equal(t, StopCost, exec.Decision.Reason)
+	require.Equal(t, 40.0, exec.Stats.CostUSD)
+}
+
+func TestTaskStatesMatchDefinition(t *testing.T) {
+	def, err := asl.LoadFile(filepath.Join("..", "..", "..", "terraform", "sfn", "definition.asl.json"), nil)
+	require.NoError(t, err)
+	for _, name := range TaskStates() {
+		st := def.States[string(name)]
+		require.NotNil(t, st, name)
+		spec := taskStates[name]
+		require.Equal(t, string(spec.Next), st.Next, name)
+		require.Len(t, st.Retry, 1, name)
+		r := st.Retry[0]
+		require.Equal(t, spec.Retry.ErrorEquals, r.ErrorEquals, name)
+		require.Equal(t, spec.Retry.Interval, time.Duration(*r.IntervalSeconds*float64(time.Second)), name)
+		require.Equal(t, spec.Retry.BackoffRate, *r.BackoffRate, name)
+		require.Equal(t, spec.Retry.MaxAttempts, *r.MaxAttempts, name)
+	}
 }
@@ -23,10 +23,37 @@ resource "aws_sqs_queue" "frontier" {
   }
 }
 
+# Priority lanes: the frontier queue above is the medium lane; primary seeds
+# go to the high lane and tile sweeps/secondaries to the low lane. All lanes
+# redrive to the same DLQ.
+resource "aws_sqs_queue" "frontier_lane" {
+  for_each                   = toset(["high", "low"])
+  name                       = "${local.name_prefix}-frontier-${each.key}"
+  visibility_timeout_seconds = 120
+  sqs_managed_sse_enabled    = true
+  redrive_policy = jsonencode({
+    deadLetterTargetArn = aws_sqs_queue.frontier_dlq.arn
+    maxReceiveCount     = 5
+  })
+  tags = {
+    Project     = var.project_prefix
+    Environment = var.environment
+    Lane        = each.key
+  }
+}
+
 output "frontier_queue_url" {
   value = aws_sqs_queue.frontier.id
 }
 
 output "frontier_dlq_url" {
   value = aws_sqs_queue.frontier_dlq.id
-}
\ No newline at end of file
+}
+
+output "frontier_lane_queue_urls" {
+  value = {
+    high   = aws_sqs_queue.frontier_lane["high"].id
+    medium = aws_sqs_queue.frontier.id
+    low    = aws_sqs_queue.frontier_lane["low"].id
+  }
+}